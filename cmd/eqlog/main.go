@@ -8,13 +8,15 @@ import (
 	"os/signal"
 	"sort"
 	"strings"
+	"sync"
 	"text/tabwriter"
 	"time"
 
 	"github.com/ZehenForever/eqemu-log-parser/internal/engine"
+	"github.com/ZehenForever/eqemu-log-parser/internal/logindex"
 	"github.com/ZehenForever/eqemu-log-parser/internal/model"
 	"github.com/ZehenForever/eqemu-log-parser/internal/parse"
-	"github.com/ZehenForever/eqemu-log-parser/internal/tail"
+	"github.com/ZehenForever/eqemu-log-parser/internal/pipeline"
 )
 
 type multiStringFlag []string
@@ -43,6 +45,12 @@ func run(args []string) int {
 		return runParse(args[1:])
 	case "encounters":
 		return runEncounters(args[1:])
+	case "loot":
+		return runLoot(args[1:])
+	case "index":
+		return runIndex(args[1:])
+	case "coverage":
+		return runCoverage(args[1:])
 	case "-h", "--help", "help":
 		usage()
 		return 0
@@ -56,6 +64,9 @@ func run(args []string) int {
 func usage() {
 	fmt.Fprintln(os.Stderr, "eqlog parse --file <path>")
 	fmt.Fprintln(os.Stderr, "eqlog encounters --file <path>")
+	fmt.Fprintln(os.Stderr, "eqlog loot --file <path>")
+	fmt.Fprintln(os.Stderr, "eqlog index --file <path>")
+	fmt.Fprintln(os.Stderr, "eqlog coverage --file <path>")
 }
 
 func startAtEnd(follow bool, start string) (bool, error) {
@@ -79,6 +90,9 @@ func runParse(args []string) int {
 	follow := fs.Bool("follow", false, "tail the file and process new lines as they are appended")
 	start := fs.String("start", "", "when following, start at begin or end (default: end when --follow, begin otherwise)")
 	lastHours := fs.Float64("last-hours", 0, "only ingest events from the last N hours (0 disables)")
+	var rulePacks multiStringFlag
+	fs.Var(&rulePacks, "rules", "JSON rule pack layered over the built-in rules (repeatable)")
+	tz := fs.String("tz", "", "time zone the log was written in: IANA name or UTC offset (default: this machine's)")
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
@@ -86,6 +100,16 @@ func runParse(args []string) int {
 		fmt.Fprintln(os.Stderr, "--file is required")
 		return 2
 	}
+	rules, err := parse.LoadRuleSet(rulePacks...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load rules: %v\n", err)
+		return 2
+	}
+	loc, err := parse.LoadLocation(*tz)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --tz: %v\n", err)
+		return 2
+	}
 	startEnd, err := startAtEnd(*follow, *start)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -96,132 +120,19 @@ func runParse(args []string) int {
 	tf := engine.NewTimeFilterLastHours(*lastHours, now)
 
 	if *follow {
-		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-		defer stop()
-
-		playerName, _ := parse.PlayerNameFromLogPath(*filePath)
-		pctx := &model.ParseContext{LocalActorName: playerName}
 		e := engine.New()
-
-		if *lastHours > 0 && startEnd {
-			f, err := os.Open(*filePath)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "failed to open file for preload: %v\n", err)
-				return 1
-			}
-			it := parse.ParseFile(f, pctx, time.Local)
-			for it.Next() {
-				ev := it.Event()
-				if !tf.Allow(ev.Timestamp) {
-					continue
-				}
-				if playerName != "" {
-					if ev.Actor == "YOU" {
-						ev.Actor = playerName
-					}
-					if ev.Target == "YOU" {
-						ev.Target = playerName
-					}
-				}
-				e.Process(ev)
-			}
-			_ = f.Close()
-			if err := it.Err(); err != nil {
-				fmt.Fprintf(os.Stderr, "failed to read file for preload: %v\n", err)
-				return 1
-			}
-		}
-
-		tlr, err := tail.NewTailer(*filePath, tail.TailOptions{StartAtEnd: startEnd})
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to start tailer: %v\n", err)
-			return 1
-		}
-		defer tlr.Stop()
-
-		lineCh := make(chan string, 1024)
-		errCh := make(chan error, 1)
-		go func() {
-			errCh <- tlr.Run(ctx, func(line string) {
-				select {
-				case lineCh <- line:
-				default:
-				}
-			})
-		}()
-
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-		dirty := *lastHours > 0 && startEnd
-		for {
-			select {
-			case <-ctx.Done():
-				return 0
-			case err := <-errCh:
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "tail error: %v\n", err)
-					return 1
-				}
-				return 0
-			case line := <-lineCh:
-				ev, ok := parse.ParseLine(pctx, line, time.Local)
-				if !ok {
-					continue
-				}
-				if !tf.Allow(ev.Timestamp) {
-					continue
-				}
-				if playerName != "" {
-					if ev.Actor == "YOU" {
-						ev.Actor = playerName
-					}
-					if ev.Target == "YOU" {
-						ev.Target = playerName
-					}
-				}
-				e.Process(ev)
-				dirty = true
-			case <-ticker.C:
-				if !dirty {
-					continue
-				}
-				printActorTable(e)
-				fmt.Fprintln(os.Stdout)
-				printTopTargets(e, 10)
-				fmt.Fprintln(os.Stdout)
-				dirty = false
-			}
-		}
+		pipe := pipeline.ForLog(*filePath, rules, loc, tf, noteClockJump, pipeline.Sink(func(ev model.Event) { e.Process(ev) }))
+		return followLog(pipe, *filePath, tf, startEnd, *lastHours > 0 && startEnd, func() {
+			printActorTable(e)
+			fmt.Fprintln(os.Stdout)
+			printTopTargets(e, 10)
+			fmt.Fprintln(os.Stdout)
+		})
 	}
 
-	f, err := os.Open(*filePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to open file: %v\n", err)
-		return 1
-	}
-	defer f.Close()
-
-	playerName, _ := parse.PlayerNameFromLogPath(*filePath)
-	ctx := &model.ParseContext{LocalActorName: playerName}
-
 	e := engine.New()
-	it := parse.ParseFile(f, ctx, time.Local)
-	for it.Next() {
-		ev := it.Event()
-		if !tf.Allow(ev.Timestamp) {
-			continue
-		}
-		if playerName != "" {
-			if ev.Actor == "YOU" {
-				ev.Actor = playerName
-			}
-			if ev.Target == "YOU" {
-				ev.Target = playerName
-			}
-		}
-		e.Process(ev)
-	}
-	if err := it.Err(); err != nil {
+	pipe := pipeline.ForLog(*filePath, rules, loc, tf, noteClockJump, pipeline.Sink(func(ev model.Event) { e.Process(ev) }))
+	if err := pipe.Run(context.Background(), pipeline.File(*filePath, tf.Cutoff)); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to read file: %v\n", err)
 		return 1
 	}
@@ -243,10 +154,16 @@ func runEncounters(args []string) int {
 	lastHours := fs.Float64("last-hours", 0, "only ingest events from the last N hours (0 disables)")
 	pcThreshold := fs.Int("pc-threshold", engine.DefaultPCThreshold, "score threshold for LikelyPC classification")
 	debugIdentities := fs.Bool("debug-identities", false, "print identity classification summary")
+	zone := fs.String("zone", "", "only show encounters fought in this zone")
+	groupByZone := fs.Bool("group-by-zone", false, "list encounters zone by zone")
+	targetSwitchGap := fs.Duration("target-switch-gap", engine.DefaultTargetSwitchGap, "start a new pull when you switch targets to an NPC whose fight has been quiet this long (0 disables)")
 	var forcePC multiStringFlag
 	var forceNPC multiStringFlag
 	fs.Var(&forcePC, "force-pc", "force a name to be treated as PC (repeatable)")
 	fs.Var(&forceNPC, "force-npc", "force a name to be treated as NPC (repeatable)")
+	var rulePacks multiStringFlag
+	fs.Var(&rulePacks, "rules", "JSON rule pack layered over the built-in rules (repeatable)")
+	tz := fs.String("tz", "", "time zone the log was written in: IANA name or UTC offset (default: this machine's)")
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
@@ -254,6 +171,16 @@ func runEncounters(args []string) int {
 		fmt.Fprintln(os.Stderr, "--file is required")
 		return 2
 	}
+	rules, err := parse.LoadRuleSet(rulePacks...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load rules: %v\n", err)
+		return 2
+	}
+	loc, err := parse.LoadLocation(*tz)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --tz: %v\n", err)
+		return 2
+	}
 	startEnd, err := startAtEnd(*follow, *start)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -264,205 +191,104 @@ func runEncounters(args []string) int {
 	tf := engine.NewTimeFilterLastHours(*lastHours, now)
 
 	if *follow {
-		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-		defer stop()
-
 		playerName, _ := parse.PlayerNameFromLogPath(*filePath)
-		pctx := &model.ParseContext{LocalActorName: playerName}
 		seg := engine.NewEncounterSegmenter(*idleTimeout, playerName)
+		seg.TargetSwitchGap = *targetSwitchGap
 		identityEvents := make([]model.Event, 0, 4096)
-
-		if *lastHours > 0 && startEnd {
-			f, err := os.Open(*filePath)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "failed to open file for preload: %v\n", err)
-				return 1
-			}
-			it := parse.ParseFile(f, pctx, time.Local)
-			for it.Next() {
-				ev := it.Event()
-				if !tf.Allow(ev.Timestamp) {
-					continue
-				}
-				if playerName != "" {
-					if ev.Actor == "YOU" {
-						ev.Actor = playerName
-					}
-					if ev.Target == "YOU" {
-						ev.Target = playerName
-					}
-				}
-				seg.Process(ev)
+		pipe := pipeline.ForLog(*filePath, rules, loc, tf, noteClockJump,
+			pipeline.Sink(seg.Process),
+			pipeline.Sink(func(ev model.Event) {
 				identityEvents = append(identityEvents, ev)
 				if len(identityEvents) > 8192 {
 					identityEvents = identityEvents[len(identityEvents)-4096:]
 				}
-			}
-			_ = f.Close()
-			if err := it.Err(); err != nil {
-				fmt.Fprintf(os.Stderr, "failed to read file for preload: %v\n", err)
-				return 1
-			}
-		}
-
-		tlr, err := tail.NewTailer(*filePath, tail.TailOptions{StartAtEnd: startEnd})
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to start tailer: %v\n", err)
-			return 1
-		}
-		defer tlr.Stop()
-
-		lineCh := make(chan string, 1024)
-		errCh := make(chan error, 1)
-		go func() {
-			errCh <- tlr.Run(ctx, func(line string) {
-				select {
-				case lineCh <- line:
-				default:
-				}
-			})
-		}()
-
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-		dirty := *lastHours > 0 && startEnd
-		for {
-			select {
-			case <-ctx.Done():
-				return 0
-			case err := <-errCh:
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "tail error: %v\n", err)
-					return 1
-				}
-				return 0
-			case line := <-lineCh:
-				ev, ok := parse.ParseLine(pctx, line, time.Local)
-				if !ok {
-					continue
-				}
-				if !tf.Allow(ev.Timestamp) {
-					continue
-				}
-				if playerName != "" {
-					if ev.Actor == "YOU" {
-						ev.Actor = playerName
-					}
-					if ev.Target == "YOU" {
-						ev.Target = playerName
-					}
-				}
-				seg.Process(ev)
-				identityEvents = append(identityEvents, ev)
-				if len(identityEvents) > 8192 {
-					identityEvents = identityEvents[len(identityEvents)-4096:]
-				}
-				dirty = true
-			case <-ticker.C:
-				if !dirty {
-					continue
-				}
-
-				scores := engine.ClassifyNames(identityEvents)
-				forcePCSet := make(map[string]struct{}, len(forcePC))
-				for _, n := range forcePC {
-					forcePCSet[n] = struct{}{}
-					if _, ok := scores[n]; !ok {
-						scores[n] = engine.IdentityScore{Name: n}
-					}
+			}),
+		)
+		seg.SetNameTable(&pipe.Context().Names)
+
+		return followLog(pipe, *filePath, tf, startEnd, *lastHours > 0 && startEnd, func() {
+			scores := engine.ClassifyNames(identityEvents)
+			forcePCSet := make(map[string]struct{}, len(forcePC))
+			for _, n := range forcePC {
+				forcePCSet[n] = struct{}{}
+				if _, ok := scores[n]; !ok {
+					scores[n] = engine.IdentityScore{Name: n}
 				}
-				forceNPCSet := make(map[string]struct{}, len(forceNPC))
-				for _, n := range forceNPC {
-					forceNPCSet[n] = struct{}{}
-					if _, ok := scores[n]; !ok {
-						scores[n] = engine.IdentityScore{Name: n}
-					}
+			}
+			forceNPCSet := make(map[string]struct{}, len(forceNPC))
+			for _, n := range forceNPC {
+				forceNPCSet[n] = struct{}{}
+				if _, ok := scores[n]; !ok {
+					scores[n] = engine.IdentityScore{Name: n}
 				}
-				engine.ApplyIdentityOverrides(scores, *pcThreshold, forcePCSet, forceNPCSet)
-
-				if *debugIdentities {
-					seen := make(map[string]struct{})
-					for _, ev := range identityEvents {
-						switch ev.Kind {
-						case model.KindMeleeDamage, model.KindNonMeleeDamage:
-							if ev.AmountKnown {
-								if ev.Actor != "" {
-									seen[ev.Actor] = struct{}{}
-								}
-								if ev.Target != "" {
-									seen[ev.Target] = struct{}{}
-								}
+			}
+			engine.ApplyIdentityOverrides(scores, *pcThreshold, forcePCSet, forceNPCSet)
+			seg.SetIdentityScores(scores)
+
+			if *debugIdentities {
+				seen := make(map[string]struct{})
+				for _, ev := range identityEvents {
+					switch ev.Kind {
+					case model.KindMeleeDamage, model.KindNonMeleeDamage:
+						if ev.AmountKnown {
+							if ev.Actor != "" {
+								seen[ev.Actor] = struct{}{}
+							}
+							if ev.Target != "" {
+								seen[ev.Target] = struct{}{}
 							}
 						}
 					}
-					rows := make([]engine.IdentityScore, 0, len(seen))
-					for name := range seen {
-						if sc, ok := scores[name]; ok {
-							rows = append(rows, sc)
-						}
-					}
-					sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
-					w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
-					fmt.Fprintln(w, "Name\tScore\tClass\tReasons")
-					for _, sc := range rows {
-						fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", sc.Name, sc.Score, sc.Class.String(), strings.Join(sc.Reasons, ","))
+				}
+				rows := make([]engine.IdentityScore, 0, len(seen))
+				for name := range seen {
+					if sc, ok := scores[name]; ok {
+						rows = append(rows, sc)
 					}
-					_ = w.Flush()
-					fmt.Fprintln(os.Stdout)
 				}
+				sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+				w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+				fmt.Fprintln(w, "Name\tScore\tClass\tReasons")
+				for _, sc := range rows {
+					fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", sc.Name, sc.Score, sc.Class.String(), strings.Join(sc.Reasons, ","))
+				}
+				_ = w.Flush()
+				fmt.Fprintln(os.Stdout)
+			}
 
-				encs := seg.Snapshot()
-				if !*includePCTargets {
-					filt := encs[:0]
-					for _, e := range encs {
-						if sc, ok := scores[e.Target]; ok {
-							if sc.Class == engine.IdentityLikelyPC {
-								continue
-							}
+			encs := seg.Snapshot()
+			if !*includePCTargets {
+				filt := encs[:0]
+				for _, e := range encs {
+					if sc, ok := scores[e.Target]; ok {
+						if sc.Class == engine.IdentityLikelyPC {
+							continue
 						}
-						filt = append(filt, e)
 					}
-					encs = filt
-				}
-				if len(encs) > 0 {
-					latest := encs[len(encs)-1]
-					printEncounters([]*engine.Encounter{latest})
-					fmt.Fprintln(os.Stdout)
+					filt = append(filt, e)
 				}
-				dirty = false
+				encs = filt
 			}
-		}
-	}
-
-	f, err := os.Open(*filePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to open file: %v\n", err)
-		return 1
+			encs = engine.FilterEncountersByZone(encs, *zone)
+			if len(encs) > 0 {
+				latest := encs[len(encs)-1]
+				printEncounters(seg, []*engine.Encounter{latest})
+				fmt.Fprintln(os.Stdout)
+			}
+		})
 	}
-	defer f.Close()
 
 	playerName, _ := parse.PlayerNameFromLogPath(*filePath)
-	ctx := &model.ParseContext{LocalActorName: playerName}
 	seg := engine.NewEncounterSegmenter(*idleTimeout, playerName)
-
-	it := parse.ParseFile(f, ctx, time.Local)
+	seg.TargetSwitchGap = *targetSwitchGap
+	// Identities are scored over the whole log before segmenting, so events are collected
+	// first and replayed into the segmenter below.
 	events := make([]model.Event, 0, 1024)
-	for it.Next() {
-		ev := it.Event()
-		if !tf.Allow(ev.Timestamp) {
-			continue
-		}
-		if playerName != "" {
-			if ev.Actor == "YOU" {
-				ev.Actor = playerName
-			}
-			if ev.Target == "YOU" {
-				ev.Target = playerName
-			}
-		}
+	pipe := pipeline.ForLog(*filePath, rules, loc, tf, noteClockJump, pipeline.Sink(func(ev model.Event) {
 		events = append(events, ev)
-	}
-	if err := it.Err(); err != nil {
+	}))
+	seg.SetNameTable(&pipe.Context().Names)
+	if err := pipe.Run(context.Background(), pipeline.File(*filePath, tf.Cutoff)); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to read file: %v\n", err)
 		return 1
 	}
@@ -483,6 +309,7 @@ func runEncounters(args []string) int {
 		}
 	}
 	engine.ApplyIdentityOverrides(scores, *pcThreshold, forcePCSet, forceNPCSet)
+	seg.SetIdentityScores(scores)
 
 	if *debugIdentities {
 		seen := make(map[string]struct{})
@@ -528,17 +355,21 @@ func runEncounters(args []string) int {
 		seg.Process(ev)
 	}
 
-	encs := seg.Finalize()
-	printEncounters(encs)
+	encs := engine.FilterEncountersByZone(seg.Finalize(), *zone)
+	if *groupByZone {
+		encs = engine.GroupEncountersByZone(encs)
+	}
+	printEncounters(seg, encs)
 	return 0
 }
 
-func printEncounters(encs []*engine.Encounter) {
+func printEncounters(seg *engine.EncounterSegmenter, encs []*engine.Encounter) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
-	fmt.Fprintln(w, "Target\tStart\tEnd\tDurationSeconds\tTotalDamage\tDPS(encounter)")
+	fmt.Fprintln(w, "Target\tZone\tStart\tEnd\tDurationSeconds\tTotalDamage\tDPS(encounter)")
 	for _, enc := range encs {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%.1f\t%d\t%.1f\n",
-			enc.Target,
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.1f\t%d\t%.1f\n",
+			seg.DisplayName(enc.Target),
+			enc.Zone,
 			enc.Start.Format(time.RFC3339),
 			enc.End.Format(time.RFC3339),
 			enc.DurationSeconds(),
@@ -550,7 +381,7 @@ func printEncounters(encs []*engine.Encounter) {
 
 	for _, enc := range encs {
 		fmt.Fprintln(os.Stdout)
-		fmt.Fprintf(os.Stdout, "Encounter: %s\n", enc.Target)
+		fmt.Fprintf(os.Stdout, "Encounter: %s\n", seg.DisplayName(enc.Target))
 		aw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
 		fmt.Fprintln(aw, "Actor\tMelee\tNonMelee\tTotal\tDPS(enc)\tSDPS\tSec")
 		actors := enc.ActorsSortedByTotal()
@@ -570,9 +401,344 @@ func printEncounters(encs []*engine.Encounter) {
 			if activeSec > 0 {
 				sdps = float64(st.Total) / activeSec
 			}
-			fmt.Fprintf(aw, "%s\t%d\t%d\t%d\t%.1f\t%.1f\t%.0f\n", st.Actor, st.Melee, st.NonMelee, st.Total, dpsEnc, sdps, activeSec)
+			fmt.Fprintf(aw, "%s\t%d\t%d\t%d\t%.1f\t%.1f\t%.0f\n", seg.DisplayName(st.Actor), st.Melee, st.NonMelee, st.Total, dpsEnc, sdps, activeSec)
 		}
 		_ = aw.Flush()
+
+		if len(enc.ByHealer) > 0 {
+			hw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(hw, "Healer\tTotal\tHPS(enc)\tHeals\tCrit%")
+			for _, st := range enc.HealersSortedByTotal() {
+				hpsEnc := 0.0
+				if encSec > 0 {
+					hpsEnc = float64(st.Total) / encSec
+				}
+				critPct := 0.0
+				if st.Heals > 0 {
+					critPct = (float64(st.CritHeals) / float64(st.Heals)) * 100
+				}
+				fmt.Fprintf(hw, "%s\t%d\t%.1f\t%d\t%.1f\n", seg.DisplayName(st.Healer), st.Total, hpsEnc, st.Heals, critPct)
+			}
+			_ = hw.Flush()
+		}
+
+		if len(enc.ByDefender) > 0 {
+			if enc.Rampages > 0 || enc.WildRampages > 0 || enc.Flurries > 0 {
+				fmt.Fprintf(os.Stdout, "Special attacks: rampage=%d wild_rampage=%d flurry=%d\n", enc.Rampages, enc.WildRampages, enc.Flurries)
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(tw, "Defender\tTaken\tHits\tMaxHit\tRampage\tWildRampage\tFlurry\tDodge\tParry\tRiposte\tBlock\tMiss\tAbsorbed")
+			for _, st := range enc.DefendersSortedByTotal() {
+				special := func(m model.AttackModifier) int64 {
+					if r := st.ByModifier[m]; r != nil {
+						return r.Total
+					}
+					return 0
+				}
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n",
+					seg.DisplayName(st.Defender), st.Total, st.Hits, st.MaxHit,
+					special(model.ModifierRampage), special(model.ModifierWildRampage), special(model.ModifierFlurry),
+					st.Dodges, st.Parries, st.Ripostes, st.Blocks, st.Misses, st.Absorbed,
+				)
+			}
+			_ = tw.Flush()
+		}
+
+		if len(enc.ByStatusSubject) > 0 {
+			sw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(sw, "Subject\tStuns\tStunnedSec\tStunResist\tStunAvoid\tFailedTaunt\tStagger\tDelirium")
+			for _, st := range enc.StatusSubjectsSorted() {
+				fmt.Fprintf(sw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n",
+					seg.DisplayName(st.Subject), st.Stuns, st.StunnedSec, st.StunResists, st.StunAvoids,
+					st.FailedTaunts, st.Staggers, st.Delirium,
+				)
+			}
+			_ = sw.Flush()
+		}
+
+		if len(enc.ByCast) > 0 {
+			cw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(cw, "Caster\tSpell\tStarted\tLanded\tInterrupted\tFizzled\tRecovered\tResisted")
+			for _, st := range enc.SpellCastsSorted() {
+				spell := st.Spell
+				if spell == "" {
+					spell = "(unknown)"
+				}
+				fmt.Fprintf(cw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\n",
+					seg.DisplayName(st.Caster), spell, st.Started, st.Landed, st.Interrupted,
+					st.Fizzled, st.Recovered, st.Resisted,
+				)
+			}
+			_ = cw.Flush()
+		}
+
+		for _, rs := range []struct {
+			label string
+			stats map[string]*engine.SpellResistStats
+		}{
+			{"Spell", enc.SpellResists},
+			{"IncomingSpell", enc.IncomingResists},
+		} {
+			if len(rs.stats) == 0 {
+				continue
+			}
+			rw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintf(rw, "%s\tLanded\tResisted\tResist%%\n", rs.label)
+			for _, st := range engine.SpellResistsSorted(rs.stats) {
+				fmt.Fprintf(rw, "%s\t%d\t%d\t%.1f\n", st.Spell, st.Landed, st.Resisted, st.ResistPct())
+			}
+			_ = rw.Flush()
+		}
+	}
+}
+
+func runLoot(args []string) int {
+	fs := flag.NewFlagSet("loot", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	filePath := fs.String("file", "", "path to EverQuest combat log")
+	sessionGap := fs.Duration("session-gap", engine.DefaultSessionGap, "quiet time that starts a new session")
+	zone := fs.String("zone", "", "only show loot and experience from this zone")
+	lastHours := fs.Float64("last-hours", 0, "only ingest events from the last N hours (0 disables)")
+	var rulePacks multiStringFlag
+	fs.Var(&rulePacks, "rules", "JSON rule pack layered over the built-in rules (repeatable)")
+	tz := fs.String("tz", "", "time zone the log was written in: IANA name or UTC offset (default: this machine's)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *filePath == "" {
+		fmt.Fprintln(os.Stderr, "--file is required")
+		return 2
+	}
+	rules, err := parse.LoadRuleSet(rulePacks...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load rules: %v\n", err)
+		return 2
+	}
+	loc, err := parse.LoadLocation(*tz)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --tz: %v\n", err)
+		return 2
+	}
+
+	tf := engine.NewTimeFilterLastHours(*lastHours, time.Now())
+	loot := engine.NewLootLog(*sessionGap)
+	pipe := pipeline.ForLog(*filePath, rules, loc, tf, noteClockJump, pipeline.Sink(loot.Process))
+	if err := pipe.Run(context.Background(), pipeline.File(*filePath, tf.Cutoff)); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read file: %v\n", err)
+		return 1
+	}
+
+	printLoot(loot.Sessions(), *zone)
+	return 0
+}
+
+// noteClockJump warns on stderr where the log's clock jumped back in a way --tz does not
+// explain.
+func noteClockJump(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "note: "+format+"\n", args...)
+}
+
+// followLog tails the log at path into pipe until interrupted, after reading the part tf
+// allows when preload is set, and calls render at most once a second while events are
+// coming out of pipe. It returns the command's exit status.
+func followLog(pipe *pipeline.Pipeline, path string, tf engine.TimeFilter, startEnd, preload bool, render func()) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// mu serialises the tailed lines with render, which reads the sinks.
+	var mu sync.Mutex
+	dirty := false
+	pipe.Then(pipeline.Func(func(*model.Event) bool {
+		dirty = true
+		return true
+	}))
+
+	if preload {
+		if err := pipe.Run(ctx, pipeline.File(path, tf.Cutoff)); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to preload file: %v\n", err)
+			return 1
+		}
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- pipe.Run(ctx, pipeline.Tail(path, startEnd, &mu)) }()
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return 0
+		case err := <-errCh:
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "tail error: %v\n", err)
+				return 1
+			}
+			return 0
+		case <-ticker.C:
+			mu.Lock()
+			if dirty {
+				render()
+				dirty = false
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+func runIndex(args []string) int {
+	fs := flag.NewFlagSet("index", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	filePath := fs.String("file", "", "path to EverQuest combat log")
+	var rulePacks multiStringFlag
+	fs.Var(&rulePacks, "rules", "JSON rule pack layered over the built-in rules (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *filePath == "" {
+		fmt.Fprintln(os.Stderr, "--file is required")
+		return 2
+	}
+	rules, err := parse.LoadRuleSet(rulePacks...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load rules: %v\n", err)
+		return 2
+	}
+
+	ix, err := logindex.Update(*filePath, rules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to index file: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "%s: %d minutes over %d bytes\n", logindex.SidecarPath(*filePath), len(ix.Entries), ix.Size)
+	return 0
+}
+
+func runCoverage(args []string) int {
+	fs := flag.NewFlagSet("coverage", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	filePath := fs.String("file", "", "path to EverQuest combat log")
+	top := fs.Int("top", 25, "number of unrecognised templates to list (0 lists all)")
+	failUnder := fs.Float64("fail-under", 0, "exit 1 when under this percentage of lines is recognised")
+	failUnderDamage := fs.Float64("fail-under-damage", 0, "exit 1 when under this percentage of damage lines is recognised")
+	var rulePacks multiStringFlag
+	fs.Var(&rulePacks, "rules", "JSON rule pack layered over the built-in rules (repeatable)")
+	tz := fs.String("tz", "", "time zone the log was written in: IANA name or UTC offset (default: this machine's)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *filePath == "" {
+		fmt.Fprintln(os.Stderr, "--file is required")
+		return 2
+	}
+	rules, err := parse.LoadRuleSet(rulePacks...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load rules: %v\n", err)
+		return 2
+	}
+	loc, err := parse.LoadLocation(*tz)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --tz: %v\n", err)
+		return 2
+	}
+
+	f, err := os.Open(*filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open file: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	playerName, _ := parse.PlayerNameFromLogPath(*filePath)
+	ctx := &model.ParseContext{LocalActorName: playerName}
+	census := parse.NewCensus()
+	if err := pipeline.New(rules, ctx, loc, pipeline.Sink(census.Add)).Parse(f); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read file: %v\n", err)
+		return 1
+	}
+
+	lines, damage := 100*census.Coverage(), 100*census.DamageCoverage()
+	fmt.Fprintf(os.Stdout, "Lines recognised:        %d/%d (%.1f%%)\n", census.Recognized, census.Lines, lines)
+	fmt.Fprintf(os.Stdout, "Damage lines recognised: %d/%d (%.1f%%)\n", census.DamageRecognized, census.DamageLines, damage)
+
+	templates := census.Templates(ctx)
+	if len(templates) > 0 {
+		fmt.Fprintln(os.Stdout)
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "Count\tShare\tTemplate\tExample")
+		for i, t := range templates {
+			if *top > 0 && i >= *top {
+				break
+			}
+			share := 100 * float64(t.Count) / float64(census.Lines)
+			fmt.Fprintf(w, "%d\t%.2f%%\t%s\t%s\n", t.Count, share, clip(t.Text, 90), clip(t.Example, 120))
+		}
+		_ = w.Flush()
+		if *top > 0 && len(templates) > *top {
+			fmt.Fprintf(os.Stdout, "... %d more templates\n", len(templates)-*top)
+		}
+	}
+
+	if lines < *failUnder || damage < *failUnderDamage {
+		fmt.Fprintf(os.Stderr, "coverage below threshold: lines %.1f%% (min %.1f%%), damage lines %.1f%% (min %.1f%%)\n",
+			lines, *failUnder, damage, *failUnderDamage)
+		return 1
+	}
+	return 0
+}
+
+// clip shortens s to at most n bytes for table output.
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
+func printLoot(sessions []*engine.LootSession, zone string) {
+	n := 0
+	for _, sess := range sessions {
+		// Narrow the session first so the header totals count only the zones listed below.
+		sess = sess.InZone(zone)
+		if sess == nil || len(sess.Zones) == 0 {
+			continue
+		}
+		n++
+		if n > 1 {
+			fmt.Fprintln(os.Stdout)
+		}
+		fmt.Fprintf(os.Stdout, "Session %d: %s - %s drops=%d aa=%d xp=%d\n",
+			n, sess.Start.Format(time.RFC3339), sess.End.Format(time.RFC3339),
+			sess.Drops(), sess.AAPoints(), sess.XPEvents(),
+		)
+		for _, z := range sess.Zones {
+			name := z.Zone
+			if name == "" {
+				name = "(unknown zone)"
+			}
+			types := make([]string, 0, len(z.XPByType))
+			for t, c := range z.XPByType {
+				types = append(types, fmt.Sprintf("%s=%d", t, c))
+			}
+			sort.Strings(types)
+			fmt.Fprintf(os.Stdout, "\nZone: %s  drops=%d aa=%d xp=%d", name, len(z.Drops), z.AAPoints, z.XPEvents)
+			if len(types) > 0 {
+				fmt.Fprintf(os.Stdout, " (%s)", strings.Join(types, " "))
+			}
+			fmt.Fprintln(os.Stdout)
+			if len(z.Drops) == 0 {
+				continue
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(w, "Time\tLooter\tQty\tItem\tCorpse")
+			for _, d := range z.Drops {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", d.Timestamp.Format(time.RFC3339), d.Looter, d.Quantity, d.Item, d.Corpse)
+			}
+			_ = w.Flush()
+		}
+	}
+	if n == 0 {
+		fmt.Fprintln(os.Stdout, "No loot or experience found.")
 	}
 }
 
@@ -17,7 +17,7 @@ import (
 	"github.com/ZehenForever/eqemu-log-parser/internal/engine"
 	"github.com/ZehenForever/eqemu-log-parser/internal/model"
 	"github.com/ZehenForever/eqemu-log-parser/internal/parse"
-	"github.com/ZehenForever/eqemu-log-parser/internal/tail"
+	"github.com/ZehenForever/eqemu-log-parser/internal/pipeline"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
@@ -29,16 +29,21 @@ type App struct {
 	filePath  string
 	tailing   bool
 	lastHours float64
+	// logTZ is the time zone the log was written in ("" for this machine's) and logLoc
+	// the location it names.
+	logTZ  string
+	logLoc *time.Location
 
 	playerName string
-	pctx       *model.ParseContext
 	seg        *engine.EncounterSegmenter
-	timeFilter engine.TimeFilter
+	// pipe parses the log and feeds the segmenter and the hub; the Tail source pushes lines
+	// through it under mu.
+	pipe *pipeline.Pipeline
 
-	tlr    *tail.Tailer
 	cancel context.CancelFunc
 
 	includePCTargets bool
+	rollupPets       bool
 
 	encListCacheAt      time.Time
 	encListCacheTTL     time.Duration
@@ -116,6 +121,12 @@ func (a *App) SetIncludePCTargets(include bool) {
 	a.mu.Unlock()
 }
 
+func (a *App) SetRollupPets(rollup bool) {
+	a.mu.Lock()
+	a.rollupPets = rollup
+	a.mu.Unlock()
+}
+
 func (a *App) SetLastHours(hours float64) {
 	if hours < 0 {
 		hours = 0
@@ -132,6 +143,30 @@ func (a *App) GetLastHours() float64 {
 	return h
 }
 
+// SetLogTimezone sets the time zone the next log to be started was written in: an IANA
+// name, a UTC offset such as "UTC-5", or "" for this machine's zone.
+func (a *App) SetLogTimezone(name string) error {
+	loc, err := parse.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.tailing {
+		return errors.New("stop tailing before changing the log time zone")
+	}
+	a.logTZ = strings.TrimSpace(name)
+	a.logLoc = loc
+	return nil
+}
+
+func (a *App) GetLogTimezone() string {
+	a.mu.RLock()
+	tz := a.logTZ
+	a.mu.RUnlock()
+	return tz
+}
+
 func (a *App) Start(path string, startAtEnd bool) error {
 	if path == "" {
 		return errors.New("empty path")
@@ -145,54 +180,31 @@ func (a *App) Start(path string, startAtEnd bool) error {
 	playerName, _ := parse.PlayerNameFromLogPath(path)
 	a.filePath = path
 	a.playerName = playerName
-	a.pctx = &model.ParseContext{LocalActorName: playerName}
-	a.seg = engine.NewEncounterSegmenter(8*time.Second, playerName)
 	lastHours := a.lastHours
 	tf := engine.NewTimeFilterLastHours(lastHours, time.Now())
-	a.timeFilter = tf
+	if a.logLoc == nil {
+		a.logLoc = time.Local
+	}
+	loc := a.logLoc
+	seg := engine.NewEncounterSegmenter(8*time.Second, playerName)
+	pipe := pipeline.ForLog(path, nil, loc, tf, log.Printf,
+		pipeline.Sink(seg.Process),
+		pipeline.Sink(a.maybeEnqueueHubDamage),
+	)
+	seg.SetNameTable(&pipe.Context().Names)
+	a.seg = seg
+	a.pipe = pipe
 	a.tailing = true
 	ctx, cancel := context.WithCancel(context.Background())
 	a.cancel = cancel
 	a.mu.Unlock()
 
-	seg := a.seg
-	pctx := a.pctx
 	if lastHours > 0 {
-		f, err := os.Open(path)
-		if err != nil {
-			cancel()
-			a.mu.Lock()
-			a.tailing = false
-			a.cancel = nil
-			a.tlr = nil
-			a.mu.Unlock()
-			return err
-		}
-		defer func() { _ = f.Close() }()
-
-		it := parse.ParseFile(f, pctx, time.Local)
-		for it.Next() {
-			ev := it.Event()
-			if !tf.Allow(ev.Timestamp) {
-				continue
-			}
-			if playerName != "" {
-				if ev.Actor == "YOU" {
-					ev.Actor = playerName
-				}
-				if ev.Target == "YOU" {
-					ev.Target = playerName
-				}
-			}
-			seg.Process(ev)
-			a.maybeEnqueueHubDamage(ev, playerName)
-		}
-		if err := it.Err(); err != nil {
+		if err := pipe.Run(ctx, pipeline.File(path, tf.Cutoff)); err != nil {
 			cancel()
 			a.mu.Lock()
 			a.tailing = false
 			a.cancel = nil
-			a.tlr = nil
 			a.mu.Unlock()
 			return err
 		}
@@ -203,26 +215,10 @@ func (a *App) Start(path string, startAtEnd bool) error {
 		startTailAtEnd = true
 	}
 
-	tlr, err := tail.NewTailer(path, tail.TailOptions{StartAtEnd: startTailAtEnd})
-	if err != nil {
-		a.mu.Lock()
-		a.tailing = false
-		a.cancel = nil
-		a.mu.Unlock()
-		return err
-	}
-
-	a.mu.Lock()
-	a.tlr = tlr
-	a.mu.Unlock()
-
 	go func() {
-		_ = tlr.Run(ctx, func(line string) {
-			a.onLine(line)
-		})
+		_ = pipe.Run(ctx, pipeline.Tail(path, startTailAtEnd, &a.mu))
 		a.mu.Lock()
 		a.tailing = false
-		a.tlr = nil
 		a.cancel = nil
 		a.mu.Unlock()
 	}()
@@ -230,46 +226,16 @@ func (a *App) Start(path string, startAtEnd bool) error {
 	return nil
 }
 
-func (a *App) onLine(line string) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	if a.seg == nil || a.pctx == nil {
-		return
-	}
-	ev, ok := parse.ParseLine(a.pctx, line, time.Local)
-	if !ok {
-		return
-	}
-	if !a.timeFilter.Allow(ev.Timestamp) {
-		return
-	}
-	if a.playerName != "" {
-		if ev.Actor == "YOU" {
-			ev.Actor = a.playerName
-		}
-		if ev.Target == "YOU" {
-			ev.Target = a.playerName
-		}
-	}
-	a.seg.Process(ev)
-	a.maybeEnqueueHubDamage(ev, a.playerName)
-}
-
 func (a *App) Stop() error {
 	a.mu.Lock()
 	cancel := a.cancel
-	tlr := a.tlr
 	a.cancel = nil
-	a.tlr = nil
 	a.tailing = false
 	a.mu.Unlock()
 
 	if cancel != nil {
 		cancel()
 	}
-	if tlr != nil {
-		_ = tlr.Stop()
-	}
 	return nil
 }
 
@@ -413,18 +379,16 @@ func (a *App) ListHubRooms(hubURL string) (RoomListUI, error) {
 	return out, nil
 }
 
-func (a *App) maybeEnqueueHubDamage(ev model.Event, localPlayer string) {
+// maybeEnqueueHubDamage is the pipeline sink that publishes the damage of player-like
+// actors to the hub. "YOU" has already been rewritten to the player's name.
+func (a *App) maybeEnqueueHubDamage(ev model.Event) {
 	if a.hub == nil {
 		return
 	}
 	if !ev.AmountKnown {
 		return
 	}
-	actor := ev.Actor
-	if actor == "YOU" && localPlayer != "" {
-		actor = localPlayer
-	}
-	actor = strings.TrimSpace(actor)
+	actor := strings.TrimSpace(ev.Actor)
 	if actor == "" {
 		return
 	}
@@ -484,6 +448,7 @@ func (a *App) GetSnapshot() SnapshotUI {
 	filePath := a.filePath
 	tailing := a.tailing
 	includePCTargets := a.includePCTargets
+	rollupPets := a.rollupPets
 	lastHours := a.lastHours
 	a.mu.RUnlock()
 
@@ -492,7 +457,7 @@ func (a *App) GetSnapshot() SnapshotUI {
 		out.LastHours = lastHours
 		return out
 	}
-	snap := seg.BuildSnapshot(time.Now(), filePath, tailing, engine.SnapshotOptions{IncludePCTargets: includePCTargets, LimitEncounters: 100, CoalesceTargets: true})
+	snap := seg.BuildSnapshot(time.Now(), filePath, tailing, engine.SnapshotOptions{IncludePCTargets: includePCTargets, LimitEncounters: 100, CoalesceTargets: true, RollupPets: rollupPets})
 	out := SnapshotToUI(snap)
 	out.LastHours = lastHours
 	return out
@@ -569,13 +534,14 @@ func (a *App) GetEncounter(target string) (EncounterViewUI, error) {
 	filePath := a.filePath
 	tailing := a.tailing
 	includePCTargets := a.includePCTargets
+	rollupPets := a.rollupPets
 	a.mu.RUnlock()
 
 	if seg == nil {
 		return EncounterViewUI{}, errors.New("not started")
 	}
 
-	view, ok := seg.BuildEncounterView(time.Now(), filePath, tailing, engine.SnapshotOptions{IncludePCTargets: includePCTargets, LimitEncounters: 0, CoalesceTargets: true}, target)
+	view, ok := seg.BuildEncounterView(time.Now(), filePath, tailing, engine.SnapshotOptions{IncludePCTargets: includePCTargets, LimitEncounters: 0, CoalesceTargets: true, RollupPets: rollupPets}, target)
 	if !ok {
 		return EncounterViewUI{}, errors.New("encounter not found")
 	}
@@ -686,13 +652,14 @@ func (a *App) GetEncounterByID(encounterId string) (EncounterViewUI, error) {
 	filePath := a.filePath
 	tailing := a.tailing
 	includePCTargets := a.includePCTargets
+	rollupPets := a.rollupPets
 	a.mu.RUnlock()
 
 	if seg == nil {
 		return EncounterViewUI{}, errors.New("not started")
 	}
 
-	view, ok := seg.BuildEncounterViewExact(time.Now(), filePath, tailing, engine.SnapshotOptions{IncludePCTargets: includePCTargets, LimitEncounters: 0, CoalesceTargets: true}, target, start, end)
+	view, ok := seg.BuildEncounterViewExact(time.Now(), filePath, tailing, engine.SnapshotOptions{IncludePCTargets: includePCTargets, LimitEncounters: 0, CoalesceTargets: true, RollupPets: rollupPets}, target, start, end)
 	if !ok {
 		return EncounterViewUI{}, errors.New("encounter not found")
 	}
@@ -716,13 +683,14 @@ func (a *App) GetEncounterByKey(encounterKey string) (EncounterViewUI, error) {
 	filePath := a.filePath
 	tailing := a.tailing
 	includePCTargets := a.includePCTargets
+	rollupPets := a.rollupPets
 	a.mu.RUnlock()
 
 	if seg == nil {
 		return EncounterViewUI{}, errors.New("not started")
 	}
 
-	view, ok := seg.BuildEncounterViewByKey(time.Now(), filePath, tailing, engine.SnapshotOptions{IncludePCTargets: includePCTargets, LimitEncounters: 0, CoalesceTargets: true}, target, start)
+	view, ok := seg.BuildEncounterViewByKey(time.Now(), filePath, tailing, engine.SnapshotOptions{IncludePCTargets: includePCTargets, LimitEncounters: 0, CoalesceTargets: true, RollupPets: rollupPets}, target, start)
 	if !ok {
 		return EncounterViewUI{}, errors.New("encounter not found")
 	}
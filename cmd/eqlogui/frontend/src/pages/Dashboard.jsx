@@ -1,7 +1,7 @@
 import React, { useEffect, useLayoutEffect, useMemo, useRef, useState } from 'react'
 import { Link } from 'react-router-dom'
 
-import { ConfigureHub, ConfigureSubscribe, GetConfigDefaults, GetDamageBreakdownByKey, GetEncounterByKey, GetPlayersSeries, GetRemotePlayersSeries, ListHubRooms, PublishingStatus, SelectLogFile, Start, StartPublishing, StartSubscribe, Stop, StopPublishing, StopSubscribe, SubscribeStatus, SetIncludePCTargets, SetLastHours } from '../../wailsjs/go/main/App'
+import { ConfigureHub, ConfigureSubscribe, GetConfigDefaults, GetDamageBreakdownByKey, GetEncounterByKey, GetPlayersSeries, GetRemotePlayersSeries, ListHubRooms, PublishingStatus, SelectLogFile, Start, StartPublishing, StartSubscribe, Stop, StopPublishing, StopSubscribe, SubscribeStatus, SetIncludePCTargets, SetLastHours, SetLogTimezone, SetRollupPets } from '../../wailsjs/go/main/App'
 
 import { useSnapshot } from '../hooks/useSnapshot'
 
@@ -51,7 +51,9 @@ export default function Dashboard() {
   const [selectedFile, setSelectedFile] = useState('')
   const [startAtEnd, setStartAtEnd] = useState(true)
   const [includePCTargets, setIncludePCTargets] = useState(false)
+  const [rollupPets, setRollupPets] = useState(false)
   const [lastHours, setLastHours] = useState(0)
+  const [logTimezone, setLogTimezone] = useState('')
 
   const [settingsOpen, setSettingsOpen] = useState(true)
 
@@ -707,6 +709,16 @@ export default function Dashboard() {
     }
   }
 
+  const onToggleRollupPets = async (v) => {
+    setRollupPets(v)
+    try {
+      await SetRollupPets(v)
+      refreshNow()
+    } catch (e) {
+      setUIError(String(e))
+    }
+  }
+
   const onChangeLastHours = async (v) => {
     const raw = Number(v)
     const next = Number.isFinite(raw) && raw > 0 ? raw : 0
@@ -720,6 +732,15 @@ export default function Dashboard() {
     }
   }
 
+  const onCommitLogTimezone = async () => {
+    setUIError('')
+    try {
+      await SetLogTimezone(logTimezone.trim())
+    } catch (e) {
+      setUIError(String(e))
+    }
+  }
+
   return (
     <div className="space-y-4">
       <section className="rounded-lg border border-slate-800 bg-slate-900/30 p-4">
@@ -858,6 +879,17 @@ export default function Dashboard() {
                     onChange={(e) => onToggleIncludePC(e.target.checked)}
                   />
                 </div>
+                <div className="mt-3 flex items-center justify-between">
+                  <div>
+                    <div className="text-sm font-medium">Roll up pets into owners</div>
+                    <div className="text-xs text-slate-400">Default: off</div>
+                  </div>
+                  <input
+                    type="checkbox"
+                    checked={rollupPets}
+                    onChange={(e) => onToggleRollupPets(e.target.checked)}
+                  />
+                </div>
               </div>
 
 		  <div className="rounded-md border border-slate-800 bg-slate-950/30 p-3">
@@ -879,6 +911,25 @@ export default function Dashboard() {
 			</div>
 		  </div>
 
+		  <div className="rounded-md border border-slate-800 bg-slate-950/30 p-3">
+			<div className="text-sm font-medium">Log time zone</div>
+			<div className="mt-2 flex items-center justify-between gap-3">
+				<div className="text-xs text-slate-400">
+					<div>Blank = this computer</div>
+					<div>e.g. America/Chicago, UTC-5</div>
+				</div>
+				<input
+					type="text"
+					value={logTimezone}
+					placeholder="Local"
+					onChange={(e) => setLogTimezone(e.target.value)}
+					onBlur={onCommitLogTimezone}
+					disabled={tailing}
+					className="w-40 rounded-md border border-slate-800 bg-slate-950 px-2 py-1 text-sm font-mono text-slate-100"
+				/>
+			</div>
+		  </div>
+
 		  <div className="rounded-md border border-slate-800 bg-slate-950/30 p-3 md:col-span-3">
 			<div className="flex items-center justify-between">
 				<div className="text-sm font-medium">Share</div>
@@ -1416,12 +1467,13 @@ export default function Dashboard() {
                     <th className="px-3 py-2 text-right font-medium whitespace-nowrap">Min</th>
                     <th className="px-3 py-2 text-right font-medium whitespace-nowrap">Avg</th>
                     <th className="px-3 py-2 text-right font-medium whitespace-nowrap">Crit%</th>
+                    <th className="px-3 py-2 text-right font-medium whitespace-nowrap">Crippling%</th>
                   </tr>
                 </thead>
                 <tbody>
-                  {breakdownData.rows.map((r) => (
-                    <tr key={r.name} className="border-b border-slate-900">
-                      <td className="px-3 py-2 text-slate-200 whitespace-nowrap">{r.name}</td>
+                  {[...breakdownData.rows, ...(breakdownData.pets || []).map((p) => ({ ...p, pet: true }))].map((r) => (
+                    <tr key={(r.pet ? 'pet:' : '') + r.name} className="border-b border-slate-900">
+                      <td className="px-3 py-2 text-slate-200 whitespace-nowrap">{r.pet ? `↳ ${r.name}` : r.name}</td>
                       <td className="px-3 py-2 text-right font-mono tabular-nums whitespace-nowrap text-slate-200">{formatFloat1(r.pctPlayer || 0)}%</td>
                       <td className="px-3 py-2 text-right font-mono tabular-nums whitespace-nowrap text-slate-200" title={formatInt(r.damage || 0)}>
                         {formatCompact(r.damage || 0)}
@@ -1438,6 +1490,7 @@ export default function Dashboard() {
                       </td>
                       <td className="px-3 py-2 text-right font-mono tabular-nums whitespace-nowrap text-slate-200">{formatFloat1(r.avgHit || 0)}</td>
                       <td className="px-3 py-2 text-right font-mono tabular-nums whitespace-nowrap text-slate-200">{formatFloat1(r.critPct || 0)}%</td>
+                      <td className="px-3 py-2 text-right font-mono tabular-nums whitespace-nowrap text-slate-200">{formatFloat1(r.cripplingPct || 0)}%</td>
                     </tr>
                   ))}
                 </tbody>
@@ -20,6 +20,7 @@ export default function EncounterDetail() {
   const [backendConnected, setBackendConnected] = useState(null)
 
   const [pollMs] = useState(750)
+  const [npcChatOnly, setNpcChatOnly] = useState(false)
 
   useEffect(() => {
     let alive = true
@@ -59,6 +60,7 @@ export default function EncounterDetail() {
         <div>
           <div className="text-sm text-slate-400">Encounter</div>
           <div className="text-xl font-semibold">{encounter?.target || ''}</div>
+          {encounter?.zone && <div className="text-sm text-slate-400">{encounter.zone}</div>}
         </div>
         <Link to="/" className="text-sm text-slate-200 hover:underline">
           Back
@@ -133,6 +135,289 @@ export default function EncounterDetail() {
               </tbody>
             </table>
           </div>
+
+          {(encounter.healers || []).length > 0 && (
+            <div className="mt-6">
+              <div className="text-sm text-slate-400">
+                Healing{' '}
+                <span className="font-mono tabular-nums" title={formatInt(encounter.totalHealing || 0)}>
+                  {formatCompact(encounter.totalHealing || 0)}
+                </span>{' '}
+                · HPS(enc) <span className="font-mono tabular-nums">{formatFloat1(encounter.hpsEncounter || 0)}</span>
+              </div>
+              <div className="mt-2 overflow-x-auto">
+                <table className="min-w-full text-sm">
+                  <thead className="text-slate-400">
+                    <tr className="border-b border-slate-800">
+                      <th className="py-2 text-left font-medium">Healer</th>
+                      <th className="py-2 text-right font-medium">%Total</th>
+                      <th className="py-2 text-right font-medium">Total</th>
+                      <th className="py-2 text-right font-medium">HPS(enc)</th>
+                      <th className="py-2 text-right font-medium">SHPS</th>
+                      <th className="py-2 text-right font-medium">Heals</th>
+                      <th className="py-2 text-right font-medium">MaxHeal</th>
+                      <th className="py-2 text-right font-medium">Crit%</th>
+                      <th className="py-2 pl-4 text-left font-medium">Top spells</th>
+                    </tr>
+                  </thead>
+                  <tbody>
+                    {(encounter.healers || []).map((h) => (
+                      <tr key={h.healer} className="border-b border-slate-900">
+                        <td className="py-2 pr-4">{h.healer}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatFloat1(h.pctTotal || 0)}%</td>
+                        <td className="py-2 text-right font-mono tabular-nums" title={formatCompact(h.total || 0)}>
+                          {formatInt(h.total || 0)}
+                        </td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatFloat1(h.hpsEncounter || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatFloat1(h.shps || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(h.heals || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums" title={formatInt(h.maxHeal || 0)}>
+                          {formatCompact(h.maxHeal || 0)}
+                        </td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatFloat1(h.critPct || 0)}%</td>
+                        <td className="py-2 pl-4 text-slate-300">
+                          {(h.topSpells || []).map((sp) => sp.spell).join(', ')}
+                        </td>
+                      </tr>
+                    ))}
+                  </tbody>
+                </table>
+              </div>
+            </div>
+          )}
+
+          {(encounter.defenders || []).length > 0 && (
+            <div className="mt-6">
+              <div className="text-sm text-slate-400">
+                Damage taken{' '}
+                <span className="font-mono tabular-nums" title={formatInt(encounter.totalDamageTaken || 0)}>
+                  {formatCompact(encounter.totalDamageTaken || 0)}
+                </span>
+                {(encounter.rampages > 0 || encounter.wildRampages > 0 || encounter.flurries > 0) && (
+                  <span className="ml-3 font-mono tabular-nums">
+                    rampage {encounter.rampages || 0} · wild {encounter.wildRampages || 0} · flurry {encounter.flurries || 0}
+                  </span>
+                )}
+              </div>
+              <div className="mt-2 overflow-x-auto">
+                <table className="min-w-full text-sm">
+                  <thead className="text-slate-400">
+                    <tr className="border-b border-slate-800">
+                      <th className="py-2 text-left font-medium">Defender</th>
+                      <th className="py-2 text-right font-medium">%Total</th>
+                      <th className="py-2 text-right font-medium">Taken</th>
+                      <th className="py-2 text-right font-medium">DTPS(enc)</th>
+                      <th className="py-2 text-right font-medium">Hits</th>
+                      <th className="py-2 text-right font-medium">MaxHit</th>
+                      <th className="py-2 text-right font-medium">Dodge</th>
+                      <th className="py-2 text-right font-medium">Parry</th>
+                      <th className="py-2 text-right font-medium">Riposte</th>
+                      <th className="py-2 text-right font-medium">Block</th>
+                      <th className="py-2 text-right font-medium">Miss</th>
+                      <th className="py-2 text-right font-medium">Avoid%</th>
+                      <th className="py-2 text-right font-medium">Absorbed</th>
+                      <th className="py-2 pl-4 text-left font-medium">Special</th>
+                    </tr>
+                  </thead>
+                  <tbody>
+                    {(encounter.defenders || []).map((d) => (
+                      <tr key={d.defender} className="border-b border-slate-900">
+                        <td className="py-2 pr-4">{d.defender}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatFloat1(d.pctTotal || 0)}%</td>
+                        <td className="py-2 text-right font-mono tabular-nums" title={formatCompact(d.total || 0)}>
+                          {formatInt(d.total || 0)}
+                        </td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatFloat1(d.dtpsEncounter || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(d.hits || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums" title={formatInt(d.maxHit || 0)}>
+                          {formatCompact(d.maxHit || 0)}
+                        </td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(d.dodges || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(d.parries || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(d.ripostes || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(d.blocks || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(d.misses || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatFloat1(d.avoidPct || 0)}%</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(d.absorbed || 0)}</td>
+                        <td className="py-2 pl-4 text-slate-300">
+                          {(d.modifiers || [])
+                            .filter((m) => m.name !== 'Normal')
+                            .map((m) => `${m.name} ${formatCompact(m.total || 0)}`)
+                            .join(', ')}
+                        </td>
+                      </tr>
+                    ))}
+                  </tbody>
+                </table>
+              </div>
+            </div>
+          )}
+
+          {(encounter.spellCasts || []).length > 0 && (
+            <div className="mt-6">
+              <div className="text-sm text-slate-400">Spell casts</div>
+              <div className="mt-2 overflow-x-auto">
+                <table className="min-w-full text-sm">
+                  <thead className="text-slate-400">
+                    <tr className="border-b border-slate-800">
+                      <th className="py-2 text-left font-medium">Caster</th>
+                      <th className="py-2 text-left font-medium">Spell</th>
+                      <th className="py-2 text-right font-medium">Started</th>
+                      <th className="py-2 text-right font-medium">Landed</th>
+                      <th className="py-2 text-right font-medium">Interrupted</th>
+                      <th className="py-2 text-right font-medium">Fizzled</th>
+                      <th className="py-2 text-right font-medium">Recovered</th>
+                      <th className="py-2 text-right font-medium">Resisted</th>
+                    </tr>
+                  </thead>
+                  <tbody>
+                    {(encounter.spellCasts || []).map((c) => (
+                      <tr key={`${c.caster}|${c.spell}`} className="border-b border-slate-900">
+                        <td className="py-2 pr-4">{c.caster}</td>
+                        <td className="py-2 pr-4">{c.spell || '(unknown)'}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(c.started || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(c.landed || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(c.interrupted || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(c.fizzled || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(c.recovered || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(c.resisted || 0)}</td>
+                      </tr>
+                    ))}
+                  </tbody>
+                </table>
+              </div>
+            </div>
+          )}
+
+          {(encounter.spellResists || []).length > 0 && (
+            <div className="mt-6">
+              <div className="text-sm text-slate-400">Spell resists (your spells)</div>
+              <div className="mt-2 overflow-x-auto">
+                <table className="min-w-full text-sm">
+                  <thead className="text-slate-400">
+                    <tr className="border-b border-slate-800">
+                      <th className="py-2 text-left font-medium">Spell</th>
+                      <th className="py-2 text-right font-medium">Landed</th>
+                      <th className="py-2 text-right font-medium">Resisted</th>
+                      <th className="py-2 text-right font-medium">Resist%</th>
+                    </tr>
+                  </thead>
+                  <tbody>
+                    {(encounter.spellResists || []).map((r) => (
+                      <tr key={r.spell} className="border-b border-slate-900">
+                        <td className="py-2 pr-4">{r.spell}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(r.landed || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(r.resisted || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatFloat1(r.resistPct || 0)}%</td>
+                      </tr>
+                    ))}
+                  </tbody>
+                </table>
+              </div>
+            </div>
+          )}
+
+          {(encounter.incomingResists || []).length > 0 && (
+            <div className="mt-6">
+              <div className="text-sm text-slate-400">Spell resists (against you)</div>
+              <div className="mt-2 overflow-x-auto">
+                <table className="min-w-full text-sm">
+                  <thead className="text-slate-400">
+                    <tr className="border-b border-slate-800">
+                      <th className="py-2 text-left font-medium">Spell</th>
+                      <th className="py-2 text-right font-medium">Landed</th>
+                      <th className="py-2 text-right font-medium">Resisted</th>
+                      <th className="py-2 text-right font-medium">Resist%</th>
+                    </tr>
+                  </thead>
+                  <tbody>
+                    {(encounter.incomingResists || []).map((r) => (
+                      <tr key={r.spell} className="border-b border-slate-900">
+                        <td className="py-2 pr-4">{r.spell}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(r.landed || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(r.resisted || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatFloat1(r.resistPct || 0)}%</td>
+                      </tr>
+                    ))}
+                  </tbody>
+                </table>
+              </div>
+            </div>
+          )}
+
+          {(encounter.status || []).length > 0 && (
+            <div className="mt-6">
+              <div className="text-sm text-slate-400">Status</div>
+              <div className="mt-2 overflow-x-auto">
+                <table className="min-w-full text-sm">
+                  <thead className="text-slate-400">
+                    <tr className="border-b border-slate-800">
+                      <th className="py-2 text-left font-medium">Subject</th>
+                      <th className="py-2 text-right font-medium">Stuns</th>
+                      <th className="py-2 text-right font-medium">Stunned(s)</th>
+                      <th className="py-2 text-right font-medium">Stun resist</th>
+                      <th className="py-2 text-right font-medium">Stun avoid</th>
+                      <th className="py-2 text-right font-medium">Failed taunts</th>
+                      <th className="py-2 text-right font-medium">Staggers</th>
+                      <th className="py-2 text-right font-medium">Delirium</th>
+                    </tr>
+                  </thead>
+                  <tbody>
+                    {(encounter.status || []).map((st) => (
+                      <tr key={st.subject} className="border-b border-slate-900">
+                        <td className="py-2 pr-4">{st.subject}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(st.stuns || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(st.stunnedSec || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(st.stunResists || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(st.stunAvoids || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(st.failedTaunts || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(st.staggers || 0)}</td>
+                        <td className="py-2 text-right font-mono tabular-nums">{formatInt(st.delirium || 0)}</td>
+                      </tr>
+                    ))}
+                  </tbody>
+                </table>
+              </div>
+              {(encounter.statusTimeline || []).length > 0 && (
+                <div className="mt-2 max-h-48 space-y-1 overflow-y-auto text-sm">
+                  {(encounter.statusTimeline || []).map((e, i) => (
+                    <div key={`${e.ts}-${i}`} className="flex gap-3">
+                      <span className="font-mono tabular-nums text-slate-500">{new Date(e.ts).toLocaleTimeString()}</span>
+                      <span className="text-slate-300">{e.subject}</span>
+                      <span className="text-slate-400">{e.effect}</span>
+                    </div>
+                  ))}
+                </div>
+              )}
+            </div>
+          )}
+
+          {(encounter.chat || []).length > 0 && (
+            <div className="mt-6">
+              <div className="flex items-center justify-between text-sm text-slate-400">
+                <div>Chat</div>
+                <label className="flex items-center gap-2">
+                  <input type="checkbox" checked={npcChatOnly} onChange={(e) => setNpcChatOnly(e.target.checked)} />
+                  NPC speech only
+                </label>
+              </div>
+              <div className="mt-2 space-y-1 text-sm">
+                {(encounter.chat || [])
+                  .filter((c) => !npcChatOnly || c.channel === 'npc')
+                  .map((c, i) => (
+                    <div key={`${c.ts}-${i}`} className="flex gap-3">
+                      <span className="font-mono tabular-nums text-slate-500">{new Date(c.ts).toLocaleTimeString()}</span>
+                      <span className="text-slate-500">[{c.channel}]</span>
+                      <span className="text-slate-300">
+                        {c.speaker}
+                        {c.target ? ` → ${c.target}` : ''}:
+                      </span>
+                      <span className="text-slate-200">{c.message}</span>
+                    </div>
+                  ))}
+              </div>
+            </div>
+          )}
         </div>
       )}
     </div>
@@ -21,17 +21,280 @@ type ActorStatsViewUI struct {
 	CritPct   float64 `json:"critPct"`
 	AvgCrit   float64 `json:"avgCrit"`
 	Crits     int64   `json:"crits"`
+
+	PetDamage int64 `json:"petDamage"`
+}
+
+type HealSpellViewUI struct {
+	Spell     string  `json:"spell"`
+	Heals     int64   `json:"heals"`
+	Total     int64   `json:"total"`
+	PctHealer float64 `json:"pctHealer"`
+}
+
+type HealerStatsViewUI struct {
+	Healer    string            `json:"healer"`
+	Total     int64             `json:"total"`
+	HPSEnc    float64           `json:"hpsEncounter"`
+	SHPS      float64           `json:"shps"`
+	ActiveSec int64             `json:"activeSec"`
+	PctTotal  float64           `json:"pctTotal"`
+	Heals     int64             `json:"heals"`
+	MaxHeal   int64             `json:"maxHeal"`
+	AvgHeal   float64           `json:"avgHeal"`
+	CritPct   float64           `json:"critPct"`
+	Crits     int64             `json:"crits"`
+	TopSpells []HealSpellViewUI `json:"topSpells"`
+}
+
+type HealTargetViewUI struct {
+	Target   string  `json:"target"`
+	Total    int64   `json:"total"`
+	Heals    int64   `json:"heals"`
+	PctTotal float64 `json:"pctTotal"`
+}
+
+func healersToUI(healers []engine.HealerStatsView) []HealerStatsViewUI {
+	out := make([]HealerStatsViewUI, 0, len(healers))
+	for _, h := range healers {
+		row := HealerStatsViewUI{
+			Healer:    h.Healer,
+			Total:     h.Total,
+			HPSEnc:    h.HPS,
+			SHPS:      h.SHPS,
+			ActiveSec: h.ActiveSec,
+			PctTotal:  h.PctTotal,
+			Heals:     h.Heals,
+			MaxHeal:   h.MaxHeal,
+			AvgHeal:   h.AvgHeal,
+			CritPct:   h.CritPct,
+			Crits:     h.Crits,
+			TopSpells: make([]HealSpellViewUI, 0, len(h.TopSpells)),
+		}
+		for _, sp := range h.TopSpells {
+			row.TopSpells = append(row.TopSpells, HealSpellViewUI{
+				Spell:     sp.Spell,
+				Heals:     sp.Heals,
+				Total:     sp.Total,
+				PctHealer: sp.PctHealer,
+			})
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+func healTargetsToUI(targets []engine.HealTargetView) []HealTargetViewUI {
+	out := make([]HealTargetViewUI, 0, len(targets))
+	for _, t := range targets {
+		out = append(out, HealTargetViewUI{
+			Target:   t.Target,
+			Total:    t.Total,
+			Heals:    t.Heals,
+			PctTotal: t.PctTotal,
+		})
+	}
+	return out
+}
+
+type DamageTakenRowViewUI struct {
+	Name        string  `json:"name"`
+	Hits        int64   `json:"hits"`
+	Total       int64   `json:"total"`
+	MaxHit      int64   `json:"maxHit"`
+	AvgHit      float64 `json:"avgHit"`
+	PctDefender float64 `json:"pctDefender"`
+}
+
+type DefenderStatsViewUI struct {
+	Defender  string                 `json:"defender"`
+	Total     int64                  `json:"total"`
+	DTPS      float64                `json:"dtpsEncounter"`
+	PctTotal  float64                `json:"pctTotal"`
+	Hits      int64                  `json:"hits"`
+	MaxHit    int64                  `json:"maxHit"`
+	AvgHit    float64                `json:"avgHit"`
+	Dodges    int64                  `json:"dodges"`
+	Parries   int64                  `json:"parries"`
+	Ripostes  int64                  `json:"ripostes"`
+	Blocks    int64                  `json:"blocks"`
+	Misses    int64                  `json:"misses"`
+	AvoidPct  float64                `json:"avoidPct"`
+	Absorbed  int64                  `json:"absorbed"`
+	Modifiers []DamageTakenRowViewUI `json:"modifiers"`
+	Attacks   []DamageTakenRowViewUI `json:"attacks"`
+}
+
+func takenRowsToUI(rows []engine.DamageTakenRowView) []DamageTakenRowViewUI {
+	out := make([]DamageTakenRowViewUI, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, DamageTakenRowViewUI{
+			Name:        r.Name,
+			Hits:        r.Hits,
+			Total:       r.Total,
+			MaxHit:      r.MaxHit,
+			AvgHit:      r.AvgHit,
+			PctDefender: r.PctDefender,
+		})
+	}
+	return out
+}
+
+func defendersToUI(defenders []engine.DefenderStatsView) []DefenderStatsViewUI {
+	out := make([]DefenderStatsViewUI, 0, len(defenders))
+	for _, d := range defenders {
+		out = append(out, DefenderStatsViewUI{
+			Defender:  d.Defender,
+			Total:     d.Total,
+			DTPS:      d.DTPS,
+			PctTotal:  d.PctTotal,
+			Hits:      d.Hits,
+			MaxHit:    d.MaxHit,
+			AvgHit:    d.AvgHit,
+			Dodges:    d.Dodges,
+			Parries:   d.Parries,
+			Ripostes:  d.Ripostes,
+			Blocks:    d.Blocks,
+			Misses:    d.Misses,
+			AvoidPct:  d.AvoidPct,
+			Absorbed:  d.Absorbed,
+			Modifiers: takenRowsToUI(d.Modifiers),
+			Attacks:   takenRowsToUI(d.Attacks),
+		})
+	}
+	return out
+}
+
+type StatusStatsViewUI struct {
+	Subject      string `json:"subject"`
+	Stuns        int64  `json:"stuns"`
+	StunResists  int64  `json:"stunResists"`
+	StunAvoids   int64  `json:"stunAvoids"`
+	FailedTaunts int64  `json:"failedTaunts"`
+	Staggers     int64  `json:"staggers"`
+	Delirium     int64  `json:"delirium"`
+	StunnedSec   int64  `json:"stunnedSec"`
+}
+
+type StatusEventViewUI struct {
+	Ts      string `json:"ts"`
+	Subject string `json:"subject"`
+	Effect  string `json:"effect"`
+}
+
+func statusToUI(rows []engine.StatusStatsView) []StatusStatsViewUI {
+	out := make([]StatusStatsViewUI, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, StatusStatsViewUI{
+			Subject:      r.Subject,
+			Stuns:        r.Stuns,
+			StunResists:  r.StunResists,
+			StunAvoids:   r.StunAvoids,
+			FailedTaunts: r.FailedTaunts,
+			Staggers:     r.Staggers,
+			Delirium:     r.Delirium,
+			StunnedSec:   r.StunnedSec,
+		})
+	}
+	return out
+}
+
+func statusTimelineToUI(events []engine.StatusEventView) []StatusEventViewUI {
+	out := make([]StatusEventViewUI, 0, len(events))
+	for _, e := range events {
+		out = append(out, StatusEventViewUI{
+			Ts:      e.Timestamp.Format(time.RFC3339),
+			Subject: e.Subject,
+			Effect:  e.Effect,
+		})
+	}
+	return out
+}
+
+type ChatLineViewUI struct {
+	Ts      string `json:"ts"`
+	Channel string `json:"channel"`
+	Speaker string `json:"speaker"`
+	Target  string `json:"target"`
+	Message string `json:"message"`
+}
+
+func chatToUI(lines []engine.ChatLineView) []ChatLineViewUI {
+	out := make([]ChatLineViewUI, 0, len(lines))
+	for _, c := range lines {
+		out = append(out, ChatLineViewUI{
+			Ts:      c.Timestamp.Format(time.RFC3339),
+			Channel: c.Channel,
+			Speaker: c.Speaker,
+			Target:  c.Target,
+			Message: c.Message,
+		})
+	}
+	return out
+}
+
+type SpellResistViewUI struct {
+	Spell     string  `json:"spell"`
+	Landed    int64   `json:"landed"`
+	Resisted  int64   `json:"resisted"`
+	ResistPct float64 `json:"resistPct"`
+}
+
+func resistsToUI(rows []engine.SpellResistView) []SpellResistViewUI {
+	out := make([]SpellResistViewUI, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, SpellResistViewUI{
+			Spell:     r.Spell,
+			Landed:    r.Landed,
+			Resisted:  r.Resisted,
+			ResistPct: r.ResistPct,
+		})
+	}
+	return out
+}
+
+type SpellCastViewUI struct {
+	Caster      string `json:"caster"`
+	Spell       string `json:"spell"`
+	Started     int64  `json:"started"`
+	Landed      int64  `json:"landed"`
+	Interrupted int64  `json:"interrupted"`
+	Fizzled     int64  `json:"fizzled"`
+	Recovered   int64  `json:"recovered"`
+	Resisted    int64  `json:"resisted"`
+}
+
+func castsToUI(rows []engine.SpellCastView) []SpellCastViewUI {
+	out := make([]SpellCastViewUI, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, SpellCastViewUI{
+			Caster:      r.Caster,
+			Spell:       r.Spell,
+			Started:     r.Started,
+			Landed:      r.Landed,
+			Interrupted: r.Interrupted,
+			Fizzled:     r.Fizzled,
+			Recovered:   r.Recovered,
+			Resisted:    r.Resisted,
+		})
+	}
+	return out
 }
 
 func DamageBreakdownViewToUI(v engine.DamageBreakdownView) DamageBreakdownViewUI {
-	out := DamageBreakdownViewUI{
+	return DamageBreakdownViewUI{
 		EncounterID: v.EncounterID,
 		Target:      v.Target,
 		Actor:       v.Actor,
-		Rows:        make([]DamageBreakdownRowViewUI, 0, len(v.Rows)),
+		Rows:        breakdownRowsToUI(v.Rows),
+		Pets:        breakdownRowsToUI(v.Pets),
 	}
-	for _, r := range v.Rows {
-		out.Rows = append(out.Rows, DamageBreakdownRowViewUI{
+}
+
+func breakdownRowsToUI(rows []engine.DamageBreakdownRowView) []DamageBreakdownRowViewUI {
+	out := make([]DamageBreakdownRowViewUI, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, DamageBreakdownRowViewUI{
 			Name:      r.Name,
 			PctPlayer: r.PctPlayer,
 			Damage:    r.Damage,
@@ -44,6 +307,9 @@ func DamageBreakdownViewToUI(v engine.DamageBreakdownView) DamageBreakdownViewUI
 			AvgHit:    r.AvgHit,
 			CritPct:   r.CritPct,
 			AvgCrit:   r.AvgCrit,
+
+			CripplingPct: r.CripplingPct,
+			AvgCrippling: r.AvgCrippling,
 		})
 	}
 	return out
@@ -53,12 +319,37 @@ type EncounterViewUI struct {
 	EncounterKey string             `json:"encounterKey"`
 	EncounterID  string             `json:"encounterId"`
 	Target       string             `json:"target"`
+	Zone         string             `json:"zone"`
 	Start        string             `json:"start"`
 	End          string             `json:"end"`
 	EncounterSec int64              `json:"encounterSec"`
 	TotalDamage  int64              `json:"totalDamage"`
 	DPSEncounter float64            `json:"dpsEncounter"`
+	Killed       bool               `json:"killed"`
+	Killer       string             `json:"killer"`
 	Actors       []ActorStatsViewUI `json:"actors"`
+
+	TotalHealing int64               `json:"totalHealing"`
+	HPSEncounter float64             `json:"hpsEncounter"`
+	Healers      []HealerStatsViewUI `json:"healers"`
+	HealTargets  []HealTargetViewUI  `json:"healTargets"`
+
+	TotalDamageTaken int64                 `json:"totalDamageTaken"`
+	Defenders        []DefenderStatsViewUI `json:"defenders"`
+
+	Rampages     int64 `json:"rampages"`
+	WildRampages int64 `json:"wildRampages"`
+	Flurries     int64 `json:"flurries"`
+
+	SpellResists    []SpellResistViewUI `json:"spellResists"`
+	IncomingResists []SpellResistViewUI `json:"incomingResists"`
+
+	Status []StatusStatsViewUI `json:"status"`
+
+	SpellCasts []SpellCastViewUI `json:"spellCasts"`
+
+	Chat           []ChatLineViewUI    `json:"chat"`
+	StatusTimeline []StatusEventViewUI `json:"statusTimeline"`
 }
 
 type SnapshotUI struct {
@@ -134,6 +425,9 @@ type DamageBreakdownRowViewUI struct {
 	AvgHit    float64 `json:"avgHit"`
 	CritPct   float64 `json:"critPct"`
 	AvgCrit   float64 `json:"avgCrit"`
+
+	CripplingPct float64 `json:"cripplingPct"`
+	AvgCrippling float64 `json:"avgCrippling"`
 }
 
 type DamageBreakdownViewUI struct {
@@ -141,19 +435,38 @@ type DamageBreakdownViewUI struct {
 	Target      string                     `json:"target"`
 	Actor       string                     `json:"actor"`
 	Rows        []DamageBreakdownRowViewUI `json:"rows"`
+	Pets        []DamageBreakdownRowViewUI `json:"pets"`
 }
 
 func EncounterViewToUI(e engine.EncounterView) EncounterViewUI {
 	enc := EncounterViewUI{
-		EncounterKey: e.EncounterKey,
-		EncounterID:  e.EncounterID,
-		Target:       e.Target,
-		Start:        e.Start.Format(time.RFC3339),
-		End:          e.End.Format(time.RFC3339),
-		EncounterSec: e.EncounterSec,
-		TotalDamage:  e.TotalDamage,
-		DPSEncounter: e.DPSEncounter,
-		Actors:       make([]ActorStatsViewUI, 0, len(e.Actors)),
+		EncounterKey:     e.EncounterKey,
+		EncounterID:      e.EncounterID,
+		Target:           e.Target,
+		Zone:             e.Zone,
+		Start:            e.Start.Format(time.RFC3339),
+		End:              e.End.Format(time.RFC3339),
+		EncounterSec:     e.EncounterSec,
+		TotalDamage:      e.TotalDamage,
+		DPSEncounter:     e.DPSEncounter,
+		Killed:           e.Killed,
+		Killer:           e.Killer,
+		Actors:           make([]ActorStatsViewUI, 0, len(e.Actors)),
+		TotalHealing:     e.TotalHealing,
+		HPSEncounter:     e.HPSEncounter,
+		Healers:          healersToUI(e.Healers),
+		HealTargets:      healTargetsToUI(e.HealTargets),
+		TotalDamageTaken: e.TotalDamageTaken,
+		Rampages:         e.Rampages,
+		WildRampages:     e.WildRampages,
+		Flurries:         e.Flurries,
+		Defenders:        defendersToUI(e.Defenders),
+		SpellResists:     resistsToUI(e.SpellResists),
+		IncomingResists:  resistsToUI(e.IncomingResists),
+		Status:           statusToUI(e.Status),
+		SpellCasts:       castsToUI(e.SpellCasts),
+		Chat:             chatToUI(e.Chat),
+		StatusTimeline:   statusTimelineToUI(e.StatusTimeline),
 	}
 	for _, a := range e.Actors {
 		enc.Actors = append(enc.Actors, ActorStatsViewUI{
@@ -171,6 +484,8 @@ func EncounterViewToUI(e engine.EncounterView) EncounterViewUI {
 			CritPct:   a.CritPct,
 			AvgCrit:   a.AvgCrit,
 			Crits:     a.Crits,
+
+			PetDamage: a.PetDamage,
 		})
 	}
 	return enc
@@ -186,15 +501,24 @@ func SnapshotToUISummary(s engine.Snapshot) SnapshotUI {
 	}
 	for _, e := range s.Encounters {
 		out.Encounters = append(out.Encounters, EncounterViewUI{
-			EncounterKey: e.EncounterKey,
-			EncounterID:  e.EncounterID,
-			Target:       e.Target,
-			Start:        e.Start.Format(time.RFC3339),
-			End:          e.End.Format(time.RFC3339),
-			EncounterSec: e.EncounterSec,
-			TotalDamage:  e.TotalDamage,
-			DPSEncounter: e.DPSEncounter,
-			Actors:       nil,
+			EncounterKey:     e.EncounterKey,
+			EncounterID:      e.EncounterID,
+			Target:           e.Target,
+			Zone:             e.Zone,
+			Start:            e.Start.Format(time.RFC3339),
+			End:              e.End.Format(time.RFC3339),
+			EncounterSec:     e.EncounterSec,
+			TotalDamage:      e.TotalDamage,
+			DPSEncounter:     e.DPSEncounter,
+			Killed:           e.Killed,
+			Killer:           e.Killer,
+			Actors:           nil,
+			TotalHealing:     e.TotalHealing,
+			HPSEncounter:     e.HPSEncounter,
+			TotalDamageTaken: e.TotalDamageTaken,
+			Rampages:         e.Rampages,
+			WildRampages:     e.WildRampages,
+			Flurries:         e.Flurries,
 		})
 	}
 	return out
@@ -210,15 +534,31 @@ func snapshotToUI(s engine.Snapshot) SnapshotUI {
 	}
 	for _, e := range s.Encounters {
 		enc := EncounterViewUI{
-			EncounterKey: e.EncounterKey,
-			EncounterID:  e.EncounterID,
-			Target:       e.Target,
-			Start:        e.Start.Format(time.RFC3339),
-			End:          e.End.Format(time.RFC3339),
-			EncounterSec: e.EncounterSec,
-			TotalDamage:  e.TotalDamage,
-			DPSEncounter: e.DPSEncounter,
-			Actors:       make([]ActorStatsViewUI, 0, len(e.Actors)),
+			EncounterKey:     e.EncounterKey,
+			EncounterID:      e.EncounterID,
+			Target:           e.Target,
+			Zone:             e.Zone,
+			Start:            e.Start.Format(time.RFC3339),
+			End:              e.End.Format(time.RFC3339),
+			EncounterSec:     e.EncounterSec,
+			TotalDamage:      e.TotalDamage,
+			DPSEncounter:     e.DPSEncounter,
+			Killed:           e.Killed,
+			Killer:           e.Killer,
+			Actors:           make([]ActorStatsViewUI, 0, len(e.Actors)),
+			TotalHealing:     e.TotalHealing,
+			HPSEncounter:     e.HPSEncounter,
+			Healers:          healersToUI(e.Healers),
+			HealTargets:      healTargetsToUI(e.HealTargets),
+			TotalDamageTaken: e.TotalDamageTaken,
+			Rampages:         e.Rampages,
+			WildRampages:     e.WildRampages,
+			Flurries:         e.Flurries,
+			Defenders:        defendersToUI(e.Defenders),
+			SpellResists:     resistsToUI(e.SpellResists),
+			IncomingResists:  resistsToUI(e.IncomingResists),
+			Status:           statusToUI(e.Status),
+			SpellCasts:       castsToUI(e.SpellCasts),
 		}
 		for _, a := range e.Actors {
 			enc.Actors = append(enc.Actors, ActorStatsViewUI{
@@ -236,6 +576,8 @@ func snapshotToUI(s engine.Snapshot) SnapshotUI {
 				CritPct:   a.CritPct,
 				AvgCrit:   a.AvgCrit,
 				Crits:     a.Crits,
+
+				PetDamage: a.PetDamage,
 			})
 		}
 		out.Encounters = append(out.Encounters, enc)
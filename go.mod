@@ -2,4 +2,4 @@ module github.com/ZehenForever/eqemu-log-parser
 
 go 1.22
 
-require github.com/gorilla/websocket v1.5.3 // indirect
+require github.com/gorilla/websocket v1.5.3
@@ -18,6 +18,10 @@ type DamageBreakdownStats struct {
 	MinHit      int64
 	MaxHit      int64
 	CritDamage  int64
+
+	// Crippling blows are counted separately from ordinary crits.
+	CripplingHits   int64
+	CripplingDamage int64
 }
 
 type DamageBreakdownRowView struct {
@@ -33,6 +37,9 @@ type DamageBreakdownRowView struct {
 	AvgHit    float64 `json:"avgHit"`
 	CritPct   float64 `json:"critPct"`
 	AvgCrit   float64 `json:"avgCrit"`
+
+	CripplingPct float64 `json:"cripplingPct"`
+	AvgCrippling float64 `json:"avgCrippling"`
 }
 
 type DamageBreakdownView struct {
@@ -40,6 +47,8 @@ type DamageBreakdownView struct {
 	Target      string                   `json:"target"`
 	Actor       string                   `json:"actor"`
 	Rows        []DamageBreakdownRowView `json:"rows"`
+	// Pets has one summary row per pet owned by Actor.
+	Pets []DamageBreakdownRowView `json:"pets"`
 }
 
 func parseEncounterKey(encounterKey string) (target string, start time.Time, ok bool) {
@@ -103,17 +112,22 @@ func (s *EncounterSegmenter) GetDamageBreakdownByKey(encounterKey string, actor
 	if actor == "" {
 		return DamageBreakdownView{}, false
 	}
+	actor = s.canonicalName(actor)
 
 	enc := s.findEncounterByKey(target, start)
 	if enc == nil {
 		return DamageBreakdownView{}, false
 	}
 	st := enc.ByActor[actor]
+	pets := s.buildPetBreakdownRows(enc, actor)
 	if st == nil {
-		return DamageBreakdownView{}, false
+		if len(pets) == 0 {
+			return DamageBreakdownView{}, false
+		}
+		st = &EncounterActorStats{Actor: actor}
 	}
 	if st.Breakdown == nil {
-		return DamageBreakdownView{EncounterID: encounterID(enc.Target, enc.Start, enc.End), Target: enc.Target, Actor: actor, Rows: nil}, true
+		return DamageBreakdownView{EncounterID: encounterID(enc.Target, enc.Start, enc.End), Target: s.DisplayName(enc.Target), Actor: s.DisplayName(actor), Rows: nil, Pets: pets}, true
 	}
 
 	encSec := durationSecondsInt(enc.Start, enc.End)
@@ -121,7 +135,7 @@ func (s *EncounterSegmenter) GetDamageBreakdownByKey(encounterKey string, actor
 	actorTotal := st.Total
 
 	rows := make([]DamageBreakdownRowView, 0, len(st.Breakdown))
-	for _, c := range []model.DamageClass{model.DamageClassPierce, model.DamageClassSlash, model.DamageClassCrush, model.DamageClassBash, model.DamageClassKick, model.DamageClassDirect} {
+	for _, c := range []model.DamageClass{model.DamageClassPierce, model.DamageClassSlash, model.DamageClassCrush, model.DamageClassBash, model.DamageClassKick, model.DamageClassDirect, model.DamageClassDoT} {
 		agg := st.Breakdown[c]
 		if agg == nil || agg.Hits <= 0 {
 			continue
@@ -151,6 +165,14 @@ func (s *EncounterSegmenter) GetDamageBreakdownByKey(encounterKey string, actor
 		if agg.CritHits > 0 {
 			avgCrit = float64(agg.CritDamage) / float64(agg.CritHits)
 		}
+		cripplingPct := 0.0
+		if agg.Hits > 0 {
+			cripplingPct = (float64(agg.CripplingHits) / float64(agg.Hits)) * 100
+		}
+		avgCrippling := 0.0
+		if agg.CripplingHits > 0 {
+			avgCrippling = float64(agg.CripplingDamage) / float64(agg.CripplingHits)
+		}
 
 		rows = append(rows, DamageBreakdownRowView{
 			Name:      agg.Name,
@@ -165,6 +187,9 @@ func (s *EncounterSegmenter) GetDamageBreakdownByKey(encounterKey string, actor
 			AvgHit:    avgHit,
 			CritPct:   critPct,
 			AvgCrit:   avgCrit,
+
+			CripplingPct: cripplingPct,
+			AvgCrippling: avgCrippling,
 		})
 	}
 
@@ -175,7 +200,7 @@ func (s *EncounterSegmenter) GetDamageBreakdownByKey(encounterKey string, actor
 		return rows[i].Damage > rows[j].Damage
 	})
 
-	return DamageBreakdownView{EncounterID: encounterID(enc.Target, enc.Start, enc.End), Target: enc.Target, Actor: actor, Rows: rows}, true
+	return DamageBreakdownView{EncounterID: encounterID(enc.Target, enc.Start, enc.End), Target: s.DisplayName(enc.Target), Actor: s.DisplayName(actor), Rows: rows, Pets: pets}, true
 }
 
 func damageClassName(c model.DamageClass) string {
@@ -192,6 +217,8 @@ func damageClassName(c model.DamageClass) string {
 		return "Kicks"
 	case model.DamageClassDirect:
 		return "Direct Damage"
+	case model.DamageClassDoT:
+		return "DoT"
 	default:
 		return "Unknown"
 	}
@@ -205,17 +232,22 @@ func (s *EncounterSegmenter) GetDamageBreakdown(encounterId string, actor string
 	if actor == "" {
 		return DamageBreakdownView{}, false
 	}
+	actor = s.canonicalName(actor)
 
 	enc := s.findEncounterExact(target, start, end)
 	if enc == nil {
 		return DamageBreakdownView{}, false
 	}
 	st := enc.ByActor[actor]
+	pets := s.buildPetBreakdownRows(enc, actor)
 	if st == nil {
-		return DamageBreakdownView{}, false
+		if len(pets) == 0 {
+			return DamageBreakdownView{}, false
+		}
+		st = &EncounterActorStats{Actor: actor}
 	}
 	if st.Breakdown == nil {
-		return DamageBreakdownView{EncounterID: encounterId, Target: enc.Target, Actor: actor, Rows: nil}, true
+		return DamageBreakdownView{EncounterID: encounterId, Target: s.DisplayName(enc.Target), Actor: s.DisplayName(actor), Rows: nil, Pets: pets}, true
 	}
 
 	encSec := durationSecondsInt(enc.Start, enc.End)
@@ -223,7 +255,7 @@ func (s *EncounterSegmenter) GetDamageBreakdown(encounterId string, actor string
 	actorTotal := st.Total
 
 	rows := make([]DamageBreakdownRowView, 0, len(st.Breakdown))
-	for _, c := range []model.DamageClass{model.DamageClassPierce, model.DamageClassSlash, model.DamageClassCrush, model.DamageClassBash, model.DamageClassKick, model.DamageClassDirect} {
+	for _, c := range []model.DamageClass{model.DamageClassPierce, model.DamageClassSlash, model.DamageClassCrush, model.DamageClassBash, model.DamageClassKick, model.DamageClassDirect, model.DamageClassDoT} {
 		agg := st.Breakdown[c]
 		if agg == nil || agg.Hits <= 0 {
 			continue
@@ -253,6 +285,14 @@ func (s *EncounterSegmenter) GetDamageBreakdown(encounterId string, actor string
 		if agg.CritHits > 0 {
 			avgCrit = float64(agg.CritDamage) / float64(agg.CritHits)
 		}
+		cripplingPct := 0.0
+		if agg.Hits > 0 {
+			cripplingPct = (float64(agg.CripplingHits) / float64(agg.Hits)) * 100
+		}
+		avgCrippling := 0.0
+		if agg.CripplingHits > 0 {
+			avgCrippling = float64(agg.CripplingDamage) / float64(agg.CripplingHits)
+		}
 
 		rows = append(rows, DamageBreakdownRowView{
 			Name:      agg.Name,
@@ -267,6 +307,9 @@ func (s *EncounterSegmenter) GetDamageBreakdown(encounterId string, actor string
 			AvgHit:    avgHit,
 			CritPct:   critPct,
 			AvgCrit:   avgCrit,
+
+			CripplingPct: cripplingPct,
+			AvgCrippling: avgCrippling,
 		})
 	}
 
@@ -277,7 +320,7 @@ func (s *EncounterSegmenter) GetDamageBreakdown(encounterId string, actor string
 		return rows[i].Damage > rows[j].Damage
 	})
 
-	return DamageBreakdownView{EncounterID: encounterId, Target: enc.Target, Actor: actor, Rows: rows}, true
+	return DamageBreakdownView{EncounterID: encounterId, Target: s.DisplayName(enc.Target), Actor: s.DisplayName(actor), Rows: rows, Pets: pets}, true
 }
 
 func (s *EncounterSegmenter) findEncounterExact(target string, start, end time.Time) *Encounter {
@@ -66,6 +66,30 @@ func TestDamageBreakdown_Aggregation(t *testing.T) {
 	}
 }
 
+func TestDamageBreakdown_CripplingBlowSeparateFromCrits(t *testing.T) {
+	seg := NewEncounterSegmenter(8*time.Second, "")
+
+	start := time.Unix(100, 0).In(time.UTC)
+	seg.Process(model.Event{Timestamp: start, Kind: model.KindMeleeDamage, DamageClass: model.DamageClassPierce, Verb: "pierce", Actor: "Alice", Target: "a rat", Amount: 100, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(101, 0).In(time.UTC), Kind: model.KindMeleeDamage, DamageClass: model.DamageClassPierce, Verb: "pierce", Actor: "Alice", Target: "a rat", Amount: 300, AmountKnown: true, Crit: true, CritType: model.CritNormal})
+	seg.Process(model.Event{Timestamp: time.Unix(102, 0).In(time.UTC), Kind: model.KindMeleeDamage, DamageClass: model.DamageClassPierce, Verb: "pierce", Actor: "Alice", Target: "a rat", Amount: 900, AmountKnown: true, Crit: true, CritType: model.CritCrippling})
+	end := time.Unix(103, 0).In(time.UTC)
+	seg.Process(model.Event{Timestamp: end, Kind: model.KindMeleeDamage, DamageClass: model.DamageClassPierce, Verb: "pierce", Actor: "Alice", Target: "a rat", Amount: 100, AmountKnown: true})
+
+	encounterId := "a rat|" + start.Format(time.RFC3339) + "|" + end.Format(time.RFC3339)
+	view, ok := seg.GetDamageBreakdown(encounterId, "Alice")
+	if !ok || len(view.Rows) != 1 {
+		t.Fatalf("ok=%v rows=%d", ok, len(view.Rows))
+	}
+	row := view.Rows[0]
+	if row.CritPct != 25 || row.AvgCrit != 300 {
+		t.Fatalf("critPct=%v avgCrit=%v", row.CritPct, row.AvgCrit)
+	}
+	if row.CripplingPct != 25 || row.AvgCrippling != 900 {
+		t.Fatalf("cripplingPct=%v avgCrippling=%v", row.CripplingPct, row.AvgCrippling)
+	}
+}
+
 func TestDamageBreakdown_Regression_TestdataHasMeleeAndDirect(t *testing.T) {
 	p := filepath.Join("..", "..", "testdata", "eqlog_Emberval_Imperium_EQ.txt")
 	f, err := os.Open(p)
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+// SpellCastStats counts one caster's casts of one spell during an encounter. Landed and
+// Resisted are only known for the local player's spells on the encounter's target.
+type SpellCastStats struct {
+	Caster      string
+	Spell       string
+	Started     int64
+	Landed      int64
+	Interrupted int64
+	Fizzled     int64
+	Recovered   int64
+	Resisted    int64
+}
+
+type SpellCastView struct {
+	Caster      string `json:"caster"`
+	Spell       string `json:"spell"`
+	Started     int64  `json:"started"`
+	Landed      int64  `json:"landed"`
+	Interrupted int64  `json:"interrupted"`
+	Fizzled     int64  `json:"fizzled"`
+	Recovered   int64  `json:"recovered"`
+	Resisted    int64  `json:"resisted"`
+}
+
+func (e *Encounter) spellCast(caster, spell string) *SpellCastStats {
+	if e.ByCast == nil {
+		e.ByCast = make(map[string]*SpellCastStats)
+	}
+	key := caster + "\x00" + spell
+	st := e.ByCast[key]
+	if st == nil {
+		st = &SpellCastStats{Caster: caster, Spell: spell}
+		e.ByCast[key] = st
+	}
+	return st
+}
+
+// addCastToActive records cast starts, interrupts, fizzles and recoveries. Interrupt lines
+// rarely name the spell, so it is taken from the caster's last "begins casting" line.
+// A caster who is an encounter's target counts toward that encounter; the local player's
+// casts go to the encounter of their last target; anyone else's go to every live encounter.
+func (s *EncounterSegmenter) addCastToActive(ev model.Event) {
+	if ev.Actor == "" {
+		return
+	}
+	spell := ev.SpellOrSkill
+	switch ev.Kind {
+	case model.KindCastStart:
+		if s.casting == nil {
+			s.casting = make(map[string]string)
+		}
+		s.casting[ev.Actor] = spell
+	case model.KindCastOutcome:
+		switch ev.Cast {
+		case model.CastInterrupted, model.CastFizzled, model.CastRecovered:
+		default:
+			return
+		}
+		if spell == "" {
+			spell = s.casting[ev.Actor]
+		}
+		if ev.Cast != model.CastRecovered {
+			delete(s.casting, ev.Actor)
+		}
+	default:
+		return
+	}
+
+	for _, ae := range s.castEncounters(ev) {
+		st := ae.enc.spellCast(ev.Actor, spell)
+		if ev.Kind == model.KindCastStart {
+			st.Started++
+			continue
+		}
+		switch ev.Cast {
+		case model.CastInterrupted:
+			st.Interrupted++
+		case model.CastFizzled:
+			st.Fizzled++
+		case model.CastRecovered:
+			st.Recovered++
+		}
+	}
+}
+
+func (s *EncounterSegmenter) castEncounters(ev model.Event) []*activeEncounter {
+	if ae := s.active[ev.Actor]; s.isLive(ae, ev) {
+		return []*activeEncounter{ae}
+	}
+	if s.isLocalActor(ev.Actor) {
+		if ae := s.active[s.lastLocalTarget]; s.isLive(ae, ev) {
+			return []*activeEncounter{ae}
+		}
+	}
+	var out []*activeEncounter
+	for _, ae := range s.active {
+		if s.isLive(ae, ev) {
+			out = append(out, ae)
+		}
+	}
+	return out
+}
+
+// SpellCastsSorted orders the cast table by caster, then by casts started.
+func (e *Encounter) SpellCastsSorted() []*SpellCastStats {
+	out := make([]*SpellCastStats, 0, len(e.ByCast))
+	for _, st := range e.ByCast {
+		if st != nil {
+			out = append(out, st)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Caster != out[j].Caster {
+			return out[i].Caster < out[j].Caster
+		}
+		if out[i].Started != out[j].Started {
+			return out[i].Started > out[j].Started
+		}
+		return out[i].Spell < out[j].Spell
+	})
+	return out
+}
+
+func (s *EncounterSegmenter) buildSpellCastViews(enc *Encounter) []SpellCastView {
+	out := make([]SpellCastView, 0, len(enc.ByCast))
+	for _, st := range enc.SpellCastsSorted() {
+		out = append(out, SpellCastView{
+			Caster:      s.DisplayName(st.Caster),
+			Spell:       st.Spell,
+			Started:     st.Started,
+			Landed:      st.Landed,
+			Interrupted: st.Interrupted,
+			Fizzled:     st.Fizzled,
+			Recovered:   st.Recovered,
+			Resisted:    st.Resisted,
+		})
+	}
+	return out
+}
+
+func copyCasts(dst, src *Encounter) {
+	if src.ByCast == nil {
+		return
+	}
+	dst.ByCast = make(map[string]*SpellCastStats, len(src.ByCast))
+	for k, v := range src.ByCast {
+		if v == nil {
+			continue
+		}
+		cp := *v
+		dst.ByCast[k] = &cp
+	}
+}
+
+func mergeCasts(dst, src *Encounter) {
+	for _, v := range src.ByCast {
+		if v == nil {
+			continue
+		}
+		st := dst.spellCast(v.Caster, v.Spell)
+		st.Started += v.Started
+		st.Landed += v.Landed
+		st.Interrupted += v.Interrupted
+		st.Fizzled += v.Fizzled
+		st.Recovered += v.Recovered
+		st.Resisted += v.Resisted
+	}
+}
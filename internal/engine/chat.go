@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+const maxChatEvents = 20000
+
+type ChatLineView struct {
+	Timestamp time.Time `json:"ts"`
+	Channel   string    `json:"channel"`
+	Speaker   string    `json:"speaker"`
+	Target    string    `json:"target"`
+	Message   string    `json:"message"`
+}
+
+// addChat keeps a bounded, time-ordered window of chat lines for encounter timelines.
+// Chat never opens or extends an encounter.
+func (s *EncounterSegmenter) addChat(ev model.Event) {
+	s.chat = append(s.chat, ev)
+	if len(s.chat) > maxChatEvents {
+		s.chat = s.chat[len(s.chat)-maxChatEvents/2:]
+	}
+}
+
+// chatTimeline returns the chat lines logged between start and end (inclusive seconds).
+func (s *EncounterSegmenter) chatTimeline(start, end time.Time) []ChatLineView {
+	idx := sort.Search(len(s.chat), func(i int) bool {
+		return !s.chat[i].Timestamp.Before(start)
+	})
+	out := make([]ChatLineView, 0)
+	for _, ev := range s.chat[idx:] {
+		if ev.Timestamp.After(end) {
+			break
+		}
+		out = append(out, ChatLineView{
+			Timestamp: ev.Timestamp,
+			Channel:   ev.Channel,
+			Speaker:   s.DisplayName(ev.Actor),
+			Target:    s.DisplayName(ev.Target),
+			Message:   ev.Message,
+		})
+	}
+	return out
+}
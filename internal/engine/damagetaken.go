@@ -0,0 +1,459 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+type DamageTakenStats struct {
+	Name   string
+	Hits   int64
+	Total  int64
+	MaxHit int64
+}
+
+func (s *DamageTakenStats) add(amount int64) {
+	s.Hits++
+	s.Total += amount
+	if amount > s.MaxHit {
+		s.MaxHit = amount
+	}
+}
+
+type EncounterDefenderStats struct {
+	Defender   string
+	Total      int64
+	Hits       int64
+	MaxHit     int64
+	ByAttack   map[string]*DamageTakenStats
+	ByModifier map[model.AttackModifier]*DamageTakenStats
+
+	Dodges   int64
+	Parries  int64
+	Ripostes int64
+	Blocks   int64
+	Misses   int64
+	// Absorbed is damage a spellshield soaked up before it landed. It is not in Total.
+	Absorbed int64
+}
+
+func (s *EncounterDefenderStats) Avoided() int64 {
+	return s.Dodges + s.Parries + s.Ripostes + s.Blocks + s.Misses
+}
+
+type DamageTakenRowView struct {
+	Name        string  `json:"name"`
+	Hits        int64   `json:"hits"`
+	Total       int64   `json:"total"`
+	MaxHit      int64   `json:"maxHit"`
+	AvgHit      float64 `json:"avgHit"`
+	PctDefender float64 `json:"pctDefender"`
+}
+
+type DefenderStatsView struct {
+	Defender  string               `json:"defender"`
+	Total     int64                `json:"total"`
+	DTPS      float64              `json:"dtpsEncounter"`
+	PctTotal  float64              `json:"pctTotal"`
+	Hits      int64                `json:"hits"`
+	MaxHit    int64                `json:"maxHit"`
+	AvgHit    float64              `json:"avgHit"`
+	Dodges    int64                `json:"dodges"`
+	Parries   int64                `json:"parries"`
+	Ripostes  int64                `json:"ripostes"`
+	Blocks    int64                `json:"blocks"`
+	Misses    int64                `json:"misses"`
+	AvoidPct  float64              `json:"avoidPct"`
+	Absorbed  int64                `json:"absorbed"`
+	Modifiers []DamageTakenRowView `json:"modifiers"`
+	Attacks   []DamageTakenRowView `json:"attacks"`
+}
+
+func attackModifierName(m model.AttackModifier) string {
+	switch m {
+	case model.ModifierRampage:
+		return "Rampage"
+	case model.ModifierWildRampage:
+		return "Wild Rampage"
+	case model.ModifierFlurry:
+		return "Flurry"
+	default:
+		return "Normal"
+	}
+}
+
+// attackName groups incoming hits by spell when known, otherwise by the base melee verb
+// ("slashes" -> "slash", "frenzies" -> "frenzy").
+func attackName(ev model.Event) string {
+	if ev.SpellOrSkill != "" {
+		return ev.SpellOrSkill
+	}
+	v := strings.ToLower(ev.Verb)
+	switch {
+	case v == "":
+		if ev.Kind == model.KindNonMeleeDamage {
+			return "non-melee"
+		}
+		return "unknown"
+	case strings.HasSuffix(v, "ies"):
+		return v[:len(v)-3] + "y"
+	case strings.HasSuffix(v, "shes"), strings.HasSuffix(v, "ches"):
+		return v[:len(v)-2]
+	case strings.HasSuffix(v, "s") && !strings.HasSuffix(v, "ss"):
+		return v[:len(v)-1]
+	}
+	return v
+}
+
+func isEncounterTakenEvent(ev model.Event) bool {
+	if !ev.AmountKnown || ev.Amount <= 0 {
+		return false
+	}
+	switch ev.Kind {
+	case model.KindIncomingDamage, model.KindMeleeDamage, model.KindNonMeleeDamage:
+		return ev.Target != ""
+	default:
+		return false
+	}
+}
+
+// addTakenToActive records damage dealt by an encounter's target to its defenders.
+// Incoming damage with no named attacker (e.g. "You have taken N points of non-melee
+// damage.") goes to one encounter, picked like a heal's.
+func (s *EncounterSegmenter) addTakenToActive(ev model.Event) {
+	if ev.Actor == "" {
+		if ev.Kind != model.KindIncomingDamage {
+			return
+		}
+		if ae := s.focusEncounter(ev); ae != nil {
+			ae.enc.addTaken(ev)
+		}
+		return
+	}
+	ae := s.active[ev.Actor]
+	if s.isLive(ae, ev) {
+		ae.enc.addTaken(ev)
+	}
+}
+
+func (s *EncounterSegmenter) addAvoidToActive(ev model.Event) {
+	if ev.Actor == "" || ev.Target == "" {
+		return
+	}
+	ae := s.active[ev.Actor]
+	if s.isLive(ae, ev) {
+		ae.enc.addAvoid(ev)
+	}
+}
+
+// addAbsorbToActive records a spellshield absorb for its defender. The line names no
+// attacker, so it goes to one encounter, picked like a heal's.
+func (s *EncounterSegmenter) addAbsorbToActive(ev model.Event) {
+	if ev.Target == "" || ev.Amount <= 0 {
+		return
+	}
+	if ae := s.focusEncounter(ev); ae != nil {
+		ae.enc.defender(ev.Target).Absorbed += ev.Amount
+	}
+}
+
+// addSpecialAttackToActive counts "goes on a RAMPAGE!" / "executes a FLURRY" announcements
+// made by an encounter's target.
+func (s *EncounterSegmenter) addSpecialAttackToActive(ev model.Event) {
+	ae := s.active[ev.Actor]
+	if !s.isLive(ae, ev) {
+		return
+	}
+	switch ev.Modifier {
+	case model.ModifierRampage:
+		ae.enc.Rampages++
+	case model.ModifierWildRampage:
+		ae.enc.WildRampages++
+	case model.ModifierFlurry:
+		ae.enc.Flurries++
+	}
+}
+
+func (s *EncounterSegmenter) isLive(ae *activeEncounter, ev model.Event) bool {
+	if ae == nil || ae.enc == nil {
+		return false
+	}
+	if ev.Timestamp.Before(ae.enc.Start) {
+		return false
+	}
+	return ev.Timestamp.Sub(ae.lastTs) <= s.IdleTimeout
+}
+
+func (e *Encounter) defender(name string) *EncounterDefenderStats {
+	if e.ByDefender == nil {
+		e.ByDefender = make(map[string]*EncounterDefenderStats)
+	}
+	st := e.ByDefender[name]
+	if st == nil {
+		st = &EncounterDefenderStats{
+			Defender:   name,
+			ByAttack:   make(map[string]*DamageTakenStats),
+			ByModifier: make(map[model.AttackModifier]*DamageTakenStats),
+		}
+		e.ByDefender[name] = st
+	}
+	return st
+}
+
+func (e *Encounter) addTaken(ev model.Event) {
+	st := e.defender(ev.Target)
+	st.Total += ev.Amount
+	st.Hits++
+	if ev.Amount > st.MaxHit {
+		st.MaxHit = ev.Amount
+	}
+
+	name := attackName(ev)
+	atk := st.ByAttack[name]
+	if atk == nil {
+		atk = &DamageTakenStats{Name: name}
+		st.ByAttack[name] = atk
+	}
+	atk.add(ev.Amount)
+
+	mod := st.ByModifier[ev.Modifier]
+	if mod == nil {
+		mod = &DamageTakenStats{Name: attackModifierName(ev.Modifier)}
+		st.ByModifier[ev.Modifier] = mod
+	}
+	mod.add(ev.Amount)
+
+	e.TotalTaken += ev.Amount
+}
+
+func (e *Encounter) addAvoid(ev model.Event) {
+	st := e.defender(ev.Target)
+	if ev.Kind == model.KindMiss {
+		st.Misses++
+		return
+	}
+	switch ev.Avoid {
+	case model.AvoidDodge:
+		st.Dodges++
+	case model.AvoidParry:
+		st.Parries++
+	case model.AvoidRiposte:
+		st.Ripostes++
+	case model.AvoidBlock:
+		st.Blocks++
+	case model.AvoidMiss:
+		st.Misses++
+	}
+}
+
+func copyDefenderStats(s *EncounterDefenderStats) *EncounterDefenderStats {
+	if s == nil {
+		return nil
+	}
+	out := *s
+	out.ByAttack = make(map[string]*DamageTakenStats, len(s.ByAttack))
+	for k, v := range s.ByAttack {
+		if v == nil {
+			continue
+		}
+		cp := *v
+		out.ByAttack[k] = &cp
+	}
+	out.ByModifier = make(map[model.AttackModifier]*DamageTakenStats, len(s.ByModifier))
+	for k, v := range s.ByModifier {
+		if v == nil {
+			continue
+		}
+		cp := *v
+		out.ByModifier[k] = &cp
+	}
+	return &out
+}
+
+func copyDamageTaken(dst, src *Encounter) {
+	dst.TotalTaken = src.TotalTaken
+	dst.Rampages = src.Rampages
+	dst.WildRampages = src.WildRampages
+	dst.Flurries = src.Flurries
+	if src.ByDefender != nil {
+		dst.ByDefender = make(map[string]*EncounterDefenderStats, len(src.ByDefender))
+		for k, v := range src.ByDefender {
+			dst.ByDefender[k] = copyDefenderStats(v)
+		}
+	}
+}
+
+func mergeTakenStats(dst, src *DamageTakenStats) {
+	dst.Hits += src.Hits
+	dst.Total += src.Total
+	if src.MaxHit > dst.MaxHit {
+		dst.MaxHit = src.MaxHit
+	}
+}
+
+func mergeDamageTaken(dst, src *Encounter) {
+	dst.TotalTaken += src.TotalTaken
+	dst.Rampages += src.Rampages
+	dst.WildRampages += src.WildRampages
+	dst.Flurries += src.Flurries
+	if len(src.ByDefender) > 0 && dst.ByDefender == nil {
+		dst.ByDefender = make(map[string]*EncounterDefenderStats)
+	}
+	for name, st := range src.ByDefender {
+		if st == nil {
+			continue
+		}
+		ex := dst.ByDefender[name]
+		if ex == nil {
+			dst.ByDefender[name] = copyDefenderStats(st)
+			continue
+		}
+		ex.Total += st.Total
+		ex.Hits += st.Hits
+		if st.MaxHit > ex.MaxHit {
+			ex.MaxHit = st.MaxHit
+		}
+		ex.Dodges += st.Dodges
+		ex.Parries += st.Parries
+		ex.Ripostes += st.Ripostes
+		ex.Blocks += st.Blocks
+		ex.Misses += st.Misses
+		ex.Absorbed += st.Absorbed
+		if ex.ByAttack == nil {
+			ex.ByAttack = make(map[string]*DamageTakenStats)
+		}
+		for k, v := range st.ByAttack {
+			if v == nil {
+				continue
+			}
+			if cur := ex.ByAttack[k]; cur != nil {
+				mergeTakenStats(cur, v)
+				continue
+			}
+			cp := *v
+			ex.ByAttack[k] = &cp
+		}
+		if ex.ByModifier == nil {
+			ex.ByModifier = make(map[model.AttackModifier]*DamageTakenStats)
+		}
+		for k, v := range st.ByModifier {
+			if v == nil {
+				continue
+			}
+			if cur := ex.ByModifier[k]; cur != nil {
+				mergeTakenStats(cur, v)
+				continue
+			}
+			cp := *v
+			ex.ByModifier[k] = &cp
+		}
+	}
+}
+
+func (e *Encounter) DefendersSortedByTotal() []*EncounterDefenderStats {
+	out := make([]*EncounterDefenderStats, 0, len(e.ByDefender))
+	for _, st := range e.ByDefender {
+		if st == nil {
+			continue
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total == out[j].Total {
+			return out[i].Defender < out[j].Defender
+		}
+		return out[i].Total > out[j].Total
+	})
+	return out
+}
+
+func takenRowViews(rows []*DamageTakenStats, defenderTotal int64) []DamageTakenRowView {
+	out := make([]DamageTakenRowView, 0, len(rows))
+	for _, r := range rows {
+		avg := 0.0
+		if r.Hits > 0 {
+			avg = float64(r.Total) / float64(r.Hits)
+		}
+		pct := 0.0
+		if defenderTotal > 0 {
+			pct = (float64(r.Total) / float64(defenderTotal)) * 100
+		}
+		out = append(out, DamageTakenRowView{
+			Name:        r.Name,
+			Hits:        r.Hits,
+			Total:       r.Total,
+			MaxHit:      r.MaxHit,
+			AvgHit:      avg,
+			PctDefender: pct,
+		})
+	}
+	return out
+}
+
+func buildDefenderViews(enc *Encounter, encSec int64) []DefenderStatsView {
+	if enc == nil || len(enc.ByDefender) == 0 {
+		return nil
+	}
+	defenders := enc.DefendersSortedByTotal()
+	out := make([]DefenderStatsView, 0, len(defenders))
+	for _, st := range defenders {
+		dtps := 0.0
+		if encSec > 0 {
+			dtps = float64(st.Total) / float64(encSec)
+		}
+		pctTotal := 0.0
+		if enc.TotalTaken > 0 {
+			pctTotal = (float64(st.Total) / float64(enc.TotalTaken)) * 100
+		}
+		avgHit := 0.0
+		if st.Hits > 0 {
+			avgHit = float64(st.Total) / float64(st.Hits)
+		}
+		avoidPct := 0.0
+		if swings := st.Hits + st.Avoided(); swings > 0 {
+			avoidPct = (float64(st.Avoided()) / float64(swings)) * 100
+		}
+
+		attacks := make([]*DamageTakenStats, 0, len(st.ByAttack))
+		for _, a := range st.ByAttack {
+			if a != nil {
+				attacks = append(attacks, a)
+			}
+		}
+		sort.Slice(attacks, func(i, j int) bool {
+			if attacks[i].Total == attacks[j].Total {
+				return attacks[i].Name < attacks[j].Name
+			}
+			return attacks[i].Total > attacks[j].Total
+		})
+
+		mods := make([]*DamageTakenStats, 0, len(st.ByModifier))
+		for _, m := range []model.AttackModifier{model.ModifierNone, model.ModifierRampage, model.ModifierWildRampage, model.ModifierFlurry} {
+			if r := st.ByModifier[m]; r != nil {
+				mods = append(mods, r)
+			}
+		}
+
+		out = append(out, DefenderStatsView{
+			Defender:  st.Defender,
+			Total:     st.Total,
+			DTPS:      dtps,
+			PctTotal:  pctTotal,
+			Hits:      st.Hits,
+			MaxHit:    st.MaxHit,
+			AvgHit:    avgHit,
+			Dodges:    st.Dodges,
+			Parries:   st.Parries,
+			Ripostes:  st.Ripostes,
+			Blocks:    st.Blocks,
+			Misses:    st.Misses,
+			AvoidPct:  avoidPct,
+			Absorbed:  st.Absorbed,
+			Modifiers: takenRowViews(mods, st.Total),
+			Attacks:   takenRowViews(attacks, st.Total),
+		})
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+// identityRefreshEvery bounds how often direction checks re-run the identity classifier
+// while events stream in.
+const identityRefreshEvery = 256
+
+// orientDamage rewrites hostile swings (an NPC hitting a player-side defender) as incoming
+// damage for the defender, so the NPC never shows up as a DPS actor and the defender never
+// keys an encounter.
+func (s *EncounterSegmenter) orientDamage(ev model.Event) model.Event {
+	if ev.Kind != model.KindMeleeDamage && ev.Kind != model.KindNonMeleeDamage {
+		return ev
+	}
+	if !ev.AmountKnown || ev.Actor == "" || ev.Target == "" {
+		return ev
+	}
+	if s.isHostileSwing(ev.Actor, ev.Target) {
+		ev.Kind = model.KindIncomingDamage
+	}
+	return ev
+}
+
+func (s *EncounterSegmenter) isHostileSwing(actor, target string) bool {
+	// Something player-side is already fighting the actor, so its swings are hostile even
+	// before the defender has been identified (e.g. a cleric hit before their first heal).
+	if _, ok := s.active[actor]; ok {
+		_, fought := s.active[target]
+		return !fought && !s.isKnownNPC(target)
+	}
+	return s.isPlayerSide(target) && !s.isPlayerSide(actor)
+}
+
+func (s *EncounterSegmenter) isPlayerSide(name string) bool {
+	if name == "" {
+		return false
+	}
+	if name == "YOU" || (s.PlayerName != "" && name == s.PlayerName) {
+		return true
+	}
+	if owner, ok := model.PetOwner(name); ok {
+		// Compare with spaces removed too: logs use both "Lord Soth`s pet" and "LordSoth`s pet".
+		if s.isPlayerSide(owner) {
+			return true
+		}
+		if s.PlayerName != "" && strings.ReplaceAll(owner, " ", "") == s.PlayerName {
+			return true
+		}
+		return false
+	}
+	if _, ok := s.active[name]; ok {
+		return false
+	}
+	if _, ok := s.friendly[name]; ok {
+		return true
+	}
+	if s.ExcludedTargets != nil {
+		if _, ok := s.ExcludedTargets[name]; ok {
+			return true
+		}
+	}
+	return IsPCActor(name, s.directionScores())
+}
+
+// observeFriendly remembers heal participants as player-side, unless they are currently
+// being fought (NPCs that heal themselves).
+func (s *EncounterSegmenter) observeFriendly(ev model.Event) {
+	for _, name := range []string{ev.Actor, ev.Target} {
+		if name == "" || name == "YOU" {
+			continue
+		}
+		if _, ok := s.active[name]; ok {
+			continue
+		}
+		if s.friendly == nil {
+			s.friendly = make(map[string]struct{})
+		}
+		s.friendly[name] = struct{}{}
+	}
+}
+
+func (s *EncounterSegmenter) isKnownNPC(name string) bool {
+	sc, ok := s.directionScores()[name]
+	return ok && sc.Class == IdentityLikelyNPC
+}
+
+// SetIdentityScores pins the identity scores used to decide damage direction, e.g. scores
+// classified over a whole file with --force-pc/--force-npc overrides applied. Without pinned
+// scores the segmenter classifies its own sliding window of recent events.
+func (s *EncounterSegmenter) SetIdentityScores(scores map[string]IdentityScore) {
+	s.pinnedScores = scores
+}
+
+func (s *EncounterSegmenter) directionScores() map[string]IdentityScore {
+	if s.pinnedScores != nil {
+		return s.pinnedScores
+	}
+	if s.identityScores == nil || s.identitySinceRefresh >= identityRefreshEvery {
+		s.refreshIdentityIfNeeded(true)
+		s.identitySinceRefresh = 0
+	}
+	return s.identityScores
+}
@@ -20,6 +20,10 @@ type EncounterActorStats struct {
 	CritDmgSum  int64
 	FirstDamage time.Time
 	LastDamage  time.Time
+
+	// Owner is set when Actor is a pet. PetDamage is only filled in by rollupPets.
+	Owner     string
+	PetDamage int64
 }
 
 func (s *EncounterActorStats) ActiveSeconds() float64 {
@@ -39,11 +43,43 @@ func (s *EncounterActorStats) ActiveSeconds() float64 {
 
 type Encounter struct {
 	Target string
+	Zone   string
 	Start  time.Time
 	End    time.Time
 
 	ByActor map[string]*EncounterActorStats
 	Total   int64
+
+	ByHealer     map[string]*EncounterHealerStats
+	ByHealTarget map[string]*EncounterHealTargetStats
+	TotalHealing int64
+
+	ByDefender map[string]*EncounterDefenderStats
+	TotalTaken int64
+
+	Rampages     int64
+	WildRampages int64
+	Flurries     int64
+
+	// SpellResists tracks the local player's spells on the target, IncomingResists the
+	// target's spells on the local player.
+	SpellResists    map[string]*SpellResistStats
+	IncomingResists map[string]*SpellResistStats
+
+	ByStatusSubject map[string]*EncounterStatusStats
+	StatusTimeline  []StatusEntry
+
+	// ByCast is the spell-cast table, keyed by caster and spell.
+	ByCast map[string]*SpellCastStats
+
+	Killed bool
+	Killer string
+	// Retargeted marks an encounter closed because the local player targeted the NPC again
+	// after a lull; like a kill, it is never coalesced with the next pull.
+	Retargeted bool
+	// ClockJumped marks an encounter closed at a clock jump in the log. Times on the two
+	// sides cannot be compared, so it is never coalesced with the next pull either.
+	ClockJumped bool
 }
 
 func (e *Encounter) DurationSeconds() float64 {
@@ -83,17 +119,34 @@ func (e *Encounter) ActorsSortedByTotal() []*EncounterActorStats {
 	return out
 }
 
+// DefaultTargetSwitchGap is short of the default idle timeout but longer than the pauses
+// of one fight, so only a target that has clearly gone quiet is split off.
+const DefaultTargetSwitchGap = 5 * time.Second
+
 type EncounterSegmenter struct {
 	IdleTimeout     time.Duration
 	PlayerName      string
 	ExcludedTargets map[string]struct{}
+	// TargetSwitchGap is the quiet time after which switching the local player's target
+	// to an NPC from a different one starts a new pull on it; zero or less disables the
+	// check. NewEncounterSegmenter sets DefaultTargetSwitchGap.
+	TargetSwitchGap time.Duration
 
 	localTouchedTargets map[string]struct{}
+	lastLocalTarget     string
 	combatTs            []time.Time
 	identityEvents      []model.Event
 	identityDirty       bool
 	identityScores      map[string]IdentityScore
 	recentDamageEvents  []model.Event
+	chat                []model.Event
+	stunnedSince        map[string]time.Time
+	casting             map[string]string
+
+	identitySinceRefresh int
+	pinnedScores         map[string]IdentityScore
+	friendly             map[string]struct{}
+	names                *model.NameTable
 
 	active map[string]*activeEncounter
 	done   []*Encounter
@@ -111,6 +164,7 @@ func NewEncounterSegmenter(idleTimeout time.Duration, playerName string) *Encoun
 	return &EncounterSegmenter{
 		IdleTimeout:         idleTimeout,
 		PlayerName:          playerName,
+		TargetSwitchGap:     DefaultTargetSwitchGap,
 		localTouchedTargets: make(map[string]struct{}),
 		active:              make(map[string]*activeEncounter),
 	}
@@ -168,11 +222,71 @@ func (s *EncounterSegmenter) hasCombatBetween(start, end time.Time) bool {
 }
 
 func (s *EncounterSegmenter) Process(ev model.Event) {
+	if ev.Kind == model.KindClockJump {
+		s.closeAtClockJump()
+		return
+	}
+	if ev.Kind == model.KindChat {
+		s.addChat(ev)
+		return
+	}
+	if ev.Kind == model.KindStatus {
+		s.addStatusToActive(ev)
+		return
+	}
+	if ev.Kind == model.KindDeath {
+		s.closeOnDeath(ev)
+		return
+	}
+	if ev.Kind == model.KindTarget {
+		s.noteTargetSwitch(ev)
+		return
+	}
+	if ev.Kind == model.KindHeal {
+		s.observeFriendly(ev)
+	}
+	if isEncounterHealEvent(ev) {
+		s.addHealToActive(ev)
+		return
+	}
+	// Damage taken is credited to the encounter keyed on the attacker; it never opens or
+	// extends an encounter on its own.
+	if isEncounterTakenEvent(ev) {
+		s.addTakenToActive(ev)
+	}
+	if ev.Kind == model.KindAvoid || ev.Kind == model.KindMiss {
+		s.addAvoidToActive(ev)
+	}
+	if ev.Kind == model.KindSpecialAttack {
+		s.addSpecialAttackToActive(ev)
+		return
+	}
+	if ev.Kind == model.KindResist {
+		s.addResistToActive(ev)
+		return
+	}
+	if ev.Kind == model.KindSpellAbsorb {
+		s.addAbsorbToActive(ev)
+		return
+	}
+	if ev.Kind == model.KindAffliction || ev.Kind == model.KindIncomingDamage {
+		s.addSpellLandedToActive(ev)
+	}
+	if ev.Kind == model.KindCastStart || ev.Kind == model.KindCastOutcome {
+		s.addCastToActive(ev)
+	}
+	if ev.Kind == model.KindCastOutcome {
+		return
+	}
 	// Identity and time-series tracking are additive and do not affect encounter segmentation.
-	// Identity classifier consumes a sliding window of recent events.
-	if ev.Kind == model.KindCastStart || isEncounterDamageEvent(ev) {
+	// Identity classifier consumes a sliding window of recent events. Only the local player's
+	// cast starts count, since NPCs cast too.
+	if (ev.Kind == model.KindCastStart && s.isLocalActor(ev.Actor)) || isEncounterDamageEvent(ev) {
 		s.observeIdentityEvent(ev)
 	}
+	// Direction is decided after the identity window sees the raw swing, so the classifier
+	// keeps scoring names exactly as it did before.
+	ev = s.orientDamage(ev)
 	// Players series consumes a bounded window of outgoing amount-bearing damage events.
 	if isEncounterDamageEvent(ev) {
 		s.recentDamageEvents = append(s.recentDamageEvents, ev)
@@ -187,6 +301,7 @@ func (s *EncounterSegmenter) Process(ev model.Event) {
 		if (s.PlayerName == "" || ev.Target != s.PlayerName) && s.PlayerName != "" {
 			if ev.Actor == s.PlayerName || ev.Actor == "YOU" {
 				s.localTouchedTargets[ev.Target] = struct{}{}
+				s.lastLocalTarget = ev.Target
 			}
 		}
 	}
@@ -208,7 +323,7 @@ func (s *EncounterSegmenter) Process(ev model.Event) {
 	target := ev.Target
 	ae := s.active[target]
 	if ae == nil {
-		ae = &activeEncounter{enc: &Encounter{Target: target, Start: ev.Timestamp, ByActor: make(map[string]*EncounterActorStats)}, lastTs: ev.Timestamp}
+		ae = &activeEncounter{enc: &Encounter{Target: target, Zone: ev.Zone, Start: ev.Timestamp, ByActor: make(map[string]*EncounterActorStats)}, lastTs: ev.Timestamp}
 		s.active[target] = ae
 	}
 
@@ -216,7 +331,7 @@ func (s *EncounterSegmenter) Process(ev model.Event) {
 		if ev.Timestamp.Sub(ae.lastTs) > s.IdleTimeout {
 			ae.enc.End = ae.lastTs
 			s.done = append(s.done, ae.enc)
-			ae = &activeEncounter{enc: &Encounter{Target: target, Start: ev.Timestamp, ByActor: make(map[string]*EncounterActorStats)}, lastTs: ev.Timestamp}
+			ae = &activeEncounter{enc: &Encounter{Target: target, Zone: ev.Zone, Start: ev.Timestamp, ByActor: make(map[string]*EncounterActorStats)}, lastTs: ev.Timestamp}
 			s.active[target] = ae
 		}
 	}
@@ -226,7 +341,7 @@ func (s *EncounterSegmenter) Process(ev model.Event) {
 
 	st := ae.enc.ByActor[ev.Actor]
 	if st == nil {
-		st = &EncounterActorStats{Actor: ev.Actor, Breakdown: make(map[model.DamageClass]*DamageBreakdownStats)}
+		st = &EncounterActorStats{Actor: ev.Actor, Owner: ev.ActorOwner, Breakdown: make(map[model.DamageClass]*DamageBreakdownStats)}
 		ae.enc.ByActor[ev.Actor] = st
 	}
 	if st.Breakdown == nil {
@@ -265,8 +380,13 @@ func (s *EncounterSegmenter) Process(ev model.Event) {
 		agg.Hits += 1
 		agg.TotalDamage += ev.Amount
 		if ev.Crit {
-			agg.CritHits += 1
-			agg.CritDamage += ev.Amount
+			if ev.CritType == model.CritCrippling {
+				agg.CripplingHits += 1
+				agg.CripplingDamage += ev.Amount
+			} else {
+				agg.CritHits += 1
+				agg.CritDamage += ev.Amount
+			}
 		}
 	}
 	st.Hits += 1
@@ -281,6 +401,52 @@ func (s *EncounterSegmenter) Process(ev model.Event) {
 	ae.enc.Total += ev.Amount
 }
 
+// closeOnDeath ends the active encounter for the slain target immediately, so the
+// next pull of an identically named mob starts a fresh encounter.
+func (s *EncounterSegmenter) closeOnDeath(ev model.Event) {
+	if !isValidEncounterTarget(ev.Target) {
+		return
+	}
+	ae := s.active[ev.Target]
+	if ae == nil || ae.enc == nil {
+		return
+	}
+	if !ae.lastTs.IsZero() && !ev.Timestamp.IsZero() {
+		dt := ev.Timestamp.Sub(ae.lastTs)
+		if dt > s.IdleTimeout {
+			// The kill arrived after the encounter already went idle; keep the
+			// idle-based end time and let the next damage event close it.
+			return
+		}
+		if dt > 0 {
+			ae.enc.End = ev.Timestamp
+		}
+	}
+	if ae.enc.End.IsZero() {
+		ae.enc.End = ae.lastTs
+	}
+	ae.enc.Killed = true
+	ae.enc.Killer = ev.Actor
+	s.done = append(s.done, ae.enc)
+	delete(s.active, ev.Target)
+}
+
+// closeAtClockJump ends every open encounter at its last event when the log's clock jumps,
+// so that no encounter spans the discontinuity.
+func (s *EncounterSegmenter) closeAtClockJump() {
+	for name, ae := range s.active {
+		if ae.enc != nil {
+			if ae.enc.End.IsZero() {
+				ae.enc.End = ae.lastTs
+			}
+			ae.enc.ClockJumped = true
+			s.done = append(s.done, ae.enc)
+		}
+		delete(s.active, name)
+	}
+	s.stunnedSince = nil
+}
+
 func (s *EncounterSegmenter) Finalize() []*Encounter {
 	for _, ae := range s.active {
 		if ae.enc != nil {
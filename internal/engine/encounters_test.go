@@ -55,6 +55,7 @@ func segmentEncountersFromFile(t *testing.T, includePCTargets bool, pcThreshold
 		}
 	}
 	ApplyIdentityOverrides(scores, pcThreshold, forcePC, forceNPC)
+	seg.SetIdentityScores(scores)
 
 	if !includePCTargets {
 		excluded := make(map[string]struct{})
@@ -72,6 +73,25 @@ func segmentEncountersFromFile(t *testing.T, includePCTargets bool, pcThreshold
 	return seg.Finalize()
 }
 
+// processLines parses lines with ctx and feeds them to seg, rewriting "YOU" to the local
+// player's name the way pipeline.PlayerName does for the CLI and the UI.
+func processLines(t *testing.T, seg *EncounterSegmenter, ctx *model.ParseContext, lines []string) {
+	t.Helper()
+	for _, line := range lines {
+		ev, ok := parse.ParseLine(ctx, line, time.Local)
+		if !ok {
+			t.Fatalf("expected ok: %s", line)
+		}
+		if ev.Actor == "YOU" {
+			ev.Actor = ctx.LocalActorName
+		}
+		if ev.Target == "YOU" {
+			ev.Target = ctx.LocalActorName
+		}
+		seg.Process(ev)
+	}
+}
+
 func TestEncounterDurationSeconds_Inclusive_FirstEqualsLastIsOne(t *testing.T) {
 	e := &Encounter{Start: time.Date(2026, 1, 23, 7, 46, 1, 0, time.Local), End: time.Date(2026, 1, 23, 7, 46, 1, 0, time.Local)}
 	if got := e.DurationSeconds(); got != 1 {
@@ -185,17 +205,26 @@ func TestEncounterSegmentation_FullTestdata_HasAtLeastTwoEncounters(t *testing.T
 	}
 }
 
-func TestEncounterSegmentation_FullTestdata_IncludePCTargets_RestoresSigdis(t *testing.T) {
+func TestEncounterSegmentation_FullTestdata_IncludePCTargets_HostileSwingsAreDamageTaken(t *testing.T) {
+	// "DPS Machine hits Sigdis" is an NPC swinging at a PC: even with PC targets included it
+	// must not key a Sigdis encounter, and shows up as damage taken on the DPS Machine instead.
 	encs := segmentEncountersFromFile(t, true, DefaultPCThreshold, nil, nil)
-	foundSigdis := false
+	foundTaken := false
 	for _, enc := range encs {
 		if enc.Target == "Sigdis" {
-			foundSigdis = true
-			break
+			t.Fatalf("unexpected encounter keyed on defender %q", enc.Target)
+		}
+		if _, ok := enc.ByActor["DPS Machine"]; ok {
+			t.Fatalf("DPS Machine listed as damage actor in %q", enc.Target)
+		}
+		if strings.Contains(enc.Target, "DPS Machine") {
+			if st := enc.ByDefender["Sigdis"]; st != nil && st.Total > 0 {
+				foundTaken = true
+			}
 		}
 	}
-	if !foundSigdis {
-		t.Fatalf("expected PC-target encounter %q when includePCTargets enabled", "Sigdis")
+	if !foundTaken {
+		t.Fatalf("expected Sigdis damage taken on DPS Machine encounter")
 	}
 }
 
@@ -286,3 +315,626 @@ func TestEncounterSegmentation_DamagePlusIncoming_IncomingDoesNotAffectTotals(t
 		t.Fatalf("total=%d want=30", encs[0].Total)
 	}
 }
+
+func TestEncounterSegmenter_DeathClosesEncounter(t *testing.T) {
+	seg := NewEncounterSegmenter(8*time.Second, "")
+
+	// First crocodile: killed at t=102.
+	seg.Process(model.Event{Timestamp: time.Unix(100, 0), Kind: model.KindMeleeDamage, Actor: "Alice", Target: "A Crocodile", Amount: 10, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(101, 0), Kind: model.KindMeleeDamage, Actor: "Alice", Target: "A Crocodile", Amount: 10, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(102, 0), Kind: model.KindDeath, Actor: "Alice", Target: "A Crocodile"})
+
+	// Combat against another mob in the gap would normally allow coalescing.
+	seg.Process(model.Event{Timestamp: time.Unix(103, 0), Kind: model.KindMeleeDamage, Actor: "Alice", Target: "A Living Flora", Amount: 5, AmountKnown: true})
+
+	// Second crocodile pulled well within the idle timeout.
+	seg.Process(model.Event{Timestamp: time.Unix(104, 0), Kind: model.KindMeleeDamage, Actor: "Alice", Target: "A Crocodile", Amount: 20, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(105, 0), Kind: model.KindMeleeDamage, Actor: "Alice", Target: "A Crocodile", Amount: 20, AmountKnown: true})
+
+	var crocs []*Encounter
+	for _, e := range seg.Finalize() {
+		if e.Target == "A Crocodile" {
+			crocs = append(crocs, e)
+		}
+	}
+	if len(crocs) != 2 {
+		t.Fatalf("encounters=%d want=2", len(crocs))
+	}
+	first, second := crocs[0], crocs[1]
+	if !first.Killed || first.Killer != "Alice" {
+		t.Fatalf("first killed=%v killer=%q", first.Killed, first.Killer)
+	}
+	if first.Total != 20 || !first.End.Equal(time.Unix(102, 0)) {
+		t.Fatalf("first total=%d end=%v", first.Total, first.End)
+	}
+	if second.Killed {
+		t.Fatalf("second encounter unexpectedly killed")
+	}
+	if second.Total != 40 || !second.Start.Equal(time.Unix(104, 0)) {
+		t.Fatalf("second total=%d start=%v", second.Total, second.Start)
+	}
+
+	// Coalescing must not fuse a killed encounter with the next pull.
+	snap := seg.BuildSnapshot(time.Unix(200, 0), "", false, SnapshotOptions{IncludePCTargets: true, CoalesceTargets: true})
+	if got := countTargets(snap.Encounters, "A Crocodile"); got != 2 {
+		t.Fatalf("coalesced encounters=%d want=2", got)
+	}
+}
+
+func TestEncounterSegmenter_HealingAggregation(t *testing.T) {
+	seg := NewEncounterSegmenter(10*time.Second, "")
+
+	seg.Process(model.Event{Timestamp: time.Unix(100, 0), Kind: model.KindMeleeDamage, Actor: "Alice", Target: "a rat", Amount: 10, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(101, 0), Kind: model.KindHeal, Actor: "Bob", Target: "Alice", SpellOrSkill: "Superior Healing", Amount: 300, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(103, 0), Kind: model.KindHeal, Actor: "Bob", Target: "Alice", SpellOrSkill: "Superior Healing", Amount: 500, AmountKnown: true, Crit: true})
+	seg.Process(model.Event{Timestamp: time.Unix(104, 0), Kind: model.KindHeal, Actor: "Carol", Target: "Bob", SpellOrSkill: "Light Healing", Amount: 200, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(109, 0), Kind: model.KindMeleeDamage, Actor: "Alice", Target: "a rat", Amount: 10, AmountKnown: true})
+
+	// Heals after the encounter has gone idle are not credited to it.
+	seg.Process(model.Event{Timestamp: time.Unix(130, 0), Kind: model.KindHeal, Actor: "Bob", Target: "Alice", Amount: 999, AmountKnown: true})
+
+	snap := seg.BuildSnapshot(time.Unix(200, 0), "", false, SnapshotOptions{IncludePCTargets: true})
+	if len(snap.Encounters) != 1 {
+		t.Fatalf("encounters=%d want=1", len(snap.Encounters))
+	}
+	ev := snap.Encounters[0]
+	if ev.TotalDamage != 20 {
+		t.Fatalf("totalDamage=%d want=20", ev.TotalDamage)
+	}
+	if ev.TotalHealing != 1000 {
+		t.Fatalf("totalHealing=%d want=1000", ev.TotalHealing)
+	}
+	if ev.HPSEncounter != 100 {
+		t.Fatalf("hpsEncounter=%v want=100", ev.HPSEncounter)
+	}
+	if len(ev.Healers) != 2 {
+		t.Fatalf("healers=%d want=2", len(ev.Healers))
+	}
+	bob := ev.Healers[0]
+	if bob.Healer != "Bob" || bob.Total != 800 || bob.Heals != 2 || bob.Crits != 1 || bob.MaxHeal != 500 {
+		t.Fatalf("bob=%+v", bob)
+	}
+	if bob.HPS != 80 || bob.ActiveSec != 3 || bob.CritPct != 50 {
+		t.Fatalf("bob hps=%v activeSec=%d critPct=%v", bob.HPS, bob.ActiveSec, bob.CritPct)
+	}
+	if len(bob.TopSpells) != 1 || bob.TopSpells[0].Spell != "Superior Healing" || bob.TopSpells[0].Total != 800 {
+		t.Fatalf("bob topSpells=%+v", bob.TopSpells)
+	}
+	if len(ev.HealTargets) != 2 || ev.HealTargets[0].Target != "Alice" || ev.HealTargets[0].Total != 800 {
+		t.Fatalf("healTargets=%+v", ev.HealTargets)
+	}
+}
+
+func TestEncounterSegmenter_DamageTaken(t *testing.T) {
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+
+	seg.Process(model.Event{Timestamp: time.Unix(100, 0), Kind: model.KindMeleeDamage, Actor: "Genaenyu", Target: "Oshiruk", Amount: 100, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(101, 0), Kind: model.KindMeleeDamage, Actor: "Oshiruk", Target: "Genaenyu", Verb: "hits", Amount: 500, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(102, 0), Kind: model.KindSpecialAttack, Actor: "Oshiruk", Modifier: model.ModifierWildRampage})
+	seg.Process(model.Event{Timestamp: time.Unix(102, 0), Kind: model.KindMeleeDamage, Actor: "Oshiruk", Target: "Genaenyu", Verb: "hits", Amount: 700, AmountKnown: true, Modifier: model.ModifierWildRampage})
+	seg.Process(model.Event{Timestamp: time.Unix(102, 0), Kind: model.KindMeleeDamage, Actor: "Oshiruk", Target: "Sigdis", Verb: "hits", Amount: 300, AmountKnown: true, Modifier: model.ModifierRampage})
+	seg.Process(model.Event{Timestamp: time.Unix(103, 0), Kind: model.KindIncomingDamage, Actor: "Oshiruk", Target: "Genaenyu", SpellOrSkill: "Tendrils of Oshiruk", Verb: "non-melee", Amount: 200, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(103, 0), Kind: model.KindAvoid, Actor: "Oshiruk", Target: "Genaenyu", Verb: "hit", Avoid: model.AvoidDodge})
+	seg.Process(model.Event{Timestamp: time.Unix(104, 0), Kind: model.KindAvoid, Actor: "Oshiruk", Target: "Genaenyu", Verb: "hit", Avoid: model.AvoidParry})
+	seg.Process(model.Event{Timestamp: time.Unix(104, 0), Kind: model.KindMiss, Actor: "Oshiruk", Target: "Genaenyu", Verb: "hit", Avoid: model.AvoidMiss})
+	seg.Process(model.Event{Timestamp: time.Unix(105, 0), Kind: model.KindMeleeDamage, Actor: "Genaenyu", Target: "Oshiruk", Amount: 100, AmountKnown: true})
+
+	snap := seg.BuildSnapshot(time.Unix(200, 0), "", false, SnapshotOptions{IncludePCTargets: true})
+	var ev EncounterView
+	for _, e := range snap.Encounters {
+		if e.Target == "Oshiruk" {
+			ev = e
+		}
+	}
+	if ev.TotalDamage != 200 {
+		t.Fatalf("target=%q totalDamage=%d", ev.Target, ev.TotalDamage)
+	}
+	if ev.TotalDamageTaken != 1700 {
+		t.Fatalf("totalDamageTaken=%d want=1700", ev.TotalDamageTaken)
+	}
+	if len(ev.Defenders) != 2 {
+		t.Fatalf("defenders=%d want=2", len(ev.Defenders))
+	}
+	tank := ev.Defenders[0]
+	if tank.Defender != "Genaenyu" || tank.Total != 1400 || tank.Hits != 3 || tank.MaxHit != 700 {
+		t.Fatalf("tank=%+v", tank)
+	}
+	if tank.Dodges != 1 || tank.Parries != 1 || tank.Misses != 1 || tank.AvoidPct != 50 {
+		t.Fatalf("tank avoidance dodges=%d parries=%d misses=%d avoidPct=%v", tank.Dodges, tank.Parries, tank.Misses, tank.AvoidPct)
+	}
+	if len(tank.Attacks) != 2 || tank.Attacks[0].Name != "hit" || tank.Attacks[0].Total != 1200 || tank.Attacks[1].Name != "Tendrils of Oshiruk" {
+		t.Fatalf("tank attacks=%+v", tank.Attacks)
+	}
+	if len(tank.Modifiers) != 2 || tank.Modifiers[0].Name != "Normal" || tank.Modifiers[0].Total != 700 || tank.Modifiers[1].Name != "Wild Rampage" || tank.Modifiers[1].Total != 700 {
+		t.Fatalf("tank modifiers=%+v", tank.Modifiers)
+	}
+	if ev.Defenders[1].Defender != "Sigdis" || ev.Defenders[1].Total != 300 {
+		t.Fatalf("second defender=%+v", ev.Defenders[1])
+	}
+	if ev.WildRampages != 1 || ev.Rampages != 0 || ev.Flurries != 0 {
+		t.Fatalf("rampages=%d wildRampages=%d flurries=%d", ev.Rampages, ev.WildRampages, ev.Flurries)
+	}
+}
+
+func TestEncounterSegmenter_PetRollup(t *testing.T) {
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+
+	seg.Process(model.Event{Timestamp: time.Unix(100, 0), Kind: model.KindMeleeDamage, DamageClass: model.DamageClassPierce, Actor: "Alice", Target: "a rat", Amount: 100, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(101, 0), Kind: model.KindMeleeDamage, DamageClass: model.DamageClassCrush, Actor: "Alice`s warder", ActorOwner: "Alice", Target: "a rat", Amount: 300, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(102, 0), Kind: model.KindMeleeDamage, DamageClass: model.DamageClassCrush, Actor: "Bob`s pet", ActorOwner: "Bob", Target: "a rat", Amount: 50, AmountKnown: true})
+
+	byActor := func(opts SnapshotOptions) map[string]ActorStatsView {
+		snap := seg.BuildSnapshot(time.Unix(200, 0), "", false, opts)
+		if len(snap.Encounters) != 1 {
+			t.Fatalf("encounters=%d want=1", len(snap.Encounters))
+		}
+		out := make(map[string]ActorStatsView)
+		for _, a := range snap.Encounters[0].Actors {
+			out[a.Actor] = a
+		}
+		return out
+	}
+
+	flat := byActor(SnapshotOptions{})
+	if len(flat) != 3 || flat["Alice"].Total != 100 || flat["Alice`s warder"].Total != 300 {
+		t.Fatalf("flat actors=%+v", flat)
+	}
+
+	rolled := byActor(SnapshotOptions{RollupPets: true})
+	if len(rolled) != 2 {
+		t.Fatalf("rolled actors=%+v", rolled)
+	}
+	if a := rolled["Alice"]; a.Total != 400 || a.PetDamage != 300 || a.Hits != 2 || a.PctTotal != 400.0/450.0*100 {
+		t.Fatalf("alice=%+v", a)
+	}
+	if b := rolled["Bob"]; b.Total != 50 || b.PetDamage != 50 {
+		t.Fatalf("bob=%+v", b)
+	}
+
+	encounterID := encounterID("a rat", time.Unix(100, 0), time.Unix(102, 0))
+	view, ok := seg.GetDamageBreakdown(encounterID, "Alice")
+	if !ok {
+		t.Fatalf("expected alice breakdown")
+	}
+	if len(view.Rows) != 1 || view.Rows[0].Damage != 100 {
+		t.Fatalf("alice rows=%+v", view.Rows)
+	}
+	if len(view.Pets) != 1 || view.Pets[0].Name != "Alice`s warder" || view.Pets[0].Damage != 300 || view.Pets[0].PctPlayer != 75 {
+		t.Fatalf("alice pets=%+v", view.Pets)
+	}
+	view, ok = seg.GetDamageBreakdown(encounterID, "Bob")
+	if !ok || len(view.Rows) != 0 || len(view.Pets) != 1 {
+		t.Fatalf("bob ok=%v view=%+v", ok, view)
+	}
+}
+
+func TestEncounterSegmenter_DisplayNames(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+	seg.SetNameTable(&ctx.Names)
+
+	lines := []string{
+		"[Sat Jan 24 23:14:00 2026] A training dummy tries to hit YOU, but misses!",
+		"[Sat Jan 24 23:14:01 2026] You pierce a training dummy for 100 points of damage.",
+		"[Sat Jan 24 23:14:02 2026] You pierce A training dummy for 100 points of damage.",
+		"[Sat Jan 24 23:14:03 2026] You pierce a training dummy  for 100 points of damage.",
+		"[Sat Jan 24 23:14:03 2026] You pierce a training dummy for 100 points of damage.",
+	}
+	processLines(t, seg, ctx, lines)
+
+	snap := seg.BuildSnapshot(time.Now(), "", false, SnapshotOptions{})
+	if len(snap.Encounters) != 1 {
+		t.Fatalf("encounters=%d want=1", len(snap.Encounters))
+	}
+	ev := snap.Encounters[0]
+	if ev.Target != "a training dummy" || ev.TotalDamage != 400 {
+		t.Fatalf("target=%q total=%d", ev.Target, ev.TotalDamage)
+	}
+	if _, ok := seg.BuildEncounterView(time.Now(), "", false, SnapshotOptions{}, ev.Target); !ok {
+		t.Fatalf("lookup by display name failed")
+	}
+	if _, ok := seg.GetDamageBreakdownByKey(ev.EncounterKey, "Genaenyu"); !ok {
+		t.Fatalf("breakdown by key failed")
+	}
+}
+
+func TestEncounterSegmenter_SpellResists(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+
+	lines := []string{
+		"[Sat Jan 24 23:17:00 2026] You slash Sharp Tooth for 5289 points of damage.",
+		"[Sat Jan 24 23:17:01 2026] Your target resisted the Sanity Warp spell.",
+		"[Sat Jan 24 23:17:02 2026] You begin casting Sanity Warp.",
+		"[Sat Jan 24 23:17:02 2026] Sharp Tooth is afflicted by Sanity Warp.",
+		"[Sat Jan 24 23:17:03 2026] Your target resisted the Sanity Warp spell.",
+		// Someone else's Sanity Warp; their resists are never seen, so it is not a landing.
+		"[Sat Jan 24 23:17:03 2026] Sharp Tooth is afflicted by Sanity Warp.",
+		"[Sat Jan 24 23:17:04 2026] You resist the Tail Sweep spell!",
+		"[Sat Jan 24 23:17:05 2026] You have taken 500 damage from Sharp Tooth by Tail Sweep.",
+		"[Sat Jan 24 23:17:06 2026] The Spellshield absorbed 35 of 35 points of damage",
+		"[Sat Jan 24 23:17:07 2026] You slash Sharp Tooth for 5289 points of damage.",
+	}
+	processLines(t, seg, ctx, lines)
+
+	snap := seg.BuildSnapshot(time.Now(), "", false, SnapshotOptions{})
+	if len(snap.Encounters) != 1 {
+		t.Fatalf("encounters=%d want=1", len(snap.Encounters))
+	}
+	enc := snap.Encounters[0]
+	if len(enc.SpellResists) != 1 {
+		t.Fatalf("spellResists=%+v", enc.SpellResists)
+	}
+	sw := enc.SpellResists[0]
+	if sw.Spell != "Sanity Warp" || sw.Landed != 1 || sw.Resisted != 2 {
+		t.Fatalf("sanity warp=%+v", sw)
+	}
+	if sw.ResistPct < 66.6 || sw.ResistPct > 66.7 {
+		t.Fatalf("resistPct=%v want=66.7", sw.ResistPct)
+	}
+	if len(enc.IncomingResists) != 1 {
+		t.Fatalf("incomingResists=%+v", enc.IncomingResists)
+	}
+	ts := enc.IncomingResists[0]
+	if ts.Spell != "Tail Sweep" || ts.Landed != 1 || ts.Resisted != 1 || ts.ResistPct != 50 {
+		t.Fatalf("tail sweep=%+v", ts)
+	}
+	if len(enc.Defenders) != 1 || enc.Defenders[0].Absorbed != 35 || enc.Defenders[0].Total != 500 {
+		t.Fatalf("defenders=%+v want Genaenyu with 500 taken and 35 absorbed", enc.Defenders)
+	}
+}
+
+func TestEncounterSegmenter_Zones(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+
+	lines := []string{
+		"[Fri Jan 23 07:45:44 2026] You have entered The Arena.",
+		"[Fri Jan 23 07:46:00 2026] You pierce A training dummy for 100 points of damage.",
+		"[Fri Jan 23 07:57:09 2026] You have entered Nexus.",
+		"[Fri Jan 23 07:58:00 2026] You pierce Lord Soth for 200 points of damage.",
+		"[Fri Jan 23 07:59:00 2026] You pierce A training dummy for 300 points of damage.",
+	}
+	processLines(t, seg, ctx, lines)
+
+	snap := seg.BuildSnapshot(time.Now(), "", false, SnapshotOptions{Zone: "the arena"})
+	if len(snap.Encounters) != 1 || snap.Encounters[0].Zone != "The Arena" || snap.Encounters[0].TotalDamage != 100 {
+		t.Fatalf("arena encounters=%+v", snap.Encounters)
+	}
+
+	snap = seg.BuildSnapshot(time.Now(), "", false, SnapshotOptions{GroupByZone: true})
+	var zones []string
+	for _, e := range snap.Encounters {
+		zones = append(zones, e.Zone)
+	}
+	if len(zones) != 3 || zones[0] != "Nexus" || zones[1] != "Nexus" || zones[2] != "The Arena" {
+		t.Fatalf("grouped zones=%v", zones)
+	}
+}
+
+func TestEncounterSegmenter_ChatTimeline(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+
+	lines := []string{
+		"[Sat Jan 24 23:17:50 2026] You tell your party, 'pulling'",
+		"[Sat Jan 24 23:17:56 2026] You pierce Lord Soth for 100 points of damage.",
+		"[Sat Jan 24 23:17:58 2026] Lord Soth shouts 'You dare challenge me?'",
+		"[Sat Jan 24 23:18:00 2026] You pierce Lord Soth for 100 points of damage.",
+		"[Sat Jan 24 23:18:30 2026] You say, 'gg'",
+	}
+	processLines(t, seg, ctx, lines)
+
+	snap := seg.BuildSnapshot(time.Now(), "", false, SnapshotOptions{})
+	if len(snap.Encounters) != 1 {
+		t.Fatalf("encounters=%d want=1", len(snap.Encounters))
+	}
+	view, ok := seg.BuildEncounterViewByKey(time.Now(), "", false, SnapshotOptions{}, "Lord Soth", snap.Encounters[0].Start)
+	if !ok {
+		t.Fatalf("expected encounter view")
+	}
+	if len(view.Chat) != 1 {
+		t.Fatalf("chat=%+v want only the in-window line", view.Chat)
+	}
+	c := view.Chat[0]
+	if c.Channel != "npc" || c.Speaker != "Lord Soth" || c.Message != "You dare challenge me?" {
+		t.Fatalf("chat line=%+v", c)
+	}
+}
+
+func TestEncounterSegmenter_StatusTimeline(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+
+	lines := []string{
+		"[Sat Jan 24 23:18:00 2026] You pierce Lord Soth for 100 points of damage.",
+		"[Sat Jan 24 23:18:01 2026] Lord Soth staggers.",
+		"[Sat Jan 24 23:18:02 2026] You have failed to taunt your target.",
+		"[Sat Jan 24 23:18:02 2026] You have failed to taunt your target.",
+		"[Sat Jan 24 23:18:03 2026] You are stunned!",
+		"[Sat Jan 24 23:18:05 2026] You are no longer stunned.",
+		"[Sat Jan 24 23:18:06 2026] A Crocodile staggers.",
+		"[Sat Jan 24 23:18:07 2026] You pierce Lord Soth for 100 points of damage.",
+	}
+	processLines(t, seg, ctx, lines)
+
+	snap := seg.BuildSnapshot(time.Now(), "", false, SnapshotOptions{})
+	if len(snap.Encounters) != 1 {
+		t.Fatalf("encounters=%d want=1", len(snap.Encounters))
+	}
+	byName := make(map[string]StatusStatsView)
+	for _, st := range snap.Encounters[0].Status {
+		byName[st.Subject] = st
+	}
+	if len(byName) != 2 {
+		t.Fatalf("status subjects=%+v want Lord Soth and Genaenyu only", snap.Encounters[0].Status)
+	}
+	if byName["Lord Soth"].Staggers != 1 {
+		t.Fatalf("Lord Soth=%+v", byName["Lord Soth"])
+	}
+	you := byName["Genaenyu"]
+	if you.FailedTaunts != 2 || you.Stuns != 1 || you.StunnedSec != 2 {
+		t.Fatalf("Genaenyu=%+v", you)
+	}
+
+	view, ok := seg.BuildEncounterView(time.Now(), "", false, SnapshotOptions{}, "Lord Soth")
+	if !ok || len(view.StatusTimeline) != 5 {
+		t.Fatalf("ok=%v timeline=%+v", ok, view.StatusTimeline)
+	}
+	if view.StatusTimeline[0].Effect != "stagger" || view.StatusTimeline[3].Effect != "stunned" {
+		t.Fatalf("timeline=%+v", view.StatusTimeline)
+	}
+}
+
+func TestEncounterSegmenter_TargetSwitchStartsNewPull(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+	seg.TargetSwitchGap = 3 * time.Second
+
+	lines := []string{
+		"[Sat Jan 24 23:17:00 2026] Targeted (NPC): a cave bat",
+		"[Sat Jan 24 23:17:01 2026] You slash a cave bat for 100 points of damage.",
+		"[Sat Jan 24 23:17:02 2026] Targeted (NPC): Sharp Tooth",
+		"[Sat Jan 24 23:17:03 2026] Your target resisted the Sanity Warp spell.",
+		"[Sat Jan 24 23:17:03 2026] Targeted (Player): Sigdis",
+		// Within the idle timeout, but the player switched back to the bat after five quiet
+		// seconds: a new pull.
+		"[Sat Jan 24 23:17:06 2026] Targeted (NPC): a cave bat",
+		"[Sat Jan 24 23:17:07 2026] You slash a cave bat for 200 points of damage.",
+		// Retargeting right away keeps the pull going.
+		"[Sat Jan 24 23:17:08 2026] Targeted (NPC): a cave bat",
+		"[Sat Jan 24 23:17:09 2026] You slash a cave bat for 300 points of damage.",
+	}
+	processLines(t, seg, ctx, lines)
+
+	encs := seg.Finalize()
+	if len(encs) != 2 {
+		t.Fatalf("encounters=%d want=2", len(encs))
+	}
+	if encs[0].Total != 100 || !encs[0].Retargeted || encs[1].Total != 500 || encs[1].Retargeted {
+		t.Fatalf("first=%d/%v second=%d/%v", encs[0].Total, encs[0].Retargeted, encs[1].Total, encs[1].Retargeted)
+	}
+	snap := seg.BuildSnapshot(time.Now(), "", false, SnapshotOptions{})
+	if len(snap.Encounters) != 2 {
+		t.Fatalf("snapshot encounters=%d want=2 (retargeted pulls must not coalesce)", len(snap.Encounters))
+	}
+}
+
+func TestEncounterSegmenter_RetargetingSameNPCKeepsPull(t *testing.T) {
+	lines := []string{
+		"[Sat Jan 24 23:17:00 2026] Targeted (NPC): a cave bat",
+		"[Sat Jan 24 23:17:01 2026] You slash a cave bat for 100 points of damage.",
+		// Same NPC after a quiet spell, e.g. re-acquiring it after it fled.
+		"[Sat Jan 24 23:17:07 2026] Targeted (NPC): a cave bat",
+		"[Sat Jan 24 23:17:08 2026] You slash a cave bat for 200 points of damage.",
+		"[Sat Jan 24 23:17:09 2026] Targeted (NPC): Sharp Tooth",
+		// A switch to a different NPC and back after seven quiet seconds: a new pull unless
+		// the gap check is off.
+		"[Sat Jan 24 23:17:15 2026] Targeted (NPC): a cave bat",
+		"[Sat Jan 24 23:17:16 2026] You slash a cave bat for 300 points of damage.",
+	}
+	if gap := NewEncounterSegmenter(0, "").TargetSwitchGap; gap != DefaultTargetSwitchGap {
+		t.Fatalf("default gap=%v want=%v", gap, DefaultTargetSwitchGap)
+	}
+	for _, gap := range []time.Duration{0, 3 * time.Second, DefaultTargetSwitchGap} {
+		ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+		seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+		seg.TargetSwitchGap = gap
+		processLines(t, seg, ctx, lines)
+
+		encs := seg.Finalize()
+		want := 1
+		if gap > 0 {
+			want = 2
+		}
+		if len(encs) != want || encs[0].Retargeted != (gap > 0) {
+			t.Fatalf("gap=%v encounters=%d want=%d", gap, len(encs), want)
+		}
+		if gap > 0 && encs[0].Total != 300 {
+			t.Fatalf("gap=%v first pull=%d want=300", gap, encs[0].Total)
+		}
+	}
+}
+
+func TestEncounterSegmenter_SpellCasts(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+
+	lines := []string{
+		"[Sat Jan 24 23:17:00 2026] You slash Sharp Tooth for 5289 points of damage.",
+		"[Sat Jan 24 23:17:01 2026] You begin casting Sanity Warp.",
+		"[Sat Jan 24 23:17:02 2026] Your spell is interrupted.",
+		"[Sat Jan 24 23:17:03 2026] You begin casting Sanity Warp.",
+		"[Sat Jan 24 23:17:04 2026] Sharp Tooth is afflicted by Sanity Warp.",
+		"[Sat Jan 24 23:17:05 2026] You begin casting Sanity Warp.",
+		"[Sat Jan 24 23:17:06 2026] Your target resisted the Sanity Warp spell.",
+		"[Sat Jan 24 23:17:06 2026] Sharp Tooth begins casting Tail Sweep.",
+		"[Sat Jan 24 23:17:07 2026] Sharp Tooth's casting is interrupted!",
+		"[Sat Jan 24 23:17:07 2026] Sigdis begins casting Complete Heal.",
+		"[Sat Jan 24 23:17:08 2026] Sigdis regains concentration and continues casting.",
+		"[Sat Jan 24 23:17:09 2026] You slash Sharp Tooth for 5289 points of damage.",
+	}
+	processLines(t, seg, ctx, lines)
+
+	snap := seg.BuildSnapshot(time.Now(), "", false, SnapshotOptions{})
+	if len(snap.Encounters) != 1 {
+		t.Fatalf("encounters=%d want=1", len(snap.Encounters))
+	}
+	casts := snap.Encounters[0].SpellCasts
+	if len(casts) != 3 {
+		t.Fatalf("spellCasts=%+v", casts)
+	}
+	want := []SpellCastView{
+		{Caster: "Genaenyu", Spell: "Sanity Warp", Started: 3, Landed: 1, Interrupted: 1, Resisted: 1},
+		{Caster: "Sharp Tooth", Spell: "Tail Sweep", Started: 1, Interrupted: 1},
+		{Caster: "Sigdis", Spell: "Complete Heal", Started: 1, Recovered: 1},
+	}
+	for i, w := range want {
+		if casts[i] != w {
+			t.Fatalf("casts[%d]=%+v want=%+v", i, casts[i], w)
+		}
+	}
+}
+
+func TestEncounterSegmenter_HostileSwingsBecomeDamageTaken(t *testing.T) {
+	seg := NewEncounterSegmenter(8*time.Second, "Genaenyu")
+
+	// The crocodile swings at the cleric before anyone has identified Sigdis as player-side.
+	seg.Process(model.Event{Timestamp: time.Unix(100, 0), Kind: model.KindMeleeDamage, Actor: "Genaenyu", Target: "A Crocodile", Amount: 100, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(101, 0), Kind: model.KindMeleeDamage, Actor: "A Crocodile", Target: "Sigdis", Verb: "hits", Amount: 400, AmountKnown: true})
+	// A mob swinging at the local player's pet is hostile too.
+	seg.Process(model.Event{Timestamp: time.Unix(102, 0), Kind: model.KindMeleeDamage, Actor: "A Snake", Target: "Genaenyu`s pet", Verb: "bites", Amount: 50, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(103, 0), Kind: model.KindMeleeDamage, Actor: "Genaenyu", Target: "A Crocodile", Amount: 100, AmountKnown: true})
+
+	encs := seg.Finalize()
+	if len(encs) != 1 {
+		t.Fatalf("encounters=%d want=1", len(encs))
+	}
+	croc := encs[0]
+	if croc.Target != "A Crocodile" || croc.Total != 200 {
+		t.Fatalf("target=%q total=%d", croc.Target, croc.Total)
+	}
+	if _, ok := croc.ByActor["A Crocodile"]; ok {
+		t.Fatalf("hostile swing counted as outgoing damage")
+	}
+	if st := croc.ByDefender["Sigdis"]; st == nil || st.Total != 400 {
+		t.Fatalf("sigdis taken=%+v", st)
+	}
+}
+
+func TestEncounterSegmenter_HealingCreditedToOneEncounter(t *testing.T) {
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+
+	seg.Process(model.Event{Timestamp: time.Unix(100, 0), Kind: model.KindMeleeDamage, Actor: "YOU", Target: "a rat", Amount: 10, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(101, 0), Kind: model.KindMeleeDamage, Actor: "Sigdis", Target: "a bat", Amount: 10, AmountKnown: true})
+	// The local player is on the rat, so the heal belongs there.
+	seg.Process(model.Event{Timestamp: time.Unix(102, 0), Kind: model.KindHeal, Actor: "Bob", Target: "Sigdis", Amount: 300, AmountKnown: true})
+
+	other := NewEncounterSegmenter(10*time.Second, "")
+	other.Process(model.Event{Timestamp: time.Unix(100, 0), Kind: model.KindMeleeDamage, Actor: "Alice", Target: "a rat", Amount: 10, AmountKnown: true})
+	other.Process(model.Event{Timestamp: time.Unix(101, 0), Kind: model.KindMeleeDamage, Actor: "Sigdis", Target: "a bat", Amount: 10, AmountKnown: true})
+	// No known target: the most recently active encounter gets it.
+	other.Process(model.Event{Timestamp: time.Unix(102, 0), Kind: model.KindHeal, Actor: "Bob", Target: "Sigdis", Amount: 300, AmountKnown: true})
+
+	for _, tc := range []struct {
+		seg  *EncounterSegmenter
+		want string
+	}{{seg, "a rat"}, {other, "a bat"}} {
+		snap := tc.seg.BuildSnapshot(time.Unix(200, 0), "", false, SnapshotOptions{IncludePCTargets: true})
+		var total int64
+		for _, e := range snap.Encounters {
+			total += e.TotalHealing
+			if e.TotalHealing != 0 && e.Target != tc.want {
+				t.Fatalf("heal credited to %q want %q", e.Target, tc.want)
+			}
+		}
+		if total != 300 {
+			t.Fatalf("total healing across encounters=%d want=300", total)
+		}
+	}
+}
+
+func TestEncounterSegmenter_AnonymousDamageTakenCountedOnce(t *testing.T) {
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+	seg.Process(model.Event{Timestamp: time.Unix(100, 0), Kind: model.KindMeleeDamage, Actor: "Genaenyu", Target: "a rat", Amount: 10, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(101, 0), Kind: model.KindMeleeDamage, Actor: "Sigdis", Target: "a bat", Amount: 10, AmountKnown: true})
+	// "You have taken 66 points of non-melee damage." names no attacker.
+	seg.Process(model.Event{Timestamp: time.Unix(102, 0), Kind: model.KindIncomingDamage, Target: "Genaenyu", Amount: 66, AmountKnown: true})
+
+	snap := seg.BuildSnapshot(time.Unix(200, 0), "", false, SnapshotOptions{IncludePCTargets: true})
+	if len(snap.Encounters) != 2 {
+		t.Fatalf("encounters=%d want=2", len(snap.Encounters))
+	}
+	var total int64
+	for _, e := range snap.Encounters {
+		total += e.TotalDamageTaken
+		if e.TotalDamageTaken != 0 && e.Target != "a rat" {
+			t.Fatalf("damage taken credited to %q want %q", e.Target, "a rat")
+		}
+	}
+	if total != 66 {
+		t.Fatalf("total taken across encounters=%d want=66", total)
+	}
+}
+
+func TestEncounterSegmenter_IncomingResistCountedOnce(t *testing.T) {
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+	seg.Process(model.Event{Timestamp: time.Unix(100, 0), Kind: model.KindMeleeDamage, Actor: "Genaenyu", Target: "a rat", Amount: 10, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(101, 0), Kind: model.KindMeleeDamage, Actor: "Sigdis", Target: "a bat", Amount: 10, AmountKnown: true})
+	// "You resist the Tail Sweep spell!" names no caster.
+	seg.Process(model.Event{Timestamp: time.Unix(102, 0), Kind: model.KindResist, Target: "Genaenyu", SpellOrSkill: "Tail Sweep"})
+
+	snap := seg.BuildSnapshot(time.Unix(200, 0), "", false, SnapshotOptions{IncludePCTargets: true})
+	var n int
+	for _, e := range snap.Encounters {
+		n += len(e.IncomingResists)
+		if len(e.IncomingResists) != 0 && e.Target != "a rat" {
+			t.Fatalf("resist credited to %q want %q", e.Target, "a rat")
+		}
+	}
+	if n != 1 {
+		t.Fatalf("resist counted in %d encounters want 1", n)
+	}
+}
+
+func TestEncounterSegmenter_LocalStatusCreditedToOneEncounter(t *testing.T) {
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+	seg.Process(model.Event{Timestamp: time.Unix(100, 0), Kind: model.KindMeleeDamage, Actor: "Genaenyu", Target: "a rat", Amount: 10, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(101, 0), Kind: model.KindMeleeDamage, Actor: "Sigdis", Target: "a bat", Amount: 10, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(102, 0), Kind: model.KindStatus, Target: "Genaenyu", Status: model.StatusStunned})
+	seg.Process(model.Event{Timestamp: time.Unix(104, 0), Kind: model.KindStatus, Target: "Genaenyu", Status: model.StatusStunEnded})
+
+	snap := seg.BuildSnapshot(time.Unix(200, 0), "", false, SnapshotOptions{})
+	if len(snap.Encounters) != 2 {
+		t.Fatalf("encounters=%d want=2", len(snap.Encounters))
+	}
+	for _, e := range snap.Encounters {
+		if e.Target != "a rat" && len(e.Status) != 0 {
+			t.Fatalf("%s status=%+v want none", e.Target, e.Status)
+		}
+		if e.Target == "a rat" && (len(e.Status) != 1 || e.Status[0].Stuns != 1 || e.Status[0].StunnedSec != 2) {
+			t.Fatalf("a rat status=%+v", e.Status)
+		}
+	}
+}
+
+func TestEncounterSegmenter_ClockJumpClosesEncounters(t *testing.T) {
+	seg := NewEncounterSegmenter(10*time.Second, "Genaenyu")
+	seg.Process(model.Event{Timestamp: time.Unix(3600, 0), Kind: model.KindMeleeDamage, Actor: "Genaenyu", Target: "a rat", Amount: 10, AmountKnown: true})
+	seg.Process(model.Event{Timestamp: time.Unix(3602, 0), Kind: model.KindMeleeDamage, Actor: "Genaenyu", Target: "a rat", Amount: 10, AmountKnown: true})
+	// The clock went back an hour mid-fight.
+	seg.Process(model.Event{Timestamp: time.Unix(5, 0), Kind: model.KindClockJump, ClockJump: -time.Hour + 3*time.Second})
+	seg.Process(model.Event{Timestamp: time.Unix(5, 0), Kind: model.KindMeleeDamage, Actor: "Genaenyu", Target: "a rat", Amount: 10, AmountKnown: true})
+
+	encs := seg.Finalize()
+	if len(encs) != 2 {
+		t.Fatalf("encounters=%d want=2", len(encs))
+	}
+	before := encs[1]
+	if !before.ClockJumped || before.Total != 20 || before.DurationSeconds() != 3 {
+		t.Fatalf("before jump=%+v", before)
+	}
+	if after := encs[0]; after.ClockJumped || after.Total != 10 || after.DurationSeconds() != 1 {
+		t.Fatalf("after jump=%+v", after)
+	}
+}
@@ -0,0 +1,375 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+// unknownHealer labels heals whose log line does not name the caster
+// (e.g. "You have been healed for N points.").
+const unknownHealer = "Unknown"
+
+const maxTopHealSpells = 5
+
+type HealSpellStats struct {
+	Spell string
+	Heals int64
+	Total int64
+}
+
+type EncounterHealerStats struct {
+	Healer    string
+	Total     int64
+	Heals     int64
+	CritHeals int64
+	MaxHeal   int64
+	FirstHeal time.Time
+	LastHeal  time.Time
+	BySpell   map[string]*HealSpellStats
+	ByTarget  map[string]int64
+}
+
+type EncounterHealTargetStats struct {
+	Target string
+	Total  int64
+	Heals  int64
+}
+
+type HealSpellView struct {
+	Spell     string  `json:"spell"`
+	Heals     int64   `json:"heals"`
+	Total     int64   `json:"total"`
+	PctHealer float64 `json:"pctHealer"`
+}
+
+type HealerStatsView struct {
+	Healer    string          `json:"healer"`
+	Total     int64           `json:"total"`
+	HPS       float64         `json:"hpsEncounter"`
+	SHPS      float64         `json:"shps"`
+	ActiveSec int64           `json:"activeSec"`
+	PctTotal  float64         `json:"pctTotal"`
+	Heals     int64           `json:"heals"`
+	MaxHeal   int64           `json:"maxHeal"`
+	AvgHeal   float64         `json:"avgHeal"`
+	CritPct   float64         `json:"critPct"`
+	Crits     int64           `json:"crits"`
+	TopSpells []HealSpellView `json:"topSpells"`
+}
+
+type HealTargetView struct {
+	Target   string  `json:"target"`
+	Total    int64   `json:"total"`
+	Heals    int64   `json:"heals"`
+	PctTotal float64 `json:"pctTotal"`
+}
+
+func isEncounterHealEvent(ev model.Event) bool {
+	return ev.Kind == model.KindHeal && ev.AmountKnown && ev.Amount > 0
+}
+
+// addHealToActive credits a heal to the encounter it was most likely cast for (see
+// focusEncounter). Heals name no hostile, so with overlapping encounters (e.g. boss plus
+// adds) crediting every one of them would count the same heal several times.
+func (s *EncounterSegmenter) addHealToActive(ev model.Event) {
+	if ae := s.focusEncounter(ev); ae != nil {
+		ae.enc.addHeal(ev)
+	}
+}
+
+// focusEncounter picks the one live encounter an event that names no hostile belongs to:
+// the local player's current target when that encounter is live, otherwise the live
+// encounter with the most recent activity.
+func (s *EncounterSegmenter) focusEncounter(ev model.Event) *activeEncounter {
+	if ae := s.active[s.lastLocalTarget]; s.isLive(ae, ev) {
+		return ae
+	}
+	var best *activeEncounter
+	for _, ae := range s.active {
+		if !s.isLive(ae, ev) {
+			continue
+		}
+		if best == nil || ae.lastTs.After(best.lastTs) || (ae.lastTs.Equal(best.lastTs) && ae.enc.Target < best.enc.Target) {
+			best = ae
+		}
+	}
+	return best
+}
+
+func (e *Encounter) addHeal(ev model.Event) {
+	healer := ev.Actor
+	if healer == "" {
+		healer = unknownHealer
+	}
+	if e.ByHealer == nil {
+		e.ByHealer = make(map[string]*EncounterHealerStats)
+	}
+	if e.ByHealTarget == nil {
+		e.ByHealTarget = make(map[string]*EncounterHealTargetStats)
+	}
+
+	st := e.ByHealer[healer]
+	if st == nil {
+		st = &EncounterHealerStats{Healer: healer, BySpell: make(map[string]*HealSpellStats), ByTarget: make(map[string]int64)}
+		e.ByHealer[healer] = st
+	}
+	if st.FirstHeal.IsZero() || ev.Timestamp.Before(st.FirstHeal) {
+		st.FirstHeal = ev.Timestamp
+	}
+	if st.LastHeal.IsZero() || ev.Timestamp.After(st.LastHeal) {
+		st.LastHeal = ev.Timestamp
+	}
+	st.Total += ev.Amount
+	st.Heals++
+	if ev.Crit {
+		st.CritHeals++
+	}
+	if ev.Amount > st.MaxHeal {
+		st.MaxHeal = ev.Amount
+	}
+	if ev.SpellOrSkill != "" {
+		sp := st.BySpell[ev.SpellOrSkill]
+		if sp == nil {
+			sp = &HealSpellStats{Spell: ev.SpellOrSkill}
+			st.BySpell[ev.SpellOrSkill] = sp
+		}
+		sp.Heals++
+		sp.Total += ev.Amount
+	}
+	if ev.Target != "" {
+		st.ByTarget[ev.Target] += ev.Amount
+
+		ht := e.ByHealTarget[ev.Target]
+		if ht == nil {
+			ht = &EncounterHealTargetStats{Target: ev.Target}
+			e.ByHealTarget[ev.Target] = ht
+		}
+		ht.Total += ev.Amount
+		ht.Heals++
+	}
+	e.TotalHealing += ev.Amount
+}
+
+func copyHealerStats(s *EncounterHealerStats) *EncounterHealerStats {
+	if s == nil {
+		return nil
+	}
+	out := *s
+	out.BySpell = make(map[string]*HealSpellStats, len(s.BySpell))
+	for k, v := range s.BySpell {
+		if v == nil {
+			continue
+		}
+		cp := *v
+		out.BySpell[k] = &cp
+	}
+	out.ByTarget = make(map[string]int64, len(s.ByTarget))
+	for k, v := range s.ByTarget {
+		out.ByTarget[k] = v
+	}
+	return &out
+}
+
+func copyHealing(dst, src *Encounter) {
+	dst.TotalHealing = src.TotalHealing
+	if src.ByHealer != nil {
+		dst.ByHealer = make(map[string]*EncounterHealerStats, len(src.ByHealer))
+		for k, v := range src.ByHealer {
+			dst.ByHealer[k] = copyHealerStats(v)
+		}
+	}
+	if src.ByHealTarget != nil {
+		dst.ByHealTarget = make(map[string]*EncounterHealTargetStats, len(src.ByHealTarget))
+		for k, v := range src.ByHealTarget {
+			if v == nil {
+				continue
+			}
+			cp := *v
+			dst.ByHealTarget[k] = &cp
+		}
+	}
+}
+
+func mergeHealing(dst, src *Encounter) {
+	dst.TotalHealing += src.TotalHealing
+	if len(src.ByHealer) > 0 && dst.ByHealer == nil {
+		dst.ByHealer = make(map[string]*EncounterHealerStats)
+	}
+	for healer, st := range src.ByHealer {
+		if st == nil {
+			continue
+		}
+		ex := dst.ByHealer[healer]
+		if ex == nil {
+			dst.ByHealer[healer] = copyHealerStats(st)
+			continue
+		}
+		ex.Total += st.Total
+		ex.Heals += st.Heals
+		ex.CritHeals += st.CritHeals
+		if st.MaxHeal > ex.MaxHeal {
+			ex.MaxHeal = st.MaxHeal
+		}
+		if ex.FirstHeal.IsZero() || (!st.FirstHeal.IsZero() && st.FirstHeal.Before(ex.FirstHeal)) {
+			ex.FirstHeal = st.FirstHeal
+		}
+		if ex.LastHeal.IsZero() || (!st.LastHeal.IsZero() && st.LastHeal.After(ex.LastHeal)) {
+			ex.LastHeal = st.LastHeal
+		}
+		if ex.BySpell == nil {
+			ex.BySpell = make(map[string]*HealSpellStats)
+		}
+		for spell, sp := range st.BySpell {
+			if sp == nil {
+				continue
+			}
+			es := ex.BySpell[spell]
+			if es == nil {
+				cp := *sp
+				ex.BySpell[spell] = &cp
+				continue
+			}
+			es.Heals += sp.Heals
+			es.Total += sp.Total
+		}
+		if ex.ByTarget == nil {
+			ex.ByTarget = make(map[string]int64)
+		}
+		for t, v := range st.ByTarget {
+			ex.ByTarget[t] += v
+		}
+	}
+	if len(src.ByHealTarget) > 0 && dst.ByHealTarget == nil {
+		dst.ByHealTarget = make(map[string]*EncounterHealTargetStats)
+	}
+	for t, ht := range src.ByHealTarget {
+		if ht == nil {
+			continue
+		}
+		ex := dst.ByHealTarget[t]
+		if ex == nil {
+			cp := *ht
+			dst.ByHealTarget[t] = &cp
+			continue
+		}
+		ex.Total += ht.Total
+		ex.Heals += ht.Heals
+	}
+}
+
+func (e *Encounter) HealersSortedByTotal() []*EncounterHealerStats {
+	out := make([]*EncounterHealerStats, 0, len(e.ByHealer))
+	for _, st := range e.ByHealer {
+		if st == nil {
+			continue
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total == out[j].Total {
+			return out[i].Healer < out[j].Healer
+		}
+		return out[i].Total > out[j].Total
+	})
+	return out
+}
+
+func buildHealerViews(enc *Encounter, encSec int64) []HealerStatsView {
+	if enc == nil || len(enc.ByHealer) == 0 {
+		return nil
+	}
+	healers := enc.HealersSortedByTotal()
+	out := make([]HealerStatsView, 0, len(healers))
+	for _, st := range healers {
+		activeSec := durationSecondsInt(st.FirstHeal, st.LastHeal)
+		hps := 0.0
+		if encSec > 0 {
+			hps = float64(st.Total) / float64(encSec)
+		}
+		shps := 0.0
+		if activeSec > 0 {
+			shps = float64(st.Total) / float64(activeSec)
+		}
+		pctTotal := 0.0
+		if enc.TotalHealing > 0 {
+			pctTotal = (float64(st.Total) / float64(enc.TotalHealing)) * 100
+		}
+		avgHeal := 0.0
+		if st.Heals > 0 {
+			avgHeal = float64(st.Total) / float64(st.Heals)
+		}
+		critPct := 0.0
+		if st.Heals > 0 {
+			critPct = (float64(st.CritHeals) / float64(st.Heals)) * 100
+		}
+
+		out = append(out, HealerStatsView{
+			Healer:    st.Healer,
+			Total:     st.Total,
+			HPS:       hps,
+			SHPS:      shps,
+			ActiveSec: activeSec,
+			PctTotal:  pctTotal,
+			Heals:     st.Heals,
+			MaxHeal:   st.MaxHeal,
+			AvgHeal:   avgHeal,
+			CritPct:   critPct,
+			Crits:     st.CritHeals,
+			TopSpells: topHealSpells(st, maxTopHealSpells),
+		})
+	}
+	return out
+}
+
+func topHealSpells(st *EncounterHealerStats, n int) []HealSpellView {
+	if st == nil || len(st.BySpell) == 0 {
+		return nil
+	}
+	out := make([]HealSpellView, 0, len(st.BySpell))
+	for _, sp := range st.BySpell {
+		if sp == nil {
+			continue
+		}
+		pct := 0.0
+		if st.Total > 0 {
+			pct = (float64(sp.Total) / float64(st.Total)) * 100
+		}
+		out = append(out, HealSpellView{Spell: sp.Spell, Heals: sp.Heals, Total: sp.Total, PctHealer: pct})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total == out[j].Total {
+			return out[i].Spell < out[j].Spell
+		}
+		return out[i].Total > out[j].Total
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+func buildHealTargetViews(enc *Encounter) []HealTargetView {
+	if enc == nil || len(enc.ByHealTarget) == 0 {
+		return nil
+	}
+	out := make([]HealTargetView, 0, len(enc.ByHealTarget))
+	for _, ht := range enc.ByHealTarget {
+		if ht == nil {
+			continue
+		}
+		pct := 0.0
+		if enc.TotalHealing > 0 {
+			pct = (float64(ht.Total) / float64(enc.TotalHealing)) * 100
+		}
+		out = append(out, HealTargetView{Target: ht.Target, Total: ht.Total, Heals: ht.Heals, PctTotal: pct})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total == out[j].Total {
+			return out[i].Target < out[j].Target
+		}
+		return out[i].Total > out[j].Total
+	})
+	return out
+}
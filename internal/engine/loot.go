@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+const DefaultSessionGap = 30 * time.Minute
+
+type LootEntry struct {
+	Timestamp time.Time
+	Looter    string
+	Item      string
+	Quantity  int64
+	Corpse    string
+}
+
+// ZoneLootStats collects the drops and progression seen during one visit to a zone.
+type ZoneLootStats struct {
+	Zone     string
+	Start    time.Time
+	End      time.Time
+	Drops    []LootEntry
+	AAPoints int64
+	// XPEvents counts "You gain ... experience!!" lines, XPByType splits them by Verb.
+	XPEvents int64
+	XPByType map[string]int64
+}
+
+// LootSession is a stretch of log with no gap longer than the log's SessionGap.
+type LootSession struct {
+	Start time.Time
+	End   time.Time
+	Zones []*ZoneLootStats
+}
+
+func (s *LootSession) Drops() int {
+	n := 0
+	for _, z := range s.Zones {
+		n += len(z.Drops)
+	}
+	return n
+}
+
+func (s *LootSession) AAPoints() int64 {
+	n := int64(0)
+	for _, z := range s.Zones {
+		n += z.AAPoints
+	}
+	return n
+}
+
+func (s *LootSession) XPEvents() int64 {
+	n := int64(0)
+	for _, z := range s.Zones {
+		n += z.XPEvents
+	}
+	return n
+}
+
+// InZone returns the session narrowed to its visits to zone (case-insensitive), so the
+// totals cover only those visits, or nil when the session never went there. An empty zone
+// returns the session itself.
+func (s *LootSession) InZone(zone string) *LootSession {
+	if zone == "" {
+		return s
+	}
+	out := &LootSession{Start: s.Start, End: s.End}
+	for _, z := range s.Zones {
+		if strings.EqualFold(z.Zone, zone) {
+			out.Zones = append(out.Zones, z)
+		}
+	}
+	if len(out.Zones) == 0 {
+		return nil
+	}
+	return out
+}
+
+// LootLog splits a log into play sessions and records loot, experience and ability points
+// per zone visit. Every event should be processed so session gaps are measured correctly.
+type LootLog struct {
+	SessionGap time.Duration
+
+	sessions []*LootSession
+	lastTs   time.Time
+}
+
+func NewLootLog(sessionGap time.Duration) *LootLog {
+	if sessionGap <= 0 {
+		sessionGap = DefaultSessionGap
+	}
+	return &LootLog{SessionGap: sessionGap}
+}
+
+func (l *LootLog) Sessions() []*LootSession {
+	return l.sessions
+}
+
+func (l *LootLog) Process(ev model.Event) {
+	if ev.Timestamp.IsZero() || ev.Kind == model.KindClockJump {
+		return
+	}
+	sess := l.current(ev.Timestamp)
+	l.lastTs = ev.Timestamp
+	sess.End = ev.Timestamp
+
+	switch ev.Kind {
+	case model.KindLoot, model.KindExperience, model.KindAbilityPoint:
+	default:
+		return
+	}
+
+	z := l.zone(sess, ev)
+	z.End = ev.Timestamp
+	switch ev.Kind {
+	case model.KindLoot:
+		z.Drops = append(z.Drops, LootEntry{
+			Timestamp: ev.Timestamp,
+			Looter:    ev.Actor,
+			Item:      ev.Item,
+			Quantity:  ev.Amount,
+			Corpse:    ev.Target,
+		})
+	case model.KindExperience:
+		z.XPEvents++
+		z.XPByType[ev.Verb]++
+	case model.KindAbilityPoint:
+		z.AAPoints += ev.Amount
+	}
+}
+
+func (l *LootLog) current(ts time.Time) *LootSession {
+	n := len(l.sessions)
+	if n == 0 || ts.Sub(l.lastTs) > l.SessionGap {
+		l.sessions = append(l.sessions, &LootSession{Start: ts, End: ts})
+		return l.sessions[n]
+	}
+	return l.sessions[n-1]
+}
+
+// zone returns the session's stats for the visit to ev.Zone, starting a new visit when the
+// last loot or experience line came from a different zone.
+func (l *LootLog) zone(sess *LootSession, ev model.Event) *ZoneLootStats {
+	if n := len(sess.Zones); n > 0 && sess.Zones[n-1].Zone == ev.Zone {
+		return sess.Zones[n-1]
+	}
+	z := &ZoneLootStats{Zone: ev.Zone, Start: ev.Timestamp, End: ev.Timestamp, XPByType: make(map[string]int64)}
+	sess.Zones = append(sess.Zones, z)
+	return z
+}
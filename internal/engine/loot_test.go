@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+func TestLootLog_SessionsAndZones(t *testing.T) {
+	base := time.Date(2026, 1, 24, 23, 0, 0, 0, time.UTC)
+	l := NewLootLog(30 * time.Minute)
+
+	events := []model.Event{
+		{Timestamp: base, Kind: model.KindZoneOrSystem, SpellOrSkill: "zone", Zone: "Nexus"},
+		{Timestamp: base.Add(1 * time.Minute), Kind: model.KindExperience, Verb: "party", Zone: "Nexus"},
+		{Timestamp: base.Add(2 * time.Minute), Kind: model.KindAbilityPoint, Amount: 3, AmountKnown: true, Zone: "Nexus"},
+		{Timestamp: base.Add(3 * time.Minute), Kind: model.KindLoot, Actor: "Genaenyu", Item: "Abyssal Ring", Amount: 1, AmountKnown: true, Zone: "Nexus"},
+		{Timestamp: base.Add(4 * time.Minute), Kind: model.KindMeleeDamage, Actor: "Genaenyu", Target: "Lord Soth", Amount: 10, AmountKnown: true, Zone: "The Arena"},
+		{Timestamp: base.Add(5 * time.Minute), Kind: model.KindLoot, Actor: "Sigdis", Item: "Reaper`s Revenge", Amount: 1, AmountKnown: true, Zone: "The Arena"},
+		// An hour of silence starts a new session.
+		{Timestamp: base.Add(70 * time.Minute), Kind: model.KindExperience, Verb: "solo", Zone: "The Arena"},
+	}
+	for _, ev := range events {
+		l.Process(ev)
+	}
+
+	sessions := l.Sessions()
+	if len(sessions) != 2 {
+		t.Fatalf("sessions=%d want=2", len(sessions))
+	}
+	first := sessions[0]
+	if len(first.Zones) != 2 || first.Zones[0].Zone != "Nexus" || first.Zones[1].Zone != "The Arena" {
+		t.Fatalf("zones=%+v", first.Zones)
+	}
+	if first.Drops() != 2 || first.AAPoints() != 3 || first.XPEvents() != 1 {
+		t.Fatalf("drops=%d aa=%d xp=%d", first.Drops(), first.AAPoints(), first.XPEvents())
+	}
+	if first.Zones[0].XPByType["party"] != 1 || first.Zones[1].Drops[0].Looter != "Sigdis" {
+		t.Fatalf("nexus=%+v arena=%+v", first.Zones[0], first.Zones[1])
+	}
+	if !first.End.Equal(base.Add(5 * time.Minute)) {
+		t.Fatalf("session end=%v", first.End)
+	}
+	if second := sessions[1]; len(second.Zones) != 1 || second.Zones[0].XPByType["solo"] != 1 {
+		t.Fatalf("second session=%+v", second.Zones)
+	}
+
+	arena := first.InZone("the arena")
+	if arena == nil || len(arena.Zones) != 1 || arena.Drops() != 1 || arena.AAPoints() != 0 || arena.XPEvents() != 0 {
+		t.Fatalf("arena-only session=%+v", arena)
+	}
+	if sessions[1].InZone("Nexus") != nil {
+		t.Fatalf("session without Nexus visits kept")
+	}
+}
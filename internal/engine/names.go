@@ -0,0 +1,52 @@
+package engine
+
+import "github.com/ZehenForever/eqemu-log-parser/internal/model"
+
+// SetNameTable lets views show the nicest spelling of each name (see model.NameTable).
+// The table is normally &ParseContext.Names and keeps filling in as lines are parsed.
+func (s *EncounterSegmenter) SetNameTable(t *model.NameTable) {
+	s.names = t
+}
+
+// DisplayName returns the spelling views should show for a canonical actor or target name.
+func (s *EncounterSegmenter) DisplayName(name string) string {
+	if s.names == nil {
+		return name
+	}
+	if d, ok := s.names.Display[name]; ok {
+		return d
+	}
+	return name
+}
+
+// canonicalName maps a name coming back from a view (possibly a display spelling) to the
+// canonical spelling encounters are keyed by.
+func (s *EncounterSegmenter) canonicalName(name string) string {
+	if s.names == nil {
+		return name
+	}
+	if c, ok := s.names.Canonical[name]; ok {
+		return c
+	}
+	return name
+}
+
+func (s *EncounterSegmenter) applyDisplayNames(v *EncounterView) {
+	if s.names == nil {
+		return
+	}
+	v.Target = s.DisplayName(v.Target)
+	v.Killer = s.DisplayName(v.Killer)
+	for i := range v.Actors {
+		v.Actors[i].Actor = s.DisplayName(v.Actors[i].Actor)
+	}
+	for i := range v.Healers {
+		v.Healers[i].Healer = s.DisplayName(v.Healers[i].Healer)
+	}
+	for i := range v.HealTargets {
+		v.HealTargets[i].Target = s.DisplayName(v.HealTargets[i].Target)
+	}
+	for i := range v.Defenders {
+		v.Defenders[i].Defender = s.DisplayName(v.Defenders[i].Defender)
+	}
+}
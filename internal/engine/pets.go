@@ -0,0 +1,114 @@
+package engine
+
+import "sort"
+
+func actorsForView(enc *Encounter, opts SnapshotOptions) []*EncounterActorStats {
+	if opts.RollupPets {
+		enc = rollupPets(enc)
+	}
+	return enc.ActorsSortedByTotal()
+}
+
+// rollupPets returns a copy of enc where every pet's damage is folded into its owner's
+// actor row. Owners that did no damage themselves get a row of their own.
+func rollupPets(enc *Encounter) *Encounter {
+	out := copyEncounter(enc)
+	for name, st := range enc.ByActor {
+		if st == nil || st.Owner == "" || st.Owner == name {
+			continue
+		}
+		owner := out.ByActor[st.Owner]
+		if owner == nil {
+			owner = &EncounterActorStats{Actor: st.Owner}
+			out.ByActor[st.Owner] = owner
+		}
+		mergeActorStats(owner, st)
+		owner.PetDamage += st.Total
+		delete(out.ByActor, name)
+	}
+	return out
+}
+
+func (e *Encounter) petsOf(owner string) []*EncounterActorStats {
+	var pets []*EncounterActorStats
+	for name, st := range e.ByActor {
+		if st != nil && st.Owner == owner && name != owner {
+			pets = append(pets, st)
+		}
+	}
+	sort.Slice(pets, func(i, j int) bool {
+		if pets[i].Total == pets[j].Total {
+			return pets[i].Actor < pets[j].Actor
+		}
+		return pets[i].Total > pets[j].Total
+	})
+	return pets
+}
+
+// buildPetBreakdownRows summarises each of owner's pets as a single breakdown row.
+// PctPlayer is relative to the owner's damage plus all of their pets.
+func (s *EncounterSegmenter) buildPetBreakdownRows(enc *Encounter, owner string) []DamageBreakdownRowView {
+	pets := enc.petsOf(owner)
+	if len(pets) == 0 {
+		return nil
+	}
+	combined := int64(0)
+	if st := enc.ByActor[owner]; st != nil {
+		combined = st.Total
+	}
+	for _, p := range pets {
+		combined += p.Total
+	}
+
+	encSec := durationSecondsInt(enc.Start, enc.End)
+	rows := make([]DamageBreakdownRowView, 0, len(pets))
+	for _, p := range pets {
+		activeSec := durationSecondsInt(p.FirstDamage, p.LastDamage)
+		pctPlayer := 0.0
+		if combined > 0 {
+			pctPlayer = (float64(p.Total) / float64(combined)) * 100
+		}
+		dps := 0.0
+		if encSec > 0 {
+			dps = float64(p.Total) / float64(encSec)
+		}
+		sdps := 0.0
+		if activeSec > 0 {
+			sdps = float64(p.Total) / float64(activeSec)
+		}
+		avgHit := 0.0
+		if p.Hits > 0 {
+			avgHit = float64(p.Total) / float64(p.Hits)
+		}
+		critPct := 0.0
+		if p.Hits > 0 {
+			critPct = (float64(p.CritHits) / float64(p.Hits)) * 100
+		}
+		avgCrit := 0.0
+		if p.CritHits > 0 {
+			avgCrit = float64(p.CritDmgSum) / float64(p.CritHits)
+		}
+		minHit := int64(0)
+		for _, agg := range p.Breakdown {
+			if agg != nil && agg.Hits > 0 && (minHit == 0 || agg.MinHit < minHit) {
+				minHit = agg.MinHit
+			}
+		}
+
+		rows = append(rows, DamageBreakdownRowView{
+			Name:      s.DisplayName(p.Actor),
+			PctPlayer: pctPlayer,
+			Damage:    p.Total,
+			DPS:       dps,
+			SDPS:      sdps,
+			Sec:       activeSec,
+			Hits:      p.Hits,
+			MaxHit:    p.MaxHit,
+			MinHit:    minHit,
+			AvgHit:    avgHit,
+			CritPct:   critPct,
+			AvgCrit:   avgCrit,
+		})
+	}
+	return rows
+}
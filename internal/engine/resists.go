@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+// SpellResistStats counts how often one spell was resisted versus how often it landed.
+// Landings are the lines that show the spell taking hold: "X is afflicted by S." for
+// outgoing spells and named spell damage ("... damage from X by S.") for incoming ones.
+type SpellResistStats struct {
+	Spell    string
+	Landed   int64
+	Resisted int64
+}
+
+func (s *SpellResistStats) ResistPct() float64 {
+	n := s.Landed + s.Resisted
+	if n == 0 {
+		return 0
+	}
+	return (float64(s.Resisted) / float64(n)) * 100
+}
+
+type SpellResistView struct {
+	Spell     string  `json:"spell"`
+	Landed    int64   `json:"landed"`
+	Resisted  int64   `json:"resisted"`
+	ResistPct float64 `json:"resistPct"`
+}
+
+func spellResist(m *map[string]*SpellResistStats, spell string) *SpellResistStats {
+	if *m == nil {
+		*m = make(map[string]*SpellResistStats)
+	}
+	st := (*m)[spell]
+	if st == nil {
+		st = &SpellResistStats{Spell: spell}
+		(*m)[spell] = st
+	}
+	return st
+}
+
+func (s *EncounterSegmenter) isLocalActor(name string) bool {
+	return name == "YOU" || (s.PlayerName != "" && name == s.PlayerName)
+}
+
+// addResistToActive credits a resisted spell. "Your target resisted" names the target only
+// through the parser's last "Targeted (...)" line; without a live encounter for it, the
+// resist goes to the encounter the local player last targeted or damaged. "You resist" does
+// not name the caster, so it goes to one encounter, picked like a heal's.
+func (s *EncounterSegmenter) addResistToActive(ev model.Event) {
+	if ev.SpellOrSkill == "" {
+		return
+	}
+	if s.isLocalActor(ev.Actor) {
+		ae := s.active[ev.Target]
+		if !s.isLive(ae, ev) {
+			ae = s.active[s.lastLocalTarget]
+		}
+		if s.isLive(ae, ev) {
+			spellResist(&ae.enc.SpellResists, ev.SpellOrSkill).Resisted++
+			ae.enc.spellCast(ev.Actor, ev.SpellOrSkill).Resisted++
+		}
+		return
+	}
+	if ae := s.focusEncounter(ev); ae != nil {
+		spellResist(&ae.enc.IncomingResists, ev.SpellOrSkill).Resisted++
+	}
+}
+
+// addSpellLandedToActive counts afflictions on an encounter's target and named spell
+// damage dealt by it to the local player. Only the local player's afflictions count toward
+// SpellResists, since only the local player's resists are ever seen.
+func (s *EncounterSegmenter) addSpellLandedToActive(ev model.Event) {
+	if ev.SpellOrSkill == "" {
+		return
+	}
+	switch ev.Kind {
+	case model.KindAffliction:
+		ae := s.active[ev.Target]
+		if !s.isLive(ae, ev) {
+			return
+		}
+		if s.isLocalActor(ev.Actor) {
+			spellResist(&ae.enc.SpellResists, ev.SpellOrSkill).Landed++
+		}
+		if ev.Actor != "" {
+			ae.enc.spellCast(ev.Actor, ev.SpellOrSkill).Landed++
+		}
+	case model.KindIncomingDamage:
+		if !s.isLocalActor(ev.Target) {
+			return
+		}
+		ae := s.active[ev.Actor]
+		if s.isLive(ae, ev) {
+			spellResist(&ae.enc.IncomingResists, ev.SpellOrSkill).Landed++
+		}
+	}
+}
+
+// SpellResistsSorted orders per-spell stats by resists, most resisted first.
+func SpellResistsSorted(m map[string]*SpellResistStats) []*SpellResistStats {
+	out := make([]*SpellResistStats, 0, len(m))
+	for _, st := range m {
+		if st != nil {
+			out = append(out, st)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Resisted == out[j].Resisted {
+			return out[i].Spell < out[j].Spell
+		}
+		return out[i].Resisted > out[j].Resisted
+	})
+	return out
+}
+
+func buildSpellResistViews(m map[string]*SpellResistStats) []SpellResistView {
+	out := make([]SpellResistView, 0, len(m))
+	for _, st := range SpellResistsSorted(m) {
+		out = append(out, SpellResistView{
+			Spell:     st.Spell,
+			Landed:    st.Landed,
+			Resisted:  st.Resisted,
+			ResistPct: st.ResistPct(),
+		})
+	}
+	return out
+}
+
+func copySpellResists(m map[string]*SpellResistStats) map[string]*SpellResistStats {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]*SpellResistStats, len(m))
+	for k, v := range m {
+		if v == nil {
+			continue
+		}
+		cp := *v
+		out[k] = &cp
+	}
+	return out
+}
+
+func mergeSpellResists(dst *map[string]*SpellResistStats, src map[string]*SpellResistStats) {
+	for k, v := range src {
+		if v == nil {
+			continue
+		}
+		st := spellResist(dst, k)
+		st.Landed += v.Landed
+		st.Resisted += v.Resisted
+	}
+}
+
+func copyResists(dst, src *Encounter) {
+	dst.SpellResists = copySpellResists(src.SpellResists)
+	dst.IncomingResists = copySpellResists(src.IncomingResists)
+}
+
+func mergeResists(dst, src *Encounter) {
+	mergeSpellResists(&dst.SpellResists, src.SpellResists)
+	mergeSpellResists(&dst.IncomingResists, src.IncomingResists)
+}
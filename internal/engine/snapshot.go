@@ -25,18 +25,47 @@ type ActorStatsView struct {
 	CritPct   float64 `json:"critPct"`
 	AvgCrit   float64 `json:"avgCrit"`
 	Crits     int64   `json:"crits"`
+
+	// PetDamage is the part of Total dealt by the actor's pets when SnapshotOptions.RollupPets is set.
+	PetDamage int64 `json:"petDamage"`
 }
 
 type EncounterView struct {
 	EncounterKey string           `json:"encounterKey"`
 	EncounterID  string           `json:"encounterId"`
 	Target       string           `json:"target"`
+	Zone         string           `json:"zone"`
 	Start        time.Time        `json:"start"`
 	End          time.Time        `json:"end"`
 	EncounterSec int64            `json:"encounterSec"`
 	TotalDamage  int64            `json:"totalDamage"`
 	DPSEncounter float64          `json:"dpsEncounter"`
+	Killed       bool             `json:"killed"`
+	Killer       string           `json:"killer"`
 	Actors       []ActorStatsView `json:"actors"`
+
+	TotalHealing int64             `json:"totalHealing"`
+	HPSEncounter float64           `json:"hpsEncounter"`
+	Healers      []HealerStatsView `json:"healers"`
+	HealTargets  []HealTargetView  `json:"healTargets"`
+
+	TotalDamageTaken int64               `json:"totalDamageTaken"`
+	Defenders        []DefenderStatsView `json:"defenders"`
+
+	Rampages     int64 `json:"rampages"`
+	WildRampages int64 `json:"wildRampages"`
+	Flurries     int64 `json:"flurries"`
+
+	SpellResists    []SpellResistView `json:"spellResists"`
+	IncomingResists []SpellResistView `json:"incomingResists"`
+
+	Status []StatusStatsView `json:"status"`
+
+	SpellCasts []SpellCastView `json:"spellCasts"`
+
+	// Chat and StatusTimeline are only filled by single-encounter views.
+	Chat           []ChatLineView    `json:"chat"`
+	StatusTimeline []StatusEventView `json:"statusTimeline"`
 }
 
 func encounterKey(target string, start time.Time) string {
@@ -56,6 +85,12 @@ type SnapshotOptions struct {
 	LimitEncounters  int
 	CoalesceTargets  bool
 	CoalesceMergeGap time.Duration
+	// RollupPets folds each pet's damage into its owner's actor row.
+	RollupPets bool
+	// Zone keeps only encounters fought in the named zone (case-insensitive).
+	Zone string
+	// GroupByZone lists encounters zone by zone, most recently visited zone first.
+	GroupByZone bool
 }
 
 func (s *EncounterSegmenter) coalesceEncounters(encs []*Encounter, mergeGap time.Duration) []*Encounter {
@@ -94,7 +129,7 @@ func (s *EncounterSegmenter) coalesceEncounters(encs []*Encounter, mergeGap time
 			}
 
 			gap := e.Start.Sub(cur.End)
-			if gap > 0 && gap <= mergeGap && s.hasCombatBetween(cur.End, e.Start) {
+			if !cur.Killed && !cur.Retargeted && !cur.ClockJumped && cur.Zone == e.Zone && gap > 0 && gap <= mergeGap && s.hasCombatBetween(cur.End, e.Start) {
 				cur = mergeEncounters(cur, e)
 				continue
 			}
@@ -116,14 +151,24 @@ func copyEncounter(e *Encounter) *Encounter {
 	}
 	out := &Encounter{
 		Target:  e.Target,
+		Zone:    e.Zone,
 		Start:   e.Start,
 		End:     e.End,
 		Total:   e.Total,
 		ByActor: make(map[string]*EncounterActorStats, len(e.ByActor)),
+		Killed:  e.Killed,
+		Killer:  e.Killer,
 	}
+	out.Retargeted = e.Retargeted
+	out.ClockJumped = e.ClockJumped
 	for k, v := range e.ByActor {
 		out.ByActor[k] = copyActorStats(v)
 	}
+	copyHealing(out, e)
+	copyDamageTaken(out, e)
+	copyResists(out, e)
+	copyStatus(out, e)
+	copyCasts(out, e)
 	return out
 }
 
@@ -142,6 +187,8 @@ func copyActorStats(s *EncounterActorStats) *EncounterActorStats {
 		CritDmgSum:  s.CritDmgSum,
 		FirstDamage: s.FirstDamage,
 		LastDamage:  s.LastDamage,
+		Owner:       s.Owner,
+		PetDamage:   s.PetDamage,
 	}
 	if s.Breakdown != nil {
 		out.Breakdown = make(map[model.DamageClass]*DamageBreakdownStats, len(s.Breakdown))
@@ -167,6 +214,15 @@ func mergeEncounters(a *Encounter, b *Encounter) *Encounter {
 	out := copyEncounter(a)
 	out.End = b.End
 	out.Total += b.Total
+	out.Killed = b.Killed
+	out.Killer = b.Killer
+	out.Retargeted = b.Retargeted
+	out.ClockJumped = b.ClockJumped
+	mergeHealing(out, b)
+	mergeDamageTaken(out, b)
+	mergeResists(out, b)
+	mergeStatus(out, b)
+	mergeCasts(out, b)
 
 	if out.ByActor == nil {
 		out.ByActor = make(map[string]*EncounterActorStats)
@@ -180,52 +236,58 @@ func mergeEncounters(a *Encounter, b *Encounter) *Encounter {
 			out.ByActor[actor] = copyActorStats(st)
 			continue
 		}
+		mergeActorStats(existing, st)
+	}
 
-		existing.Melee += st.Melee
-		existing.NonMelee += st.NonMelee
-		existing.Total += st.Total
-		existing.Hits += st.Hits
-		existing.CritHits += st.CritHits
-		existing.CritDmgSum += st.CritDmgSum
-		if st.MaxHit > existing.MaxHit {
-			existing.MaxHit = st.MaxHit
-		}
-		if existing.FirstDamage.IsZero() || (!st.FirstDamage.IsZero() && st.FirstDamage.Before(existing.FirstDamage)) {
-			existing.FirstDamage = st.FirstDamage
-		}
-		if existing.LastDamage.IsZero() || (!st.LastDamage.IsZero() && st.LastDamage.After(existing.LastDamage)) {
-			existing.LastDamage = st.LastDamage
-		}
+	return out
+}
+
+func mergeActorStats(existing, st *EncounterActorStats) {
+	existing.Melee += st.Melee
+	existing.NonMelee += st.NonMelee
+	existing.Total += st.Total
+	existing.Hits += st.Hits
+	existing.CritHits += st.CritHits
+	existing.CritDmgSum += st.CritDmgSum
+	existing.PetDamage += st.PetDamage
+	if st.MaxHit > existing.MaxHit {
+		existing.MaxHit = st.MaxHit
+	}
+	if existing.FirstDamage.IsZero() || (!st.FirstDamage.IsZero() && st.FirstDamage.Before(existing.FirstDamage)) {
+		existing.FirstDamage = st.FirstDamage
+	}
+	if existing.LastDamage.IsZero() || (!st.LastDamage.IsZero() && st.LastDamage.After(existing.LastDamage)) {
+		existing.LastDamage = st.LastDamage
+	}
 
-		if st.Breakdown != nil {
-			if existing.Breakdown == nil {
-				existing.Breakdown = make(map[model.DamageClass]*DamageBreakdownStats)
+	if st.Breakdown != nil {
+		if existing.Breakdown == nil {
+			existing.Breakdown = make(map[model.DamageClass]*DamageBreakdownStats)
+		}
+		for c, agg := range st.Breakdown {
+			if agg == nil {
+				continue
 			}
-			for c, agg := range st.Breakdown {
-				if agg == nil {
-					continue
-				}
-				ex := existing.Breakdown[c]
-				if ex == nil {
-					copyAgg := *agg
-					existing.Breakdown[c] = &copyAgg
-					continue
-				}
-				ex.Hits += agg.Hits
-				ex.CritHits += agg.CritHits
-				ex.TotalDamage += agg.TotalDamage
-				ex.CritDamage += agg.CritDamage
-				if ex.MinHit == 0 || (agg.MinHit > 0 && agg.MinHit < ex.MinHit) {
-					ex.MinHit = agg.MinHit
-				}
-				if agg.MaxHit > ex.MaxHit {
-					ex.MaxHit = agg.MaxHit
-				}
+			ex := existing.Breakdown[c]
+			if ex == nil {
+				copyAgg := *agg
+				existing.Breakdown[c] = &copyAgg
+				continue
+			}
+			ex.Hits += agg.Hits
+			ex.CritHits += agg.CritHits
+			ex.TotalDamage += agg.TotalDamage
+			ex.CritDamage += agg.CritDamage
+			ex.CripplingHits += agg.CripplingHits
+			ex.CripplingDamage += agg.CripplingDamage
+			if ex.MinHit == 0 || (agg.MinHit > 0 && agg.MinHit < ex.MinHit) {
+				ex.MinHit = agg.MinHit
+			}
+			if agg.MaxHit > ex.MaxHit {
+				ex.MaxHit = agg.MaxHit
 			}
 		}
 	}
-
-	return out
 }
 
 func sortEncountersMostRecentFirst(encs []*Encounter) {
@@ -278,6 +340,7 @@ func (s *EncounterSegmenter) BuildSnapshot(now time.Time, filePath string, taili
 
 	sortEncountersMostRecentFirst(encs)
 	filtered := filterEncountersForSnapshot(encs, opts.IncludePCTargets, s.localTouchedTargets)
+	filtered = FilterEncountersByZone(filtered, opts.Zone)
 	if opts.CoalesceTargets {
 		filtered = s.coalesceEncounters(filtered, opts.CoalesceMergeGap)
 		sortEncountersMostRecentFirst(filtered)
@@ -286,6 +349,9 @@ func (s *EncounterSegmenter) BuildSnapshot(now time.Time, filePath string, taili
 	if opts.LimitEncounters > 0 && len(filtered) > opts.LimitEncounters {
 		filtered = filtered[:opts.LimitEncounters]
 	}
+	if opts.GroupByZone {
+		filtered = GroupEncountersByZone(filtered)
+	}
 
 	out := Snapshot{
 		Now:            now,
@@ -301,19 +367,39 @@ func (s *EncounterSegmenter) BuildSnapshot(now time.Time, filePath string, taili
 		if encSec > 0 {
 			dpsEnc = float64(enc.Total) / float64(encSec)
 		}
+		hpsEnc := 0.0
+		if encSec > 0 {
+			hpsEnc = float64(enc.TotalHealing) / float64(encSec)
+		}
 		view := EncounterView{
-			EncounterKey: encounterKey(enc.Target, enc.Start),
-			EncounterID:  encounterID(enc.Target, enc.Start, enc.End),
-			Target:       enc.Target,
-			Start:        enc.Start,
-			End:          enc.End,
-			EncounterSec: encSec,
-			TotalDamage:  enc.Total,
-			DPSEncounter: dpsEnc,
-			Actors:       make([]ActorStatsView, 0, len(enc.ByActor)),
-		}
-
-		actors := enc.ActorsSortedByTotal()
+			EncounterKey:     encounterKey(enc.Target, enc.Start),
+			EncounterID:      encounterID(enc.Target, enc.Start, enc.End),
+			Target:           enc.Target,
+			Zone:             enc.Zone,
+			Start:            enc.Start,
+			End:              enc.End,
+			EncounterSec:     encSec,
+			TotalDamage:      enc.Total,
+			DPSEncounter:     dpsEnc,
+			Killed:           enc.Killed,
+			Killer:           enc.Killer,
+			Actors:           make([]ActorStatsView, 0, len(enc.ByActor)),
+			TotalHealing:     enc.TotalHealing,
+			HPSEncounter:     hpsEnc,
+			Healers:          buildHealerViews(enc, encSec),
+			HealTargets:      buildHealTargetViews(enc),
+			TotalDamageTaken: enc.TotalTaken,
+			Rampages:         enc.Rampages,
+			WildRampages:     enc.WildRampages,
+			Flurries:         enc.Flurries,
+			Defenders:        buildDefenderViews(enc, encSec),
+			SpellResists:     buildSpellResistViews(enc.SpellResists),
+			IncomingResists:  buildSpellResistViews(enc.IncomingResists),
+			Status:           s.buildStatusViews(enc),
+			SpellCasts:       s.buildSpellCastViews(enc),
+		}
+
+		actors := actorsForView(enc, opts)
 		for _, st := range actors {
 			activeSec := durationSecondsInt(st.FirstDamage, st.LastDamage)
 			dps := 0.0
@@ -357,9 +443,12 @@ func (s *EncounterSegmenter) BuildSnapshot(now time.Time, filePath string, taili
 				CritPct:   critPct,
 				AvgCrit:   avgCrit,
 				Crits:     st.CritHits,
+
+				PetDamage: st.PetDamage,
 			})
 		}
 
+		s.applyDisplayNames(&view)
 		out.Encounters = append(out.Encounters, view)
 	}
 
@@ -374,6 +463,7 @@ func (s *EncounterSegmenter) BuildSnapshotSummary(now time.Time, filePath string
 
 	sortEncountersMostRecentFirst(encs)
 	filtered := filterEncountersForSnapshot(encs, opts.IncludePCTargets, s.localTouchedTargets)
+	filtered = FilterEncountersByZone(filtered, opts.Zone)
 	if opts.CoalesceTargets {
 		filtered = s.coalesceEncounters(filtered, opts.CoalesceMergeGap)
 		sortEncountersMostRecentFirst(filtered)
@@ -382,6 +472,9 @@ func (s *EncounterSegmenter) BuildSnapshotSummary(now time.Time, filePath string
 	if opts.LimitEncounters > 0 && len(filtered) > opts.LimitEncounters {
 		filtered = filtered[:opts.LimitEncounters]
 	}
+	if opts.GroupByZone {
+		filtered = GroupEncountersByZone(filtered)
+	}
 
 	out := Snapshot{
 		Now:            now,
@@ -397,16 +490,29 @@ func (s *EncounterSegmenter) BuildSnapshotSummary(now time.Time, filePath string
 		if encSec > 0 {
 			dpsEnc = float64(enc.Total) / float64(encSec)
 		}
+		hpsEnc := 0.0
+		if encSec > 0 {
+			hpsEnc = float64(enc.TotalHealing) / float64(encSec)
+		}
 		out.Encounters = append(out.Encounters, EncounterView{
-			EncounterKey: encounterKey(enc.Target, enc.Start),
-			EncounterID:  encounterID(enc.Target, enc.Start, enc.End),
-			Target:       enc.Target,
-			Start:        enc.Start,
-			End:          enc.End,
-			EncounterSec: encSec,
-			TotalDamage:  enc.Total,
-			DPSEncounter: dpsEnc,
-			Actors:       nil,
+			EncounterKey:     encounterKey(enc.Target, enc.Start),
+			EncounterID:      encounterID(enc.Target, enc.Start, enc.End),
+			Target:           s.DisplayName(enc.Target),
+			Zone:             enc.Zone,
+			Start:            enc.Start,
+			End:              enc.End,
+			EncounterSec:     encSec,
+			TotalDamage:      enc.Total,
+			DPSEncounter:     dpsEnc,
+			Killed:           enc.Killed,
+			Killer:           s.DisplayName(enc.Killer),
+			Actors:           nil,
+			TotalHealing:     enc.TotalHealing,
+			HPSEncounter:     hpsEnc,
+			TotalDamageTaken: enc.TotalTaken,
+			Rampages:         enc.Rampages,
+			WildRampages:     enc.WildRampages,
+			Flurries:         enc.Flurries,
 		})
 	}
 
@@ -414,6 +520,7 @@ func (s *EncounterSegmenter) BuildSnapshotSummary(now time.Time, filePath string
 }
 
 func (s *EncounterSegmenter) BuildEncounterView(now time.Time, filePath string, tailing bool, opts SnapshotOptions, target string) (EncounterView, bool) {
+	target = s.canonicalName(target)
 	encs := s.Snapshot()
 	if len(encs) == 0 {
 		return EncounterView{}, false
@@ -421,6 +528,7 @@ func (s *EncounterSegmenter) BuildEncounterView(now time.Time, filePath string,
 
 	sortEncountersMostRecentFirst(encs)
 	filtered := filterEncountersForSnapshot(encs, opts.IncludePCTargets, s.localTouchedTargets)
+	filtered = FilterEncountersByZone(filtered, opts.Zone)
 	if opts.CoalesceTargets {
 		filtered = s.coalesceEncounters(filtered, opts.CoalesceMergeGap)
 		sortEncountersMostRecentFirst(filtered)
@@ -440,19 +548,39 @@ func (s *EncounterSegmenter) BuildEncounterView(now time.Time, filePath string,
 		if encSec > 0 {
 			dpsEnc = float64(enc.Total) / float64(encSec)
 		}
+		hpsEnc := 0.0
+		if encSec > 0 {
+			hpsEnc = float64(enc.TotalHealing) / float64(encSec)
+		}
 		view := EncounterView{
-			EncounterKey: encounterKey(enc.Target, enc.Start),
-			EncounterID:  encounterID(enc.Target, enc.Start, enc.End),
-			Target:       enc.Target,
-			Start:        enc.Start,
-			End:          enc.End,
-			EncounterSec: encSec,
-			TotalDamage:  enc.Total,
-			DPSEncounter: dpsEnc,
-			Actors:       make([]ActorStatsView, 0, len(enc.ByActor)),
-		}
-
-		actors := enc.ActorsSortedByTotal()
+			EncounterKey:     encounterKey(enc.Target, enc.Start),
+			EncounterID:      encounterID(enc.Target, enc.Start, enc.End),
+			Target:           enc.Target,
+			Zone:             enc.Zone,
+			Start:            enc.Start,
+			End:              enc.End,
+			EncounterSec:     encSec,
+			TotalDamage:      enc.Total,
+			DPSEncounter:     dpsEnc,
+			Killed:           enc.Killed,
+			Killer:           enc.Killer,
+			Actors:           make([]ActorStatsView, 0, len(enc.ByActor)),
+			TotalHealing:     enc.TotalHealing,
+			HPSEncounter:     hpsEnc,
+			Healers:          buildHealerViews(enc, encSec),
+			HealTargets:      buildHealTargetViews(enc),
+			TotalDamageTaken: enc.TotalTaken,
+			Rampages:         enc.Rampages,
+			WildRampages:     enc.WildRampages,
+			Flurries:         enc.Flurries,
+			Defenders:        buildDefenderViews(enc, encSec),
+			SpellResists:     buildSpellResistViews(enc.SpellResists),
+			IncomingResists:  buildSpellResistViews(enc.IncomingResists),
+			Status:           s.buildStatusViews(enc),
+			SpellCasts:       s.buildSpellCastViews(enc),
+		}
+
+		actors := actorsForView(enc, opts)
 		for _, st := range actors {
 			activeSec := durationSecondsInt(st.FirstDamage, st.LastDamage)
 			dps := 0.0
@@ -496,9 +624,14 @@ func (s *EncounterSegmenter) BuildEncounterView(now time.Time, filePath string,
 				CritPct:   critPct,
 				AvgCrit:   avgCrit,
 				Crits:     st.CritHits,
+
+				PetDamage: st.PetDamage,
 			})
 		}
 
+		view.Chat = s.chatTimeline(view.Start, view.End)
+		view.StatusTimeline = s.buildStatusTimeline(enc)
+		s.applyDisplayNames(&view)
 		return view, true
 	}
 
@@ -506,6 +639,7 @@ func (s *EncounterSegmenter) BuildEncounterView(now time.Time, filePath string,
 }
 
 func (s *EncounterSegmenter) BuildEncounterViewByKey(now time.Time, filePath string, tailing bool, opts SnapshotOptions, target string, start time.Time) (EncounterView, bool) {
+	target = s.canonicalName(target)
 	encs := s.Snapshot()
 	if len(encs) == 0 {
 		return EncounterView{}, false
@@ -513,6 +647,7 @@ func (s *EncounterSegmenter) BuildEncounterViewByKey(now time.Time, filePath str
 
 	sortEncountersMostRecentFirst(encs)
 	filtered := filterEncountersForSnapshot(encs, opts.IncludePCTargets, s.localTouchedTargets)
+	filtered = FilterEncountersByZone(filtered, opts.Zone)
 	if opts.CoalesceTargets {
 		filtered = s.coalesceEncounters(filtered, opts.CoalesceMergeGap)
 		sortEncountersMostRecentFirst(filtered)
@@ -546,19 +681,39 @@ func (s *EncounterSegmenter) BuildEncounterViewByKey(now time.Time, filePath str
 	if encSec > 0 {
 		dpsEnc = float64(best.Total) / float64(encSec)
 	}
+	hpsEnc := 0.0
+	if encSec > 0 {
+		hpsEnc = float64(best.TotalHealing) / float64(encSec)
+	}
 	view := EncounterView{
-		EncounterKey: encounterKey(best.Target, best.Start),
-		EncounterID:  encounterID(best.Target, best.Start, best.End),
-		Target:       best.Target,
-		Start:        best.Start,
-		End:          best.End,
-		EncounterSec: encSec,
-		TotalDamage:  best.Total,
-		DPSEncounter: dpsEnc,
-		Actors:       make([]ActorStatsView, 0, len(best.ByActor)),
-	}
-
-	actors := best.ActorsSortedByTotal()
+		EncounterKey:     encounterKey(best.Target, best.Start),
+		EncounterID:      encounterID(best.Target, best.Start, best.End),
+		Target:           best.Target,
+		Zone:             best.Zone,
+		Start:            best.Start,
+		End:              best.End,
+		EncounterSec:     encSec,
+		TotalDamage:      best.Total,
+		DPSEncounter:     dpsEnc,
+		Killed:           best.Killed,
+		Killer:           best.Killer,
+		Actors:           make([]ActorStatsView, 0, len(best.ByActor)),
+		TotalHealing:     best.TotalHealing,
+		HPSEncounter:     hpsEnc,
+		Healers:          buildHealerViews(best, encSec),
+		HealTargets:      buildHealTargetViews(best),
+		TotalDamageTaken: best.TotalTaken,
+		Rampages:         best.Rampages,
+		WildRampages:     best.WildRampages,
+		Flurries:         best.Flurries,
+		Defenders:        buildDefenderViews(best, encSec),
+		SpellResists:     buildSpellResistViews(best.SpellResists),
+		IncomingResists:  buildSpellResistViews(best.IncomingResists),
+		Status:           s.buildStatusViews(best),
+		SpellCasts:       s.buildSpellCastViews(best),
+	}
+
+	actors := actorsForView(best, opts)
 	for _, st := range actors {
 		activeSec := durationSecondsInt(st.FirstDamage, st.LastDamage)
 		dps := 0.0
@@ -602,13 +757,19 @@ func (s *EncounterSegmenter) BuildEncounterViewByKey(now time.Time, filePath str
 			CritPct:   critPct,
 			AvgCrit:   avgCrit,
 			Crits:     st.CritHits,
+
+			PetDamage: st.PetDamage,
 		})
 	}
 
+	view.Chat = s.chatTimeline(view.Start, view.End)
+	view.StatusTimeline = s.buildStatusTimeline(best)
+	s.applyDisplayNames(&view)
 	return view, true
 }
 
 func (s *EncounterSegmenter) BuildEncounterViewExact(now time.Time, filePath string, tailing bool, opts SnapshotOptions, target string, start, end time.Time) (EncounterView, bool) {
+	target = s.canonicalName(target)
 	encs := s.Snapshot()
 	if len(encs) == 0 {
 		return EncounterView{}, false
@@ -616,6 +777,7 @@ func (s *EncounterSegmenter) BuildEncounterViewExact(now time.Time, filePath str
 
 	sortEncountersMostRecentFirst(encs)
 	filtered := filterEncountersForSnapshot(encs, opts.IncludePCTargets, s.localTouchedTargets)
+	filtered = FilterEncountersByZone(filtered, opts.Zone)
 	if opts.CoalesceTargets {
 		filtered = s.coalesceEncounters(filtered, opts.CoalesceMergeGap)
 		sortEncountersMostRecentFirst(filtered)
@@ -641,19 +803,39 @@ func (s *EncounterSegmenter) BuildEncounterViewExact(now time.Time, filePath str
 		if encSec > 0 {
 			dpsEnc = float64(enc.Total) / float64(encSec)
 		}
+		hpsEnc := 0.0
+		if encSec > 0 {
+			hpsEnc = float64(enc.TotalHealing) / float64(encSec)
+		}
 		view := EncounterView{
-			EncounterKey: encounterKey(enc.Target, enc.Start),
-			EncounterID:  encounterID(enc.Target, enc.Start, enc.End),
-			Target:       enc.Target,
-			Start:        enc.Start,
-			End:          enc.End,
-			EncounterSec: encSec,
-			TotalDamage:  enc.Total,
-			DPSEncounter: dpsEnc,
-			Actors:       make([]ActorStatsView, 0, len(enc.ByActor)),
-		}
-
-		actors := enc.ActorsSortedByTotal()
+			EncounterKey:     encounterKey(enc.Target, enc.Start),
+			EncounterID:      encounterID(enc.Target, enc.Start, enc.End),
+			Target:           enc.Target,
+			Zone:             enc.Zone,
+			Start:            enc.Start,
+			End:              enc.End,
+			EncounterSec:     encSec,
+			TotalDamage:      enc.Total,
+			DPSEncounter:     dpsEnc,
+			Killed:           enc.Killed,
+			Killer:           enc.Killer,
+			Actors:           make([]ActorStatsView, 0, len(enc.ByActor)),
+			TotalHealing:     enc.TotalHealing,
+			HPSEncounter:     hpsEnc,
+			Healers:          buildHealerViews(enc, encSec),
+			HealTargets:      buildHealTargetViews(enc),
+			TotalDamageTaken: enc.TotalTaken,
+			Rampages:         enc.Rampages,
+			WildRampages:     enc.WildRampages,
+			Flurries:         enc.Flurries,
+			Defenders:        buildDefenderViews(enc, encSec),
+			SpellResists:     buildSpellResistViews(enc.SpellResists),
+			IncomingResists:  buildSpellResistViews(enc.IncomingResists),
+			Status:           s.buildStatusViews(enc),
+			SpellCasts:       s.buildSpellCastViews(enc),
+		}
+
+		actors := actorsForView(enc, opts)
 		for _, st := range actors {
 			activeSec := durationSecondsInt(st.FirstDamage, st.LastDamage)
 			dps := 0.0
@@ -697,9 +879,14 @@ func (s *EncounterSegmenter) BuildEncounterViewExact(now time.Time, filePath str
 				CritPct:   critPct,
 				AvgCrit:   avgCrit,
 				Crits:     st.CritHits,
+
+				PetDamage: st.PetDamage,
 			})
 		}
 
+		view.Chat = s.chatTimeline(view.Start, view.End)
+		view.StatusTimeline = s.buildStatusTimeline(enc)
+		s.applyDisplayNames(&view)
 		return view, true
 	}
 
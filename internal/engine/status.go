@@ -0,0 +1,215 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+const maxStatusTimeline = 2000
+
+// EncounterStatusStats counts the status lines about one subject during an encounter.
+type EncounterStatusStats struct {
+	Subject      string
+	Stuns        int64
+	StunResists  int64
+	StunAvoids   int64
+	FailedTaunts int64
+	Staggers     int64
+	Delirium     int64
+	StunnedSec   int64
+}
+
+type StatusEntry struct {
+	Timestamp time.Time
+	Subject   string
+	Effect    model.StatusEffect
+}
+
+type StatusStatsView struct {
+	Subject      string `json:"subject"`
+	Stuns        int64  `json:"stuns"`
+	StunResists  int64  `json:"stunResists"`
+	StunAvoids   int64  `json:"stunAvoids"`
+	FailedTaunts int64  `json:"failedTaunts"`
+	Staggers     int64  `json:"staggers"`
+	Delirium     int64  `json:"delirium"`
+	StunnedSec   int64  `json:"stunnedSec"`
+}
+
+type StatusEventView struct {
+	Timestamp time.Time `json:"ts"`
+	Subject   string    `json:"subject"`
+	Effect    string    `json:"effect"`
+}
+
+func statusEffectName(e model.StatusEffect) string {
+	switch e {
+	case model.StatusStunned:
+		return "stunned"
+	case model.StatusStunEnded:
+		return "stun ended"
+	case model.StatusStunResisted:
+		return "stun resisted"
+	case model.StatusStunAvoided:
+		return "stun avoided"
+	case model.StatusTauntFailed:
+		return "taunt failed"
+	case model.StatusStagger:
+		return "stagger"
+	case model.StatusDelirium:
+		return "delirium"
+	default:
+		return "unknown"
+	}
+}
+
+// addStatusToActive records a status line. Lines about an encounter's target go to that
+// encounter; lines about the local player go to one encounter, picked like a heal's.
+// Time spent stunned is credited when the stun ends.
+func (s *EncounterSegmenter) addStatusToActive(ev model.Event) {
+	if ev.Target == "" {
+		return
+	}
+	stunnedSec := int64(0)
+	switch ev.Status {
+	case model.StatusStunned:
+		if s.stunnedSince == nil {
+			s.stunnedSince = make(map[string]time.Time)
+		}
+		s.stunnedSince[ev.Target] = ev.Timestamp
+	case model.StatusStunEnded:
+		if since, ok := s.stunnedSince[ev.Target]; ok {
+			delete(s.stunnedSince, ev.Target)
+			if ev.Timestamp.After(since) {
+				stunnedSec = int64(ev.Timestamp.Sub(since).Seconds())
+			}
+		}
+	}
+
+	if ae := s.active[ev.Target]; ae != nil {
+		if s.isLive(ae, ev) {
+			ae.enc.addStatus(ev, stunnedSec)
+		}
+		return
+	}
+	if !s.isLocalActor(ev.Target) {
+		return
+	}
+	if ae := s.focusEncounter(ev); ae != nil {
+		ae.enc.addStatus(ev, stunnedSec)
+	}
+}
+
+func (e *Encounter) addStatus(ev model.Event, stunnedSec int64) {
+	if e.ByStatusSubject == nil {
+		e.ByStatusSubject = make(map[string]*EncounterStatusStats)
+	}
+	st := e.ByStatusSubject[ev.Target]
+	if st == nil {
+		st = &EncounterStatusStats{Subject: ev.Target}
+		e.ByStatusSubject[ev.Target] = st
+	}
+	switch ev.Status {
+	case model.StatusStunned:
+		st.Stuns++
+	case model.StatusStunEnded:
+		st.StunnedSec += stunnedSec
+	case model.StatusStunResisted:
+		st.StunResists++
+	case model.StatusStunAvoided:
+		st.StunAvoids++
+	case model.StatusTauntFailed:
+		st.FailedTaunts++
+	case model.StatusStagger:
+		st.Staggers++
+	case model.StatusDelirium:
+		st.Delirium++
+	}
+	if len(e.StatusTimeline) < maxStatusTimeline {
+		e.StatusTimeline = append(e.StatusTimeline, StatusEntry{Timestamp: ev.Timestamp, Subject: ev.Target, Effect: ev.Status})
+	}
+}
+
+func (e *Encounter) StatusSubjectsSorted() []*EncounterStatusStats {
+	out := make([]*EncounterStatusStats, 0, len(e.ByStatusSubject))
+	for _, st := range e.ByStatusSubject {
+		if st != nil {
+			out = append(out, st)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Subject < out[j].Subject })
+	return out
+}
+
+func (s *EncounterSegmenter) buildStatusViews(enc *Encounter) []StatusStatsView {
+	out := make([]StatusStatsView, 0, len(enc.ByStatusSubject))
+	for _, st := range enc.StatusSubjectsSorted() {
+		out = append(out, StatusStatsView{
+			Subject:      s.DisplayName(st.Subject),
+			Stuns:        st.Stuns,
+			StunResists:  st.StunResists,
+			StunAvoids:   st.StunAvoids,
+			FailedTaunts: st.FailedTaunts,
+			Staggers:     st.Staggers,
+			Delirium:     st.Delirium,
+			StunnedSec:   st.StunnedSec,
+		})
+	}
+	return out
+}
+
+func (s *EncounterSegmenter) buildStatusTimeline(enc *Encounter) []StatusEventView {
+	out := make([]StatusEventView, 0, len(enc.StatusTimeline))
+	for _, e := range enc.StatusTimeline {
+		out = append(out, StatusEventView{
+			Timestamp: e.Timestamp,
+			Subject:   s.DisplayName(e.Subject),
+			Effect:    statusEffectName(e.Effect),
+		})
+	}
+	return out
+}
+
+func copyStatus(dst, src *Encounter) {
+	if src.ByStatusSubject != nil {
+		dst.ByStatusSubject = make(map[string]*EncounterStatusStats, len(src.ByStatusSubject))
+		for k, v := range src.ByStatusSubject {
+			if v == nil {
+				continue
+			}
+			cp := *v
+			dst.ByStatusSubject[k] = &cp
+		}
+	}
+	dst.StatusTimeline = append([]StatusEntry(nil), src.StatusTimeline...)
+}
+
+func mergeStatus(dst, src *Encounter) {
+	if len(src.ByStatusSubject) > 0 && dst.ByStatusSubject == nil {
+		dst.ByStatusSubject = make(map[string]*EncounterStatusStats)
+	}
+	for k, v := range src.ByStatusSubject {
+		if v == nil {
+			continue
+		}
+		ex := dst.ByStatusSubject[k]
+		if ex == nil {
+			cp := *v
+			dst.ByStatusSubject[k] = &cp
+			continue
+		}
+		ex.Stuns += v.Stuns
+		ex.StunResists += v.StunResists
+		ex.StunAvoids += v.StunAvoids
+		ex.FailedTaunts += v.FailedTaunts
+		ex.Staggers += v.Staggers
+		ex.Delirium += v.Delirium
+		ex.StunnedSec += v.StunnedSec
+	}
+	dst.StatusTimeline = append(dst.StatusTimeline, src.StatusTimeline...)
+	if len(dst.StatusTimeline) > maxStatusTimeline {
+		dst.StatusTimeline = dst.StatusTimeline[:maxStatusTimeline]
+	}
+}
@@ -0,0 +1,32 @@
+package engine
+
+import "github.com/ZehenForever/eqemu-log-parser/internal/model"
+
+// noteTargetSwitch handles "Targeted (NPC): X". The NPC becomes the local player's last
+// target. When TargetSwitchGap is positive and the player switches to X from a different NPC,
+// an encounter already open on X that has gone quiet for longer than the gap is closed so
+// the next damage starts a fresh pull instead of waiting for the idle timeout. Targeting
+// the same NPC again never splits a fight.
+func (s *EncounterSegmenter) noteTargetSwitch(ev model.Event) {
+	if ev.Verb != "npc" || !isValidEncounterTarget(ev.Target) {
+		return
+	}
+	prev := s.lastLocalTarget
+	s.lastLocalTarget = ev.Target
+	if s.TargetSwitchGap <= 0 || prev == ev.Target {
+		return
+	}
+	ae := s.active[ev.Target]
+	if ae == nil || ae.enc == nil || ae.lastTs.IsZero() || ev.Timestamp.IsZero() {
+		return
+	}
+	if ev.Timestamp.Sub(ae.lastTs) <= s.TargetSwitchGap {
+		return
+	}
+	if ae.enc.End.IsZero() {
+		ae.enc.End = ae.lastTs
+	}
+	ae.enc.Retargeted = true
+	s.done = append(s.done, ae.enc)
+	delete(s.active, ev.Target)
+}
@@ -149,6 +149,7 @@ func (s *EncounterSegmenter) observeIdentityEvent(ev model.Event) {
 		s.identityEvents = s.identityEvents[len(s.identityEvents)-4096:]
 	}
 	s.identityDirty = true
+	s.identitySinceRefresh++
 }
 
 func (s *EncounterSegmenter) refreshIdentityIfNeeded(force bool) {
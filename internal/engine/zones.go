@@ -0,0 +1,40 @@
+package engine
+
+import "strings"
+
+// FilterEncountersByZone keeps the encounters fought in zone (case-insensitive). An empty
+// zone keeps everything.
+func FilterEncountersByZone(encs []*Encounter, zone string) []*Encounter {
+	zone = strings.TrimSpace(zone)
+	if zone == "" {
+		return encs
+	}
+	out := make([]*Encounter, 0, len(encs))
+	for _, e := range encs {
+		if e != nil && strings.EqualFold(e.Zone, zone) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// GroupEncountersByZone reorders encs so each zone's encounters are contiguous. Zones appear
+// in the order they are first seen in encs and encounters keep their relative order.
+func GroupEncountersByZone(encs []*Encounter) []*Encounter {
+	var zones []string
+	byZone := make(map[string][]*Encounter)
+	for _, e := range encs {
+		if e == nil {
+			continue
+		}
+		if _, ok := byZone[e.Zone]; !ok {
+			zones = append(zones, e.Zone)
+		}
+		byZone[e.Zone] = append(byZone[e.Zone], e)
+	}
+	out := make([]*Encounter, 0, len(encs))
+	for _, z := range zones {
+		out = append(out, byZone[z]...)
+	}
+	return out
+}
@@ -0,0 +1,250 @@
+package logindex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/parse"
+)
+
+// headSize is how much of the start of the log an Index keeps to recognise the file.
+const headSize = 256
+
+const indexMagic = "EQLOGIDX1\n"
+
+// An Index maps each minute of a log to the offset of the first line stamped in it. Minutes
+// are wall-clock minutes as written in the log, so an index does not depend on the time
+// zone the log is read in. A minute is only recorded the first time the log reaches it, so
+// after the clock goes back, or repeats an hour for DST, the index points at the earlier
+// pass and nothing the parser can stamp later than the cutoff is skipped. Logs only grow,
+// so an index is extended rather than rebuilt.
+type Index struct {
+	Size    int64   // bytes of the log covered; always ends on a line boundary
+	Head    []byte  // the first bytes of the log, to notice a replaced or truncated log
+	Entries []Entry // ascending in both Minute and Offset
+}
+
+type Entry struct {
+	Minute int64 // wall-clock minutes since the Unix epoch
+	Offset int64
+}
+
+// SidecarPath is where the index for the log at path is kept.
+func SidecarPath(path string) string {
+	return path + ".idx"
+}
+
+// Build indexes the whole of r, reading timestamps with the rules' layout (nil for the
+// built-in rules).
+func Build(r io.ReaderAt, size int64, rules *parse.RuleSet) (*Index, error) {
+	ix := &Index{}
+	if err := ix.Extend(r, size, rules); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Extend indexes the lines between ix.Size and size. A trailing line without a newline is
+// left for the next call, since the client may still be writing it.
+func (ix *Index) Extend(r io.ReaderAt, size int64, rules *parse.RuleSet) error {
+	rules = ruleSet(rules)
+	if ix.Size == 0 && len(ix.Head) == 0 {
+		head := make([]byte, min(size, headSize))
+		if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+			return err
+		}
+		ix.Head = head
+	}
+	last := int64(-1 << 63)
+	if n := len(ix.Entries); n > 0 {
+		last = ix.Entries[n-1].Minute
+	}
+	lr := newLineReader(r, ix.Size, size)
+	for {
+		off, line, err := lr.next()
+		if line == nil {
+			return ignoreEOF(err)
+		}
+		if line[len(line)-1] != '\n' {
+			return nil
+		}
+		if ts, ok := rules.LineTime(string(trimEOL(line)), time.UTC); ok {
+			if m := ts.Unix() / 60; m > last {
+				ix.Entries = append(ix.Entries, Entry{Minute: m, Offset: off})
+				last = m
+			}
+		}
+		ix.Size = off + int64(len(line))
+	}
+}
+
+// Offset is Seek answered from the index: the offset of the first minute at or after
+// MaxSkew before t, or ix.Size when the index ends before then.
+func (ix *Index) Offset(t time.Time, loc *time.Location) int64 {
+	if loc == nil {
+		loc = time.Local
+	}
+	w := t.In(loc).Add(-MaxSkew)
+	wall := time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), 0, 0, time.UTC)
+	m := wall.Unix() / 60
+	i := sort.Search(len(ix.Entries), func(i int) bool { return ix.Entries[i].Minute >= m })
+	if i == len(ix.Entries) {
+		return ix.Size
+	}
+	return ix.Entries[i].Offset
+}
+
+// matches reports whether ix describes a prefix of the log r of the given size.
+func (ix *Index) matches(r io.ReaderAt, size int64) bool {
+	if size < ix.Size || int64(len(ix.Head)) > size {
+		return false
+	}
+	head := make([]byte, len(ix.Head))
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return false
+	}
+	return bytes.Equal(head, ix.Head)
+}
+
+// Update brings the sidecar index of the log at path up to date, building it from scratch
+// when there is none or the log no longer matches it, and saves it.
+func Update(path string, rules *parse.RuleSet) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	ix, err := Load(SidecarPath(path))
+	if err != nil || !ix.matches(f, st.Size()) {
+		ix = &Index{}
+	}
+	if err := ix.Extend(f, st.Size(), rules); err != nil {
+		return nil, err
+	}
+	if err := ix.Save(SidecarPath(path)); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Load reads an index written by Save.
+func Load(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ix, err := readIndex(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ix, nil
+}
+
+// Save writes the index to path, replacing it atomically.
+func (ix *Index) Save(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	ix.write(w)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// The file holds the magic line, then uvarints: Size, len(Head), Head, the entry count and
+// each entry's minute and offset as deltas from the previous entry.
+func (ix *Index) write(w *bufio.Writer) {
+	var buf [binary.MaxVarintLen64]byte
+	put := func(v uint64) {
+		w.Write(buf[:binary.PutUvarint(buf[:], v)])
+	}
+	w.WriteString(indexMagic)
+	put(uint64(ix.Size))
+	put(uint64(len(ix.Head)))
+	w.Write(ix.Head)
+	put(uint64(len(ix.Entries)))
+	var prev Entry
+	for i, e := range ix.Entries {
+		if i == 0 {
+			w.Write(buf[:binary.PutVarint(buf[:], e.Minute)])
+		} else {
+			put(uint64(e.Minute - prev.Minute))
+		}
+		put(uint64(e.Offset - prev.Offset))
+		prev = e
+	}
+}
+
+var errBadIndex = errors.New("not a log index")
+
+func readIndex(r *bufio.Reader) (*Index, error) {
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != indexMagic {
+		return nil, errBadIndex
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errBadIndex
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > headSize {
+		return nil, errBadIndex
+	}
+	ix := &Index{Size: int64(size), Head: make([]byte, n)}
+	if _, err := io.ReadFull(r, ix.Head); err != nil {
+		return nil, errBadIndex
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > size {
+		return nil, errBadIndex
+	}
+	ix.Entries = make([]Entry, 0, count)
+	var prev Entry
+	for i := uint64(0); i < count; i++ {
+		var e Entry
+		if i == 0 {
+			m, err := binary.ReadVarint(r)
+			if err != nil {
+				return nil, errBadIndex
+			}
+			e.Minute = m
+		} else {
+			dm, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, errBadIndex
+			}
+			e.Minute = prev.Minute + int64(dm)
+		}
+		do, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, errBadIndex
+		}
+		e.Offset = prev.Offset + int64(do)
+		ix.Entries = append(ix.Entries, e)
+		prev = e
+	}
+	return ix, nil
+}
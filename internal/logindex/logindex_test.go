@@ -0,0 +1,321 @@
+package logindex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+	"github.com/ZehenForever/eqemu-log-parser/internal/parse"
+)
+
+const tsLayout = "Mon Jan 02 15:04:05 2006"
+
+var base = time.Date(2026, time.January, 20, 18, 0, 0, 0, time.UTC)
+
+// synthLog writes one line every step from base, with an untimestamped line now and then
+// and the occasional very long line.
+func synthLog(n int, step time.Duration) []byte {
+	var b bytes.Buffer
+	for i := 0; i < n; i++ {
+		ts := base.Add(time.Duration(i) * step)
+		switch {
+		case i%97 == 0:
+			b.WriteString("untimestamped noise\n")
+		case i%501 == 0:
+			fmt.Fprintf(&b, "[%s] You say, '%s'\r\n", ts.Format(tsLayout), strings.Repeat("z", 100*1024))
+		}
+		fmt.Fprintf(&b, "[%s] You hit a gnoll for %d points of damage.\r\n", ts.Format(tsLayout), i)
+	}
+	return b.Bytes()
+}
+
+// checkOffset verifies that off starts a line, that no line before it is stamped at or
+// after t, and that it is not more than MaxSkew early.
+func checkOffset(t *testing.T, data []byte, off int64, at time.Time) {
+	t.Helper()
+	if off > 0 && data[off-1] != '\n' {
+		t.Fatalf("offset %d for %v is not a line start", off, at)
+	}
+	for _, line := range strings.Split(string(data[:off]), "\n") {
+		if ts, ok := parse.LineTime(line, time.UTC); ok && !ts.Before(at) {
+			t.Fatalf("offset %d for %v skips %q", off, at, line)
+		}
+	}
+	if off < int64(len(data)) {
+		end := bytes.IndexByte(data[off:], '\n')
+		if end < 0 {
+			end = len(data) - int(off)
+		}
+		line := string(data[off : off+int64(end)])
+		if ts, ok := parse.LineTime(line, time.UTC); ok && ts.Before(at.Add(-MaxSkew-time.Minute)) {
+			t.Fatalf("offset %d for %v starts too early at %q", off, at, line)
+		}
+	}
+}
+
+func TestSeek_FindsTimeInSyntheticLog(t *testing.T) {
+	data := synthLog(20000, 7*time.Second)
+	r := bytes.NewReader(data)
+	ix, err := Build(r, int64(len(data)), nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	end := base.Add(20000 * 7 * time.Second)
+	for _, at := range []time.Time{
+		base.Add(-time.Hour),
+		base,
+		base.Add(3 * time.Minute),
+		base.Add(11*time.Hour + 17*time.Second),
+		end.Add(-2 * time.Hour),
+		end.Add(-time.Minute),
+		end.Add(time.Hour),
+	} {
+		off, err := Seek(r, int64(len(data)), at, nil, time.UTC)
+		if err != nil {
+			t.Fatalf("Seek(%v): %v", at, err)
+		}
+		checkOffset(t, data, off, at)
+		checkOffset(t, data, ix.Offset(at, time.UTC), at)
+	}
+}
+
+func TestSeek_ToleratesSmallBackwardSteps(t *testing.T) {
+	var b bytes.Buffer
+	for i := 0; i < 5000; i++ {
+		ts := base.Add(time.Duration(i) * 5 * time.Second)
+		if i%10 == 9 {
+			ts = ts.Add(-2 * time.Minute)
+		}
+		fmt.Fprintf(&b, "[%s] line %d\n", ts.Format(tsLayout), i)
+	}
+	data := b.Bytes()
+	at := base.Add(3 * time.Hour)
+	off, err := Seek(bytes.NewReader(data), int64(len(data)), at, nil, time.UTC)
+	if err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	checkOffset(t, data, off, at)
+}
+
+func TestIndex_OffsetUsesWallClock(t *testing.T) {
+	data := synthLog(2000, 10*time.Second)
+	ix, err := Build(bytes.NewReader(data), int64(len(data)), nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	loc := time.FixedZone("EST", -5*3600)
+	at := time.Date(2026, time.January, 20, 20, 0, 0, 0, loc)
+	off, err := Seek(bytes.NewReader(data), int64(len(data)), at, nil, loc)
+	if err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	if got := ix.Offset(at, loc); got != off {
+		t.Fatalf("Offset=%d Seek=%d", got, off)
+	}
+}
+
+func TestUpdate_ExtendsAndRebuildsSidecar(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "eqlog_Emberval_Imperium.txt")
+	data := synthLog(3000, 10*time.Second)
+	// Leave a partial line at the end, as if the client were mid-write.
+	half := len(data) - 20
+	if err := os.WriteFile(p, data[:half], 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ix, err := Update(p, nil)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if ix.Size >= int64(half) || data[ix.Size-1] != '\n' {
+		t.Fatalf("index covers %d of %d bytes", ix.Size, half)
+	}
+
+	if err := os.WriteFile(p, data, 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ix, err = Update(p, nil)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	want, _ := Build(bytes.NewReader(data), int64(len(data)), nil)
+	if !reflect.DeepEqual(ix, want) {
+		t.Fatalf("extended index differs from a fresh build")
+	}
+	loaded, err := Load(SidecarPath(p))
+	if err != nil || !reflect.DeepEqual(loaded, want) {
+		t.Fatalf("Load: %v", err)
+	}
+
+	replaced := synthLog(50, time.Minute)
+	if err := os.WriteFile(p, replaced, 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ix, err = Update(p, nil)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if ix.Size != int64(len(replaced)) || len(ix.Entries) == 0 || ix.Entries[len(ix.Entries)-1].Minute != base.Add(49*time.Minute).Unix()/60 {
+		t.Fatalf("index was not rebuilt for a replaced log: size %d entries %d", ix.Size, len(ix.Entries))
+	}
+}
+
+func TestSeekFile_SearchesPastSidecar(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "eqlog_Emberval_Imperium.txt")
+	data := synthLog(6000, 10*time.Second)
+	if err := os.WriteFile(p, data[:len(data)/2], 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := Update(p, nil); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := os.WriteFile(p, data, 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	for _, at := range []time.Time{base.Add(2 * time.Hour), base.Add(15 * time.Hour)} {
+		off, err := SeekFile(p, at, nil, time.UTC)
+		if err != nil {
+			t.Fatalf("SeekFile: %v", err)
+		}
+		checkOffset(t, data, off, at)
+	}
+}
+
+func TestSeek_Testdata(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "testdata", "eqlog_lordsoth_coalesce.txt"))
+	if err != nil {
+		t.Fatalf("testdata file not present: %v", err)
+	}
+	first, _ := parse.LineTime(string(data[:bytes.IndexByte(data, '\n')]), time.UTC)
+	at := first.Add(2 * time.Minute)
+	off, err := Seek(bytes.NewReader(data), int64(len(data)), at, nil, time.UTC)
+	if err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	checkOffset(t, data, off, at)
+}
+
+func TestOpen_RestoresZoneAndTarget(t *testing.T) {
+	stamp := func(d time.Duration, msg string) string {
+		return fmt.Sprintf("[%s] %s\r\n", base.Add(d).Format(tsLayout), msg)
+	}
+	for _, tc := range []struct {
+		head       []string
+		zone, want string
+	}{
+		{[]string{
+			stamp(-3*time.Hour, "You have entered Nexus."),
+			stamp(-2*time.Hour, "Targeted (NPC): a rat"),
+			stamp(-time.Hour, "You have entered The Arena."),
+			stamp(-time.Minute, "Targeted (NPC): Lord Soth"),
+		}, "The Arena", "Lord Soth"},
+		// The target was picked in the zone the player has since left.
+		{[]string{
+			stamp(-2*time.Hour, "Targeted (NPC): a rat"),
+			stamp(-time.Hour, "You have entered The Arena."),
+		}, "The Arena", ""},
+	} {
+		p := filepath.Join(t.TempDir(), "eqlog_Emberval_Imperium.txt")
+		data := append([]byte(strings.Join(tc.head, "")), synthLog(6000, 10*time.Second)...)
+		if err := os.WriteFile(p, data, 0600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		at := base.Add(10 * time.Hour)
+		ctx := &model.ParseContext{}
+		f, err := Open(p, &at, nil, time.UTC, ctx)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		off, err := f.Seek(0, io.SeekCurrent)
+		f.Close()
+		if err != nil {
+			t.Fatalf("seek: %v", err)
+		}
+		checkOffset(t, data, off, at)
+		if ctx.Zone != tc.zone || ctx.Target != tc.want || ctx.TargetIsNPC != (tc.want != "") {
+			t.Fatalf("zone=%q target=%q npc=%v want %q %q", ctx.Zone, ctx.Target, ctx.TargetIsNPC, tc.zone, tc.want)
+		}
+	}
+}
+
+func TestOpen_RestoreScanIsBounded(t *testing.T) {
+	defer func(n int64) { restoreLimit = n }(restoreLimit)
+	restoreLimit = 64 * 1024
+
+	zone := fmt.Sprintf("[%s] You have entered The Arena.\r\n", base.Add(-time.Hour).Format(tsLayout))
+	p := filepath.Join(t.TempDir(), "eqlog_Emberval_Imperium.txt")
+	if err := os.WriteFile(p, append([]byte(zone), synthLog(6000, 10*time.Second)...), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	at := base.Add(10 * time.Hour)
+	ctx := &model.ParseContext{}
+	f, err := Open(p, &at, nil, time.UTC, ctx)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	f.Close()
+	// The zone line is far more than restoreLimit back, so it is not looked for.
+	if ctx.Zone != "" {
+		t.Fatalf("zone=%q want none", ctx.Zone)
+	}
+}
+
+func TestSeek_AgreesWithParserAcrossDSTFallBack(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	// Every 2s from 00:00 EDT to 03:00 EST, written in local wall time, so 01:xx appears twice.
+	var b bytes.Buffer
+	start := time.Date(2026, time.November, 1, 0, 0, 0, 0, ny)
+	for ts := start; ts.Before(start.Add(4 * time.Hour)); ts = ts.Add(2 * time.Second) {
+		fmt.Fprintf(&b, "[%s] You hit a gnoll for 5 points of damage.\n", ts.In(ny).Format(tsLayout))
+	}
+	data := b.Bytes()
+
+	// The parser's timeline, with each line's offset.
+	ctx := &model.ParseContext{}
+	var offs []int64
+	var times []time.Time
+	off := int64(0)
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if ev, ok := parse.ParseLine(ctx, strings.TrimSuffix(line, "\n"), ny); ok {
+			offs = append(offs, off)
+			times = append(times, ev.Timestamp)
+		}
+		off += int64(len(line))
+	}
+
+	ix, err := Build(bytes.NewReader(data), int64(len(data)), nil)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	for _, at := range []time.Time{
+		time.Date(2026, time.November, 1, 5, 40, 0, 0, time.UTC), // 01:40 EDT, first pass
+		time.Date(2026, time.November, 1, 6, 30, 0, 0, time.UTC), // 01:30 EST, second pass
+		time.Date(2026, time.November, 1, 7, 10, 0, 0, time.UTC), // 02:10 EST
+	} {
+		first := int64(len(data))
+		for i, ts := range times {
+			if !ts.Before(at) {
+				first = offs[i]
+				break
+			}
+		}
+		got, err := Seek(bytes.NewReader(data), int64(len(data)), at, nil, ny)
+		if err != nil {
+			t.Fatalf("Seek: %v", err)
+		}
+		if got > first || ix.Offset(at, ny) > first {
+			t.Fatalf("%v: seek=%d index=%d, but the parser's first line at or after it is at %d", at, got, ix.Offset(at, ny), first)
+		}
+	}
+}
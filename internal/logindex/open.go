@@ -0,0 +1,101 @@
+package logindex
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+	"github.com/ZehenForever/eqemu-log-parser/internal/parse"
+)
+
+// Open opens the log at path for reading from the first line stamped at or after cutoff,
+// or from the start when cutoff is nil. Lines before the offset are not parsed, so Open
+// restores into ctx the parse state they would have left that later lines depend on: the
+// zone last entered and the local player's target in it. Name spellings are learned from
+// the lines actually read.
+func Open(path string, cutoff *time.Time, rules *parse.RuleSet, loc *time.Location, ctx *model.ParseContext) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil || cutoff == nil {
+		return f, err
+	}
+	off, err := SeekFile(path, *cutoff, rules, loc)
+	if err == nil && ctx != nil {
+		err = restoreState(f, off, ruleSet(rules), loc, ctx)
+	}
+	if err == nil {
+		_, err = f.Seek(off, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// restoreLimit bounds how far before the seek offset restoreState looks for a zone line, so
+// that opening the tail of a large log with no recent zoning does not parse all of it.
+var restoreLimit int64 = 16 << 20
+
+// restoreState reads the lines before off backwards up to the last zone line, keeping the
+// zone and the latest target line seen on the way. Zoning drops the target, so nothing
+// before the zone line matters. It gives up restoreLimit bytes back, leaving the zone
+// empty.
+func restoreState(r io.ReaderAt, off int64, rules *parse.RuleSet, loc *time.Location, ctx *model.ParseContext) error {
+	var target *model.ParseContext
+	err := eachLineBackward(r, max(off-restoreLimit, 0), off, func(line []byte) bool {
+		scratch := &model.ParseContext{LocalActorName: ctx.LocalActorName}
+		if _, ok := rules.ParseLine(scratch, string(line), loc); !ok {
+			return true
+		}
+		if target == nil && scratch.Target != "" {
+			target = scratch
+		}
+		if scratch.Zone == "" {
+			return true
+		}
+		ctx.Zone = scratch.Zone
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	if target != nil {
+		ctx.Target = target.Target
+		ctx.TargetIsNPC = target.TargetIsNPC
+	}
+	return nil
+}
+
+// eachLineBackward calls fn with the lines of r[lo:off], last first and without their
+// newlines, until fn returns false. off must be a line start; a line cut at lo is skipped.
+func eachLineBackward(r io.ReaderAt, lo, off int64, fn func(line []byte) bool) error {
+	var tail []byte // the start of r[off':off] not yet known to be a whole line
+	for off > lo {
+		n := min(off-lo, probeSize)
+		chunk := make([]byte, n, n+int64(len(tail)))
+		if _, err := r.ReadAt(chunk, off-n); err != nil && err != io.EOF {
+			return err
+		}
+		off -= n
+		buf := append(chunk, tail...)
+		// The last byte ends the newest line; it is its newline unless the log ends there.
+		end := len(buf)
+		for {
+			i := bytes.LastIndexByte(buf[:max(end-1, 0)], '\n')
+			if i < 0 {
+				break
+			}
+			if !fn(trimEOL(buf[i+1 : end])) {
+				return nil
+			}
+			end = i + 1
+		}
+		tail = buf[:end]
+	}
+	if len(tail) > 0 && lo == 0 {
+		fn(trimEOL(tail))
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// Package logindex finds where a point in time starts in an eqlog file, so that reading
+// only the last few hours of a multi-gigabyte log does not mean parsing all of it.
+//
+// EverQuest appends lines in time order, so Seek binary-searches the file by timestamp.
+// An Index kept in a sidecar file next to the log maps every minute to the offset of its
+// first line and answers the same question without probing.
+package logindex
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/parse"
+)
+
+// MaxSkew is how far a line's timestamp may run behind an earlier line's. Log lines are
+// near-monotonic rather than strictly ordered, so Seek and Index.Offset look for MaxSkew
+// before the requested time and leave exact filtering to the caller.
+const MaxSkew = 5 * time.Minute
+
+// probeSize is the buffer used for each probe; below it the search scans linearly.
+const probeSize = 64 * 1024
+
+// Seek returns the offset of the line to start reading at to see every line stamped t or
+// later: a line start at or before the first such line. It returns size when the file
+// holds nothing that recent. Timestamps are read with the rules' layout in loc; nil rules
+// means the built-in ones.
+//
+// Lines are compared at their latest reading (parse.LatestReading), so a line in the hour
+// a DST change repeats, which the parser may place in the second pass, is never skipped.
+// The search assumes the log's clock only runs forward otherwise. A log whose clock was
+// set back (a KindClockJump when parsed) repeats times the search may find in the later
+// pass only; the sidecar index, which records the first line to reach each minute, does
+// not have that problem.
+func Seek(r io.ReaderAt, size int64, t time.Time, rules *parse.RuleSet, loc *time.Location) (int64, error) {
+	return seek(r, 0, size, t, ruleSet(rules), loc)
+}
+
+// SeekFile is Seek for the log at path. It uses the sidecar index when one exists for this
+// file and binary-searches whatever the index does not cover.
+func SeekFile(path string, t time.Time, rules *parse.RuleSet, loc *time.Location) (int64, error) {
+	rules = ruleSet(rules)
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := st.Size()
+
+	var lo int64
+	if ix, err := Load(SidecarPath(path)); err == nil && ix.matches(f, size) {
+		off := ix.Offset(t, loc)
+		if off < ix.Size {
+			return off, nil
+		}
+		lo = ix.Size
+	}
+	return seek(f, lo, size, t, rules, loc)
+}
+
+// seek searches [lo, size), where lo is a line start.
+func seek(r io.ReaderAt, lo, size int64, t time.Time, rules *parse.RuleSet, loc *time.Location) (int64, error) {
+	target := t.Add(-MaxSkew)
+	hi := size
+	for hi-lo > probeSize {
+		mid := lo + (hi-lo)/2
+		start, ts, ok, err := firstStamped(r, mid, size, rules, loc)
+		if err != nil {
+			return 0, err
+		}
+		if ok && parse.LatestReading(ts).Before(target) {
+			lo = start
+		} else {
+			hi = mid
+		}
+	}
+
+	lr := newLineReader(r, lo, size)
+	for {
+		off, line, err := lr.next()
+		if line == nil {
+			if err == io.EOF {
+				return size, nil
+			}
+			return 0, err
+		}
+		if ts, ok := rules.LineTime(string(trimEOL(line)), loc); ok && !parse.LatestReading(ts).Before(target) {
+			return off, nil
+		}
+	}
+}
+
+// firstStamped finds the first timestamped line starting at or after off.
+func firstStamped(r io.ReaderAt, off, size int64, rules *parse.RuleSet, loc *time.Location) (int64, time.Time, bool, error) {
+	lr := newLineReader(r, off, size)
+	if off > 0 {
+		// off may fall inside a line; the first whole line starts after the next newline.
+		var prev [1]byte
+		if _, err := r.ReadAt(prev[:], off-1); err != nil {
+			return 0, time.Time{}, false, err
+		}
+		if prev[0] != '\n' {
+			if _, line, err := lr.next(); line == nil {
+				return 0, time.Time{}, false, ignoreEOF(err)
+			}
+		}
+	}
+	for {
+		start, line, err := lr.next()
+		if line == nil {
+			return 0, time.Time{}, false, ignoreEOF(err)
+		}
+		if ts, ok := rules.LineTime(string(trimEOL(line)), loc); ok {
+			return start, ts, true, nil
+		}
+	}
+}
+
+// lineReader yields the lines of r[off:size] with their offsets. A line includes its
+// newline; the last line may lack one.
+type lineReader struct {
+	br  *bufio.Reader
+	off int64
+	buf []byte
+}
+
+func newLineReader(r io.ReaderAt, off, size int64) *lineReader {
+	return &lineReader{br: bufio.NewReaderSize(io.NewSectionReader(r, off, size-off), probeSize), off: off}
+}
+
+// next returns the next line, valid until the following call, or nil and the error that
+// ended the input.
+func (lr *lineReader) next() (int64, []byte, error) {
+	line, err := lr.br.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		lr.buf = append(lr.buf[:0], line...)
+		for errors.Is(err, bufio.ErrBufferFull) {
+			line, err = lr.br.ReadSlice('\n')
+			lr.buf = append(lr.buf, line...)
+		}
+		line = lr.buf
+	}
+	if len(line) == 0 {
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, nil, err
+	}
+	if err != nil && err != io.EOF {
+		return 0, nil, err
+	}
+	start := lr.off
+	lr.off += int64(len(line))
+	return start, line, nil
+}
+
+func ruleSet(rules *parse.RuleSet) *parse.RuleSet {
+	if rules == nil {
+		return parse.DefaultRules()
+	}
+	return rules
+}
+
+func trimEOL(line []byte) []byte {
+	return bytes.TrimSuffix(line, []byte{'\n'})
+}
+
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
@@ -18,6 +18,38 @@ const (
 	KindDeath
 	KindZoneOrSystem
 	KindIncomingDamage
+	// KindSpecialAttack marks "goes on a RAMPAGE!" / "executes a FLURRY" announcements;
+	// Modifier carries which special attack was announced.
+	KindSpecialAttack
+	// KindResist is a resisted spell: Actor "YOU" for "Your target resisted the X spell."
+	// (the target is not named), Target "YOU" for "You resist the X spell!".
+	KindResist
+	// KindSpellAbsorb is "The Spellshield absorbed N of M points of damage"; Amount is the
+	// absorbed part and MetaInt the incoming total.
+	KindSpellAbsorb
+	// KindChat is a line of speech: Actor is the speaker, Channel and Message carry the rest.
+	// Tells set Target to the recipient.
+	KindChat
+	// KindStatus is a crowd-control or status line; Target is the subject and Status the effect.
+	KindStatus
+	// KindLoot is "--X has looted a Y.--": Actor is the looter, Item the item and Target the
+	// corpse when the line names one.
+	KindLoot
+	// KindExperience is "You gain party experience!!"; Verb is "solo", "party" or "raid".
+	KindExperience
+	// KindAbilityPoint is "You have gained N ability point(s)!": Amount is the points gained
+	// and MetaInt the new total.
+	KindAbilityPoint
+	// KindTarget is "Targeted (NPC): X" / "Targeted (Player): X": Actor is "YOU", Target the
+	// new target and Verb "npc" or "player".
+	KindTarget
+	// KindCastOutcome is what became of a cast after it began (Cast); Actor is the caster and
+	// SpellOrSkill the spell when the line names it. Worn-off lines put the recipient in Target.
+	KindCastOutcome
+	// KindClockJump is a discontinuity: the log's clock went back in a way the time zone
+	// does not explain. It comes from no line of its own; streams carry it just ahead of the
+	// first line after the jump, with that line's Timestamp and Raw, and ClockJump set.
+	KindClockJump
 )
 
 type DamageClass uint8
@@ -30,6 +62,73 @@ const (
 	DamageClassBash
 	DamageClassKick
 	DamageClassDirect
+	DamageClassDoT
+)
+
+// CritType distinguishes the kinds of critical success announced by meta lines.
+type CritType uint8
+
+const (
+	CritNone CritType = iota
+	CritNormal
+	CritCrippling
+	CritDeadly
+	CritExceptionalHeal
+	// CritStrikethrough marks "You strike through your opponent's defenses!". It bypasses
+	// avoidance rather than raising damage, so it is never attached to a hit.
+	CritStrikethrough
+)
+
+// AttackModifier tags melee hits that land as part of a special attack.
+type AttackModifier uint8
+
+const (
+	ModifierNone AttackModifier = iota
+	ModifierRampage
+	ModifierWildRampage
+	ModifierFlurry
+)
+
+// StatusEffect identifies what a KindStatus line reports about its subject.
+type StatusEffect uint8
+
+const (
+	StatusNone StatusEffect = iota
+	StatusStunned
+	StatusStunEnded
+	// StatusStunResisted is "You shake off the stun effect!", StatusStunAvoided is
+	// "You avoid the stunning blow."; neither is preceded by StatusStunned.
+	StatusStunResisted
+	StatusStunAvoided
+	StatusTauntFailed
+	StatusStagger
+	StatusDelirium
+)
+
+// CastOutcome identifies what a KindCastOutcome line reports about a cast.
+type CastOutcome uint8
+
+const (
+	CastNone CastOutcome = iota
+	CastInterrupted
+	CastFizzled
+	// CastRecovered is "X regains concentration and continues casting.": an interrupt was
+	// shrugged off and the cast goes on.
+	CastRecovered
+	CastSongEnded
+	CastWornOff
+)
+
+// AvoidType records how a KindAvoid or KindMiss swing failed to land.
+type AvoidType uint8
+
+const (
+	AvoidNone AvoidType = iota
+	AvoidMiss
+	AvoidDodge
+	AvoidParry
+	AvoidRiposte
+	AvoidBlock
 )
 
 type Event struct {
@@ -45,11 +144,54 @@ type Event struct {
 	AmountKnown  bool
 	Crit         bool
 	MetaInt      int64
+	Modifier     AttackModifier
+	CritType     CritType
+	Avoid        AvoidType
+	Status       StatusEffect
+	Cast         CastOutcome
+	// ActorOwner is the owner of Actor when Actor is a pet ("Lord Soth`s pet" -> "Lord Soth").
+	ActorOwner string
+	// Zone is the zone the local player was in when the line was logged, or the zone just
+	// entered for a zone change event.
+	Zone string
+	// Channel and Message are set on KindChat events. Channel is "say", "tell", "group",
+	// "guild", "raid", "shout", "ooc", "auction", "npc" (NPC speech, e.g. boss emotes) or the
+	// name of a custom chat channel.
+	Channel string
+	Message string
+	// Item is the looted item on KindLoot events.
+	Item string
+	// ClockJump is how far the log's clock jumped back, set on KindClockJump events and on
+	// the first line after the jump. Timestamps are left as the log wrote them.
+	ClockJump time.Duration
 }
 
 type ParseContext struct {
 	LocalActorName string
 	PendingCrit    *PendingCrit
+	PendingHeal    *PendingCrit
+	PendingCast    *PendingCast
+	PendingFlurry  *PendingFlurry
+	DoTs           map[string]*DoTMarker
+	Names          NameTable
+	// Zone is the zone named by the most recent "You have entered X." line.
+	Zone string
+	// Target is the local player's current target from the last "Targeted (...)" line and
+	// TargetIsNPC reports whether it was an NPC.
+	Target      string
+	TargetIsNPC bool
+	// LastTime is the timestamp of the previous line, to notice the clock jumping.
+	LastTime time.Time
+}
+
+// NameTable folds the spellings of an actor or target name ("Lord Hydrerious ",
+// "LordSoth`s pet", "A training dummy") onto one canonical spelling, which is the first
+// one seen, and tracks the nicest spelling for display.
+type NameTable struct {
+	Canonical map[string]string // spelling -> canonical spelling
+	ByKey     map[string]string // lower-cased, space-free key -> canonical spelling
+	Display   map[string]string // canonical spelling -> display spelling
+	Counts    map[string]int    // spelling -> times seen
 }
 
 type PendingCrit struct {
@@ -57,4 +199,26 @@ type PendingCrit struct {
 	Ts    time.Time
 	Value int64
 	TTL   int
+	Type  CritType
+}
+
+// PendingFlurry remembers a flurry announcement so the swings that follow it can be tagged.
+type PendingFlurry struct {
+	Actor  string
+	Target string
+	Ts     time.Time
+	TTL    int
+}
+
+type PendingCast struct {
+	Actor string
+	Spell string
+	Ts    time.Time
+}
+
+// DoTMarker records that Actor most recently landed a damage-over-time spell on a target.
+type DoTMarker struct {
+	Actor string
+	Spell string
+	Ts    time.Time
 }
@@ -0,0 +1,22 @@
+package model
+
+import "strings"
+
+var petSuffixes = []string{"`s pet", "`s warder", "`s ward"}
+
+// PetOwner returns the owner of a backtick-possessive pet name ("Lord Soth`s pet" -> "Lord Soth").
+func PetOwner(name string) (string, bool) {
+	owner, _, ok := SplitPet(name)
+	return owner, ok
+}
+
+// SplitPet splits a backtick-possessive pet name into its owner and suffix
+// ("Lord Soth`s warder" -> "Lord Soth", "`s warder").
+func SplitPet(name string) (owner string, suffix string, ok bool) {
+	for _, suf := range petSuffixes {
+		if owner, ok := strings.CutSuffix(name, suf); ok && owner != "" {
+			return owner, suf, true
+		}
+	}
+	return "", "", false
+}
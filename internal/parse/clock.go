@@ -0,0 +1,125 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+// LoadLocation resolves the time zone a log was written in: "" or "Local" for this
+// machine's zone, an IANA name such as "America/New_York", or a fixed offset such as
+// "UTC-5" or "+02:00".
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	switch name {
+	case "", "Local", "local":
+		return time.Local, nil
+	}
+	rest, ok := strings.CutPrefix(name, "UTC")
+	if !ok {
+		rest, ok = strings.CutPrefix(name, "GMT")
+	}
+	if !ok && (name[0] == '+' || name[0] == '-') {
+		rest, ok = name, true
+	}
+	if !ok {
+		return time.LoadLocation(name)
+	}
+	if rest == "" {
+		return time.UTC, nil
+	}
+	secs, ok := parseZoneOffset(rest)
+	if !ok {
+		return nil, fmt.Errorf("invalid time zone offset %q", name)
+	}
+	return time.FixedZone(name, secs), nil
+}
+
+// parseZoneOffset parses "+2", "-05", "+05:30" or "-0330" into seconds east of UTC.
+func parseZoneOffset(s string) (int, bool) {
+	if len(s) < 2 || (s[0] != '+' && s[0] != '-') {
+		return 0, false
+	}
+	sign := 1
+	if s[0] == '-' {
+		sign = -1
+	}
+	hh, mm := strings.ReplaceAll(s[1:], ":", ""), ""
+	if len(hh) > 2 {
+		hh, mm = hh[:len(hh)-2], hh[len(hh)-2:]
+	}
+	h, ok := digits(hh)
+	if !ok || len(hh) == 0 || h > 14 {
+		return 0, false
+	}
+	m := 0
+	if mm != "" {
+		if m, ok = digits(mm); !ok || m > 59 {
+			return 0, false
+		}
+	}
+	return sign * (h*3600 + m*60), true
+}
+
+// clockSkew is how far a line may run behind the one before it before the step counts as
+// the clock jumping back.
+const clockSkew = time.Minute
+
+// adjustClock resolves ts against the previous line and reports a clock jump. Logs are
+// written in order, so a line more than clockSkew behind the previous one means the clock
+// went back. When loc explains it, because ts falls in the hour a DST change repeats, the
+// line is read as the second pass through that hour and no jump is reported; the reading
+// ends with the repeated hour. Otherwise the jump is returned and ts is left as written,
+// since nothing in the log says how much time really passed. A forward step is never a
+// jump: a clock set forward reads the same as a break in play, so it goes undetected.
+func adjustClock(ctx *model.ParseContext, ts time.Time) (time.Time, time.Duration) {
+	if ctx == nil {
+		return ts, 0
+	}
+	var jump time.Duration
+	if !ctx.LastTime.IsZero() {
+		if step := ts.Sub(ctx.LastTime); step < -clockSkew {
+			if later := LatestReading(ts); !later.Before(ctx.LastTime.Add(-clockSkew)) {
+				ts = later
+			} else {
+				jump = step
+			}
+		}
+	}
+	ctx.LastTime = ts
+	return ts, jump
+}
+
+// LatestReading returns the last instant ts's wall-clock reading stands for in its
+// location. That is ts itself except in the hour a DST-aware location repeats when clocks
+// fall back, where time.Date picks the first pass and this returns the second.
+func LatestReading(ts time.Time) time.Time {
+	_, end := ts.ZoneBounds()
+	if end.IsZero() {
+		return ts
+	}
+	_, off := ts.Zone()
+	_, next := end.Zone()
+	back := time.Duration(off-next) * time.Second
+	if back <= 0 || end.Sub(ts) > back {
+		return ts
+	}
+	return ts.Add(back)
+}
+
+// ClockJumpEvent is the discontinuity event streams carry ahead of ev, the first line after
+// the log's clock jumped. ok is false when ev follows no jump.
+func ClockJumpEvent(ev model.Event) (model.Event, bool) {
+	if ev.ClockJump == 0 {
+		return model.Event{}, false
+	}
+	return model.Event{
+		Timestamp: ev.Timestamp,
+		Kind:      model.KindClockJump,
+		Raw:       ev.Raw,
+		Zone:      ev.Zone,
+		ClockJump: ev.ClockJump,
+	}, true
+}
@@ -0,0 +1,146 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+func TestLoadLocation(t *testing.T) {
+	cases := []struct {
+		name   string
+		offset int // seconds east of UTC in January 2026
+	}{
+		{"UTC", 0},
+		{"UTC-5", -5 * 3600},
+		{"GMT+2", 2 * 3600},
+		{"+05:30", 5*3600 + 30*60},
+		{"-0330", -(3*3600 + 30*60)},
+		{"America/New_York", -5 * 3600},
+	}
+	for _, tc := range cases {
+		loc, err := LoadLocation(tc.name)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		_, off := time.Date(2026, time.January, 24, 12, 0, 0, 0, loc).Zone()
+		if off != tc.offset {
+			t.Fatalf("%s: offset %d want %d", tc.name, off, tc.offset)
+		}
+	}
+	if loc, err := LoadLocation(""); err != nil || loc != time.Local {
+		t.Fatalf("empty name: %v %v", loc, err)
+	}
+	for _, bad := range []string{"UTC+", "UTC+25", "+5:75", "Nowhere/Special"} {
+		if _, err := LoadLocation(bad); err == nil {
+			t.Fatalf("%s: want error", bad)
+		}
+	}
+}
+
+func parseLines(t *testing.T, lines []string, loc *time.Location) []model.Event {
+	t.Helper()
+	ctx := &model.ParseContext{}
+	var out []model.Event
+	for _, line := range lines {
+		ev, ok := ParseLine(ctx, line, loc)
+		if !ok {
+			t.Fatalf("not parsed: %q", line)
+		}
+		out = append(out, ev)
+	}
+	return out
+}
+
+func TestParseLine_DSTChangesFollowLocation(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	utc := func(s string) time.Time {
+		ts, _ := time.Parse(time.RFC3339, s)
+		return ts
+	}
+	cases := []struct {
+		lines []string
+		want  []time.Time
+	}{
+		// Clocks fall back at 02:00 EDT: the 01:xx hour is written twice.
+		{[]string{
+			"[Sun Nov 01 01:59:58 2026] You hit a gnoll for 5 points of damage.",
+			"[Sun Nov 01 01:00:01 2026] You hit a gnoll for 6 points of damage.",
+			"[Sun Nov 01 01:00:05 2026] You hit a gnoll for 7 points of damage.",
+			"[Sun Nov 01 02:07:00 2026] You hit a gnoll for 8 points of damage.",
+		}, []time.Time{utc("2026-11-01T05:59:58Z"), utc("2026-11-01T06:00:01Z"), utc("2026-11-01T06:00:05Z"), utc("2026-11-01T07:07:00Z")}},
+		// Clocks spring forward at 02:00 EST: no 02:xx hour at all.
+		{[]string{
+			"[Sun Mar 08 01:59:58 2026] You hit a gnoll for 5 points of damage.",
+			"[Sun Mar 08 03:00:01 2026] You hit a gnoll for 6 points of damage.",
+		}, []time.Time{utc("2026-03-08T06:59:58Z"), utc("2026-03-08T07:00:01Z")}},
+	}
+	for _, tc := range cases {
+		evs := parseLines(t, tc.lines, ny)
+		for i, ev := range evs {
+			if ev.ClockJump != 0 || !ev.Timestamp.Equal(tc.want[i]) {
+				t.Fatalf("%q: time=%v jump=%v want %v", tc.lines[i], ev.Timestamp.UTC(), ev.ClockJump, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestParseFile_ClockJumpBackIsADiscontinuity(t *testing.T) {
+	log := strings.Join([]string{
+		"[Sat Jan 24 20:00:00 2026] You hit a gnoll for 5 points of damage.",
+		// A forward step is a break, however round the number.
+		"[Sat Jan 24 23:00:00 2026] You hit a gnoll for 6 points of damage.",
+		// The clock was set back an hour with no zone change to explain it.
+		"[Sat Jan 24 22:00:05 2026] You hit a gnoll for 7 points of damage.",
+		"[Sat Jan 24 22:00:09 2026] You hit a gnoll for 8 points of damage.",
+	}, "\n")
+	var got []model.Event
+	it := ParseFile(strings.NewReader(log), &model.ParseContext{}, time.UTC)
+	for it.Next() {
+		got = append(got, it.Event())
+	}
+	kinds := []model.EventKind{model.KindMeleeDamage, model.KindMeleeDamage, model.KindClockJump, model.KindMeleeDamage, model.KindMeleeDamage}
+	if len(got) != len(kinds) {
+		t.Fatalf("events=%d want %d", len(got), len(kinds))
+	}
+	for i, k := range kinds {
+		if got[i].Kind != k {
+			t.Fatalf("event %d kind=%v want %v", i, got[i].Kind, k)
+		}
+	}
+	if jump := got[2]; jump.ClockJump != -(59*time.Minute+55*time.Second) || jump.Raw != got[3].Raw || !jump.Timestamp.Equal(got[3].Timestamp) {
+		t.Fatalf("jump event=%+v", jump)
+	}
+	// Times are left as the log wrote them.
+	if want := time.Date(2026, time.January, 24, 22, 0, 5, 0, time.UTC); !got[3].Timestamp.Equal(want) || got[1].ClockJump != 0 || got[4].ClockJump != 0 {
+		t.Fatalf("after jump=%v", got[3].Timestamp)
+	}
+}
+
+func TestCompileRules_TimeLayout(t *testing.T) {
+	pack, err := LoadRulePack(strings.NewReader(`{"name": "iso", "time_layout": "2006-01-02 15:04:05", "rules": []}`))
+	if err != nil {
+		t.Fatalf("LoadRulePack: %v", err)
+	}
+	rs, err := CompileRules(ImperiumPack(), pack)
+	if err != nil {
+		t.Fatalf("CompileRules: %v", err)
+	}
+	ev, ok := rs.ParseLine(&model.ParseContext{}, "[2026-01-24 23:14:30] You hit a gnoll for 5 points of damage.", time.UTC)
+	if !ok || ev.Kind != model.KindMeleeDamage || !ev.Timestamp.Equal(time.Date(2026, time.January, 24, 23, 14, 30, 0, time.UTC)) {
+		t.Fatalf("got %v %+v", ok, ev)
+	}
+	if _, ok := rs.ParseLine(&model.ParseContext{}, "[Sat Jan 24 23:14:30 2026] You hit a gnoll for 5 points of damage.", time.UTC); ok {
+		t.Fatalf("client layout accepted by an ISO rule set")
+	}
+
+	bad := &RulePack{Name: "bad", TimeLayout: "not a layout"}
+	if _, err := CompileRules(ImperiumPack(), bad); err == nil {
+		t.Fatalf("want error for a layout without time fields")
+	}
+}
@@ -0,0 +1,237 @@
+package parse
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+// A Census counts how many parsed lines the rules recognised and groups the rest into
+// templates: the message with numbers, quoted text and known names replaced by
+// placeholders, so "Sigdis bashes a gnoll for 12 points of damage." and "Emberval bashes
+// a gnoll for 40 points of damage." count as one unknown message.
+type Census struct {
+	Lines      int // timestamped lines
+	Recognized int // lines a rule matched
+
+	// Damage lines are the ones whose message mentions damage, recognised or not.
+	DamageLines      int
+	DamageRecognized int
+
+	// unknown counts unrecognised messages after the name-independent rewrites; names are
+	// replaced in Templates, once every name in the log has been seen.
+	unknown map[string]*Template
+}
+
+// A Template is one shape of unrecognised message.
+type Template struct {
+	Text    string
+	Count   int
+	Example string // one raw line of this shape
+}
+
+// Placeholders used in templates.
+const (
+	templateNumber = "#"
+	templateName   = "<name>"
+	templateText   = "<text>"
+)
+
+func NewCensus() *Census {
+	return &Census{unknown: make(map[string]*Template)}
+}
+
+// Add counts one parsed event.
+func (c *Census) Add(ev model.Event) {
+	if ev.Kind == model.KindClockJump {
+		// Not a line of its own; the line after the jump follows it.
+		return
+	}
+	c.Lines++
+	_, msg, _ := strings.Cut(ev.Raw, "] ")
+	msg = strings.TrimSpace(msg)
+	damage := strings.Contains(msg, "damage")
+	if damage {
+		c.DamageLines++
+	}
+	if ev.Kind != model.KindUnknown {
+		c.Recognized++
+		if damage {
+			c.DamageRecognized++
+		}
+		return
+	}
+
+	key := templateNumbers(templateQuotes(cleanName(msg)))
+	t := c.unknown[key]
+	if t == nil {
+		t = &Template{Text: key, Example: ev.Raw}
+		c.unknown[key] = t
+	}
+	t.Count++
+}
+
+// Coverage is the share of lines recognised, from 0 to 1. An empty census is fully covered.
+func (c *Census) Coverage() float64 {
+	return ratio(c.Recognized, c.Lines)
+}
+
+// DamageCoverage is the share of damage lines recognised.
+func (c *Census) DamageCoverage() float64 {
+	return ratio(c.DamageRecognized, c.DamageLines)
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 1
+	}
+	return float64(n) / float64(d)
+}
+
+// Templates returns the unrecognised templates, most frequent first. Names in ctx's name
+// table and the local player's name are replaced by placeholders.
+func (c *Census) Templates(ctx *model.ParseContext) []Template {
+	names := newNameMatcher(ctx)
+	merged := make(map[string]*Template, len(c.unknown))
+	for _, t := range c.unknown {
+		text := names.replace(t.Text)
+		m := merged[text]
+		if m == nil {
+			merged[text] = &Template{Text: text, Count: t.Count, Example: t.Example}
+			continue
+		}
+		m.Count += t.Count
+		if t.Example < m.Example {
+			// Any fixed choice will do, as long as it does not depend on map order.
+			m.Example = t.Example
+		}
+	}
+	out := make([]Template, 0, len(merged))
+	for _, t := range merged {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Text < out[j].Text
+	})
+	return out
+}
+
+// templateNumbers replaces each run of digits, with any thousands separators, by "#".
+func templateNumbers(s string) string {
+	if strings.IndexAny(s, "0123456789") < 0 {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		for i+1 < len(s) && (isDigit(s[i+1]) || (s[i+1] == ',' && i+2 < len(s) && isDigit(s[i+2]))) {
+			i++
+		}
+		b.WriteString(templateNumber)
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// templateQuotes replaces what was said in "X says, 'hello'" and similar by "<text>".
+func templateQuotes(s string) string {
+	open := strings.Index(s, ", '")
+	if open < 0 || !strings.HasSuffix(s, "'") || len(s) < open+4 {
+		return s
+	}
+	return s[:open+3] + templateText + "'"
+}
+
+// nameMatcher finds known names at word boundaries. Names are grouped by first word and
+// tried longest first, so "Lord Soth`s pet" wins over "Lord Soth".
+type nameMatcher struct {
+	byFirst map[string][]string
+}
+
+func newNameMatcher(ctx *model.ParseContext) *nameMatcher {
+	nm := &nameMatcher{byFirst: make(map[string][]string)}
+	if ctx == nil {
+		return nm
+	}
+	add := func(name string) {
+		if name == "" || name == "YOU" {
+			return
+		}
+		first, _, _ := strings.Cut(name, " ")
+		nm.byFirst[first] = append(nm.byFirst[first], name)
+	}
+	for name := range ctx.Names.Canonical {
+		add(name)
+	}
+	if _, ok := ctx.Names.Canonical[ctx.LocalActorName]; !ok {
+		add(ctx.LocalActorName)
+	}
+	for first, names := range nm.byFirst {
+		sort.Slice(names, func(i, j int) bool {
+			if len(names[i]) != len(names[j]) {
+				return len(names[i]) > len(names[j])
+			}
+			return names[i] < names[j]
+		})
+		nm.byFirst[first] = names
+	}
+	return nm
+}
+
+func (nm *nameMatcher) replace(s string) string {
+	if len(nm.byFirst) == 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if i == 0 || !isWordByte(s[i-1]) {
+			end := i
+			for end < len(s) && s[end] != ' ' {
+				end++
+			}
+			if n := nm.match(s[i:], s[i:end]); n > 0 {
+				b.WriteString(templateName)
+				i += n
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// match returns the length of the longest known name at the start of s, whose first
+// space-separated token is word.
+func (nm *nameMatcher) match(s, word string) int {
+	for len(word) > 0 {
+		for _, name := range nm.byFirst[word] {
+			if strings.HasPrefix(s, name) && (len(s) == len(name) || !isWordByte(s[len(name)])) {
+				return len(name)
+			}
+		}
+		// "Sigdis's" or "Sigdis." start with the name "Sigdis" but the token carries the
+		// punctuation too.
+		cut := strings.LastIndexFunc(word, func(r rune) bool { return r < 0x80 && !isWordByte(byte(r)) })
+		if cut < 0 {
+			return 0
+		}
+		word = word[:cut]
+	}
+	return 0
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c == '_' || c == '`' || c >= 0x80
+}
@@ -0,0 +1,93 @@
+package parse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+func TestCensus_Templates(t *testing.T) {
+	lines := []string{
+		"[Sat Jan 24 23:14:30 2026] Sigdis hits a gnoll for 12 points of damage.",
+		"[Sat Jan 24 23:14:31 2026] Lord Soth`s pet hits a gnoll for 30 points of damage.",
+		"[Sat Jan 24 23:14:32 2026] Sigdis stings a gnoll for 1,809 points of damage.",
+		"[Sat Jan 24 23:14:33 2026] Lord Soth`s pet stings a gnoll for 7 points of damage.",
+		"[Sat Jan 24 23:14:34 2026] Lord Hydrerious  quivers as a bolt of energy surges through them.",
+		"[Sat Jan 24 23:14:35 2026] Lord Hydrerious quivers as a bolt of energy surges through them.",
+		"[Sat Jan 24 23:14:36 2026] Your task 'Innothuleb Daily' has been updated.",
+		"[Sat Jan 24 23:14:37 2026] Emberval tells the guild, 'inc'",
+		"[Sat Jan 24 23:14:38 2026] Lord Hydrerious hits Sigdis for 900 points of damage.",
+		"[Sat Jan 24 23:14:39 2026] Emberval's focus wavers.",
+		"no timestamp",
+	}
+	ctx := &model.ParseContext{LocalActorName: "Emberval"}
+	c := NewCensus()
+	for _, line := range lines {
+		if ev, ok := ParseLine(ctx, line, time.UTC); ok {
+			c.Add(ev)
+		}
+	}
+	if c.Lines != 10 || c.Recognized != 4 || c.DamageLines != 5 || c.DamageRecognized != 3 {
+		t.Fatalf("census %+v", c)
+	}
+
+	got := map[string]int{}
+	for _, tpl := range c.Templates(ctx) {
+		got[tpl.Text] = tpl.Count
+	}
+	want := map[string]int{
+		"<name> stings <name> for # points of damage.":            2,
+		"<name> quivers as a bolt of energy surges through them.": 2,
+		"Your task 'Innothuleb Daily' has been updated.":          1,
+		"<name>'s focus wavers.":                                  1,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("templates %v", got)
+	}
+	for text, n := range want {
+		if got[text] != n {
+			t.Fatalf("template %q count=%d want %d (all %v)", text, got[text], n, got)
+		}
+	}
+}
+
+func TestCensus_TestdataCoverage(t *testing.T) {
+	// Floors for the fixtures; raise them as the rules learn new messages.
+	floors := []struct {
+		name          string
+		lines, damage float64
+	}{
+		{"eqlog_Emberval_Imperium_EQ.txt", 0.97, 1},
+		{"eqlog_lordsoth_coalesce.txt", 0.94, 0.996},
+		{"encounter_LordHydrerious.txt", 0.90, 1},
+		{"encounter_Oshiruk.txt", 0.92, 1},
+	}
+	for _, fl := range floors {
+		f, err := os.Open(filepath.Join("..", "..", "testdata", fl.name))
+		if err != nil {
+			t.Fatalf("testdata file not present: %v", err)
+		}
+		player, _ := PlayerNameFromLogPath(fl.name)
+		ctx := &model.ParseContext{LocalActorName: player}
+		c := NewCensus()
+		it := ParseFile(f, ctx, time.UTC)
+		for it.Next() {
+			c.Add(it.Event())
+		}
+		f.Close()
+		if err := it.Err(); err != nil {
+			t.Fatalf("%s: %v", fl.name, err)
+		}
+		if c.Coverage() < fl.lines || c.DamageCoverage() < fl.damage {
+			tpls := c.Templates(ctx)
+			if len(tpls) > 5 {
+				tpls = tpls[:5]
+			}
+			t.Errorf("%s: lines %.4f (floor %.3f) damage %.4f (floor %.3f); top unknown %+v",
+				fl.name, c.Coverage(), fl.lines, c.DamageCoverage(), fl.damage, tpls)
+		}
+	}
+}
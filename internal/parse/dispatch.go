@@ -0,0 +1,218 @@
+package parse
+
+import (
+	"regexp/syntax"
+	"strings"
+	"time"
+)
+
+// Most lines match none of the rules or only one near the end of the list, so running
+// every regex in turn is what parsing time goes on. Each rule therefore carries the
+// literal text its pattern requires: the text right after "^", the text right before "$"
+// and the longest other literal. These are checked with plain string compares before the
+// regex runs, and rules are bucketed by the last byte of their suffix so a line only
+// visits rules that could end the way it does.
+
+// ruleLiterals extracts the literals a match of pattern must contain. Anything the
+// analysis does not understand simply yields no literal, which only costs speed.
+func ruleLiterals(pattern string) (prefix, suffix, needle string) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", "", ""
+	}
+	re = re.Simplify()
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+	anchoredStart := len(subs) > 0 && subs[0].Op == syntax.OpBeginText
+	anchoredEnd := len(subs) > 0 && subs[len(subs)-1].Op == syntax.OpEndText
+
+	var lits []string
+	collectLiterals(subs, &lits)
+	if anchoredStart && len(subs) > 1 && isPlainLiteral(subs[1]) {
+		prefix = string(subs[1].Rune)
+	}
+	if anchoredEnd && len(subs) > 1 && isPlainLiteral(subs[len(subs)-2]) {
+		suffix = string(subs[len(subs)-2].Rune)
+	}
+	for _, lit := range lits {
+		if lit != prefix && lit != suffix && len(lit) > len(needle) {
+			needle = lit
+		}
+	}
+	return prefix, suffix, needle
+}
+
+// collectLiterals gathers the literals that every match contains: those in the top-level
+// concatenation and inside capture groups, but not under repetition or alternation.
+func collectLiterals(subs []*syntax.Regexp, out *[]string) {
+	for _, s := range subs {
+		switch s.Op {
+		case syntax.OpLiteral:
+			if isPlainLiteral(s) {
+				*out = append(*out, string(s.Rune))
+			}
+		case syntax.OpCapture, syntax.OpConcat:
+			collectLiterals(s.Sub, out)
+		}
+	}
+}
+
+func isPlainLiteral(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpLiteral && re.Flags&syntax.FoldCase == 0
+}
+
+// mayMatch is the cheap test run before a rule's regex.
+func (cr *compiledRule) mayMatch(msg string) bool {
+	if cr.prefix != "" && !strings.HasPrefix(msg, cr.prefix) {
+		return false
+	}
+	if cr.suffix != "" && !strings.HasSuffix(msg, cr.suffix) {
+		return false
+	}
+	if cr.needle != "" && !strings.Contains(msg, cr.needle) {
+		return false
+	}
+	return true
+}
+
+// buildDispatch fills the per-last-byte candidate lists. Every list keeps priority order.
+func (rs *RuleSet) buildDispatch() {
+	for i := range rs.byLastByte {
+		rs.byLastByte[i] = nil
+	}
+	rs.noSuffix = nil
+	for _, cr := range rs.rules {
+		if cr.suffix == "" {
+			rs.noSuffix = append(rs.noSuffix, cr)
+			for i := range rs.byLastByte {
+				rs.byLastByte[i] = append(rs.byLastByte[i], cr)
+			}
+			continue
+		}
+		last := cr.suffix[len(cr.suffix)-1]
+		rs.byLastByte[last] = append(rs.byLastByte[last], cr)
+	}
+}
+
+func (rs *RuleSet) candidates(msg string) []*compiledRule {
+	if msg == "" {
+		return rs.noSuffix
+	}
+	return rs.byLastByte[msg[len(msg)-1]]
+}
+
+var (
+	monthNames   = [...]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+	weekdayNames = [...]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+)
+
+// splitLine splits "[Mon Jan 02 15:04:05 2006] message" into its timestamp and message
+// without regexes or allocations.
+func splitLine(line string, loc *time.Location) (time.Time, string, bool) {
+	stamp, msg, ok := splitBrackets(line)
+	if !ok {
+		return time.Time{}, "", false
+	}
+	ts, ok := parseTimestamp(stamp, loc)
+	if !ok {
+		return time.Time{}, "", false
+	}
+	return ts, msg, true
+}
+
+// splitLine splits a line using the rule set's timestamp layout, taking the fast path for
+// the client's own.
+func (rs *RuleSet) splitLine(line string, loc *time.Location) (time.Time, string, bool) {
+	if rs.layout == "" || rs.layout == tsLayout {
+		return splitLine(line, loc)
+	}
+	stamp, msg, ok := splitBrackets(line)
+	if !ok {
+		return time.Time{}, "", false
+	}
+	ts, err := time.ParseInLocation(rs.layout, stamp, loc)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return ts, msg, true
+}
+
+// splitBrackets splits "[stamp] message". The message follows at least one space.
+func splitBrackets(line string) (string, string, bool) {
+	if len(line) < 2 || line[0] != '[' {
+		return "", "", false
+	}
+	end := strings.IndexByte(line, ']')
+	if end < 0 {
+		return "", "", false
+	}
+	rest := line[end+1:]
+	i := 0
+	for i < len(rest) && isSpace(rest[i]) {
+		i++
+	}
+	if i == 0 || strings.IndexByte(rest[i:], '\n') >= 0 {
+		return "", "", false
+	}
+	return line[1:end], rest[i:], true
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// parseTimestamp parses the tsLayout form ("Mon Jan 02 15:04:05 2006") and rejects what
+// time.ParseInLocation would.
+func parseTimestamp(s string, loc *time.Location) (time.Time, bool) {
+	if len(s) != len(tsLayout) || s[3] != ' ' || s[7] != ' ' || s[10] != ' ' || s[13] != ':' || s[16] != ':' || s[19] != ' ' {
+		return time.Time{}, false
+	}
+	// Like time.Parse, day and month names are matched without regard to case.
+	known := false
+	for _, d := range weekdayNames {
+		if strings.EqualFold(s[:3], d) {
+			known = true
+			break
+		}
+	}
+	month := time.Month(0)
+	for i, m := range monthNames {
+		if strings.EqualFold(s[4:7], m) {
+			month = time.Month(i + 1)
+			break
+		}
+	}
+	if !known || month == 0 {
+		return time.Time{}, false
+	}
+	day, ok1 := digits(s[8:10])
+	hour, ok2 := digits(s[11:13])
+	min, ok3 := digits(s[14:16])
+	sec, ok4 := digits(s[17:19])
+	year, ok5 := digits(s[20:24])
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 {
+		return time.Time{}, false
+	}
+	if hour > 23 || min > 59 || sec > 59 || day < 1 || day > daysIn(month, year) {
+		return time.Time{}, false
+	}
+	return time.Date(year, month, day, hour, min, sec, 0, loc), true
+}
+
+func digits(s string) (int, bool) {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
+
+func daysIn(m time.Month, year int) int {
+	return time.Date(year, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,190 @@
+package parse
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+var testdataLogs = []string{
+	"eqlog_Emberval_Imperium_EQ.txt",
+	"eqlog_lordsoth_coalesce.txt",
+	"encounter_LordHydrerious.txt",
+	"encounter_Oshiruk.txt",
+}
+
+func readTestdataLines(t testing.TB, name string) []string {
+	t.Helper()
+	f, err := os.Open(filepath.Join("..", "..", "testdata", name))
+	if err != nil {
+		t.Fatalf("testdata file not present: %v", err)
+	}
+	defer f.Close()
+	var lines []string
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 128*1024), 4*1024*1024)
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return lines
+}
+
+// cascadeRules returns a copy of rs that tries every rule's regex in priority order, the
+// way the parser worked before dispatch.
+func cascadeRules(rs *RuleSet) *RuleSet {
+	out := &RuleSet{}
+	for _, cr := range rs.rules {
+		c := *cr
+		c.prefix, c.suffix, c.needle = "", "", ""
+		out.rules = append(out.rules, &c)
+	}
+	out.buildDispatch()
+	return out
+}
+
+var reTimestampLine = regexp.MustCompile(`^\[(?P<ts>[^\]]+)\]\s+(?P<msg>.*)$`)
+
+func splitLineRegexp(line string, loc *time.Location) (time.Time, string, bool) {
+	idx := reTimestampLine.FindStringSubmatchIndex(line)
+	if idx == nil {
+		return time.Time{}, "", false
+	}
+	ts, err := time.ParseInLocation(tsLayout, reSub(line, idx, 1), loc)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return ts, reSub(line, idx, 2), true
+}
+
+func TestDispatch_MatchesCascadeOnTestdata(t *testing.T) {
+	fast := DefaultRules()
+	slow := cascadeRules(fast)
+	for _, name := range testdataLogs {
+		player, _ := PlayerNameFromLogPath(name)
+		fastCtx := &model.ParseContext{LocalActorName: player}
+		slowCtx := &model.ParseContext{LocalActorName: player}
+		for _, line := range readTestdataLines(t, name) {
+			want, wantOK := slow.ParseLine(slowCtx, line, time.UTC)
+			got, gotOK := fast.ParseLine(fastCtx, line, time.UTC)
+			if gotOK != wantOK || !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s: %q\n got %v %+v\nwant %v %+v", name, line, gotOK, got, wantOK, want)
+			}
+		}
+		if !reflect.DeepEqual(fastCtx, slowCtx) {
+			t.Fatalf("%s: parse contexts differ after the file", name)
+		}
+	}
+}
+
+func TestSplitLine_MatchesTimeParse(t *testing.T) {
+	lines := []string{
+		"[Sat Jan 24 23:14:30 2026] You hit a gnoll for 5 points of damage.",
+		"[Sat Jan 24 23:14:30 2026]\t  tabbed",
+		"[Sat Jan 24 23:14:30 2026] \n newline before message",
+		"[Sat Jan 24 23:14:30 2026] line\nbreak",
+		"[Sat Jan 24 23:14:30 2026]",
+		"[Sat Jan 24 23:14:30 2026] ",
+		"[Sat Jan 24 23:14:30 2026]no space",
+		"[sat jan 24 23:14:30 2026] lower case names",
+		"[SAT JAN 24 23:14:30 2026] upper case names",
+		"[Xyz Jan 24 23:14:30 2026] bad weekday",
+		"[Sat Jam 24 23:14:30 2026] bad month",
+		"[Sat Feb 29 23:14:30 2024] leap day",
+		"[Sat Feb 29 23:14:30 2026] not a leap year",
+		"[Sat Jan 00 23:14:30 2026] day zero",
+		"[Sat Jan 32 23:14:30 2026] day 32",
+		"[Sat Jan 24 24:00:00 2026] hour 24",
+		"[Sat Jan 24 23:60:00 2026] minute 60",
+		"[Sat Jan 24 23:14:60 2026] second 60",
+		"[Sat Jan  4 23:14:30 2026] padded day",
+		"[Sat Jan 24 23:14:30 26] short year",
+		"[Sat Jan 24 23:14:30 2026 ] trailing space",
+		"[] empty",
+		"[Sat Jan 24 23:14:30 2026",
+		"Sat Jan 24 23:14:30 2026] no bracket",
+		"",
+	}
+	loc := time.FixedZone("test", -5*3600)
+	for _, name := range testdataLogs {
+		lines = append(lines, readTestdataLines(t, name)...)
+	}
+	for _, line := range lines {
+		wantTs, wantMsg, wantOK := splitLineRegexp(line, loc)
+		gotTs, gotMsg, gotOK := splitLine(line, loc)
+		if gotOK != wantOK || gotMsg != wantMsg || !gotTs.Equal(wantTs) || gotTs.Location() != wantTs.Location() {
+			t.Fatalf("%q: got %v %v %q want %v %v %q", line, gotOK, gotTs, gotMsg, wantOK, wantTs, wantMsg)
+		}
+	}
+}
+
+func TestRuleLiterals(t *testing.T) {
+	cases := []struct {
+		pattern                string
+		prefix, suffix, needle string
+	}{
+		{`^You have entered (?P<zone>.+?)\.$`, "You have entered ", ".", ""},
+		{`^(?P<actor>.+?) hits (?P<target>.+?) for (?P<amt>\d+) points? of damage\.$`, "", " of damage.", " hits "},
+		{`^(?P<actor>.+?) says out of character, '(?P<msg>.*)'$`, "", "'", " says out of character, '"},
+		{`^(?i)you (hit|bash) `, "", "", ""},
+		{`You begin casting`, "", "", "You begin casting"},
+		{`^(?:a|b)c$`, "", "c", ""},
+	}
+	for _, tc := range cases {
+		prefix, suffix, needle := ruleLiterals(tc.pattern)
+		if prefix != tc.prefix || suffix != tc.suffix || needle != tc.needle {
+			t.Errorf("%s: got %q %q %q want %q %q %q", tc.pattern, prefix, suffix, needle, tc.prefix, tc.suffix, tc.needle)
+		}
+	}
+}
+
+func benchmarkParse(b *testing.B, rs *RuleSet) {
+	var lines []string
+	for _, name := range testdataLogs {
+		lines = append(lines, readTestdataLines(b, name)...)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx := &model.ParseContext{LocalActorName: "Emberval"}
+		for _, line := range lines {
+			rs.ParseLine(ctx, line, time.UTC)
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(lines)), "ns/line")
+}
+
+func BenchmarkParse_Dispatch(b *testing.B) {
+	benchmarkParse(b, DefaultRules())
+}
+
+func BenchmarkParse_Cascade(b *testing.B) {
+	benchmarkParse(b, cascadeRules(DefaultRules()))
+}
+
+func benchmarkSplit(b *testing.B, split func(string, *time.Location) (time.Time, string, bool)) {
+	line := "[Sat Jan 24 23:14:30 2026] Emberval hits a gnoll for 125 points of damage."
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, msg, ok := split(line, time.UTC); !ok || !strings.HasPrefix(msg, "Emberval") {
+			b.Fatal("split failed")
+		}
+	}
+}
+
+func BenchmarkSplitLine(b *testing.B) {
+	benchmarkSplit(b, splitLine)
+}
+
+func BenchmarkSplitLine_Regexp(b *testing.B) {
+	benchmarkSplit(b, splitLineRegexp)
+}
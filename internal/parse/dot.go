@@ -0,0 +1,100 @@
+package parse
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+// UnattributedDoTActor is used for "was hit by non-melee" ticks that cannot be
+// correlated with a damage-over-time spell cast by the local player.
+const UnattributedDoTActor = "Unattributed DoT"
+
+const (
+	// castAfflictionWindow bounds how long after "You begin casting" an affliction
+	// line can still be credited to that cast.
+	castAfflictionWindow = 10 * time.Second
+	// dotAttributionTTL bounds how long a landed DoT keeps claiming ticks on its target.
+	dotAttributionTTL = 2 * time.Minute
+)
+
+func notePendingCast(ctx *model.ParseContext, ev *model.Event) {
+	if ctx == nil || ev.Actor != "YOU" || ev.SpellOrSkill == "" {
+		return
+	}
+	ctx.PendingCast = &model.PendingCast{Actor: ev.Actor, Spell: ev.SpellOrSkill, Ts: ev.Timestamp}
+}
+
+func noteAffliction(ctx *model.ParseContext, ev *model.Event) {
+	if ctx == nil || ctx.PendingCast == nil || ev.Target == "" {
+		return
+	}
+	pc := ctx.PendingCast
+	dt := ev.Timestamp.Sub(pc.Ts)
+	if dt < 0 || dt > castAfflictionWindow {
+		return
+	}
+	if !afflictionMatchesCast(pc.Spell, ev.SpellOrSkill) {
+		return
+	}
+	// The line may name only the resist type; resist lines name the spell, so use that.
+	ev.Actor = pc.Actor
+	ev.SpellOrSkill = pc.Spell
+	if ctx.DoTs == nil {
+		ctx.DoTs = make(map[string]*model.DoTMarker)
+	}
+	ctx.DoTs[ev.Target] = &model.DoTMarker{Actor: pc.Actor, Spell: pc.Spell, Ts: ev.Timestamp}
+	// One cast lands one affliction; anything after it belongs to someone else.
+	ctx.PendingCast = nil
+}
+
+// afflictionResists are the generic words the client prints in place of the spell name
+// ("A training dummy is afflicted by poison.") for some damage-over-time spells.
+var afflictionResists = []string{"poison", "disease", "fire", "cold", "magic", "corruption"}
+
+// afflictionMatchesCast reports whether an "is afflicted by" line can be the landing of
+// spell: it names the spell, or names its resist type and the spell name mentions it.
+func afflictionMatchesCast(spell, affliction string) bool {
+	if strings.EqualFold(spell, affliction) {
+		return true
+	}
+	for _, r := range afflictionResists {
+		if strings.EqualFold(affliction, r) {
+			for _, w := range strings.Fields(spell) {
+				if strings.EqualFold(w, r) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// attributeDoT fills in the caster of an anonymous DoT tick from the per-target
+// markers recorded by noteAffliction, falling back to UnattributedDoTActor.
+func attributeDoT(ctx *model.ParseContext, ev *model.Event) {
+	ev.Actor = UnattributedDoTActor
+	if ctx == nil || ctx.DoTs == nil {
+		return
+	}
+	m := ctx.DoTs[ev.Target]
+	if m == nil {
+		return
+	}
+	dt := ev.Timestamp.Sub(m.Ts)
+	if dt < 0 || dt > dotAttributionTTL {
+		delete(ctx.DoTs, ev.Target)
+		return
+	}
+	ev.Actor = m.Actor
+	ev.SpellOrSkill = m.Spell
+}
+
+func clearDoTs(ctx *model.ParseContext, target string) {
+	if ctx == nil || ctx.DoTs == nil {
+		return
+	}
+	delete(ctx.DoTs, target)
+}
@@ -0,0 +1,72 @@
+package parse
+
+import (
+	"strings"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+// normalizeNames rewrites Actor and Target to their canonical spelling so one mob or
+// player never splits into several encounters or actor rows.
+func normalizeNames(ctx *model.ParseContext, ev *model.Event) {
+	ev.Actor = canonicalName(ctx, ev.Actor)
+	ev.Target = canonicalName(ctx, ev.Target)
+}
+
+func canonicalName(ctx *model.ParseContext, name string) string {
+	if name == "" || name == "YOU" {
+		return name
+	}
+	name = cleanName(name)
+	if ctx == nil {
+		return name
+	}
+	t := &ctx.Names
+	c, ok := t.Canonical[name]
+	if !ok {
+		if t.Canonical == nil {
+			t.Canonical = make(map[string]string)
+			t.ByKey = make(map[string]string)
+			t.Display = make(map[string]string)
+			t.Counts = make(map[string]int)
+		}
+		key := nameKey(name)
+		if c, ok = t.ByKey[key]; !ok {
+			c = restorePetOwner(t, name)
+			t.ByKey[key] = c
+		}
+		t.Canonical[name] = c
+	}
+	noteDisplayName(t, name, c)
+	return c
+}
+
+// noteDisplayName prefers the spelling with the most spaces ("Lord Soth`s pet" over
+// "LordSoth`s pet"), then the one seen most often ("a training dummy" over the
+// sentence-initial "A training dummy").
+func noteDisplayName(t *model.NameTable, name, canonical string) {
+	t.Counts[name]++
+	cur, ok := t.Display[canonical]
+	if !ok || cur == name {
+		if !ok {
+			t.Display[canonical] = name
+		}
+		return
+	}
+	ns, cs := strings.Count(name, " "), strings.Count(cur, " ")
+	if ns > cs || (ns == cs && t.Counts[name] > t.Counts[cur]) {
+		t.Display[canonical] = name
+	}
+}
+
+// cleanName trims and collapses runs of whitespace ("Lord Hydrerious " -> "Lord Hydrerious").
+func cleanName(name string) string {
+	if !strings.Contains(name, "  ") && strings.TrimSpace(name) == name {
+		return name
+	}
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func nameKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", ""))
+}
@@ -0,0 +1,202 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+// parallelChunkSize is how much of the file one worker prepares at a time.
+var parallelChunkSize = 4 * 1024 * 1024
+
+// maxLineSize matches the longest line the sequential Iterator's scanner accepts.
+const maxLineSize = 4 * 1024 * 1024
+
+// ParseFileParallel is ParseFile with the built-in rules, spread over workers.
+func ParseFileParallel(r io.Reader, ctx *model.ParseContext, loc *time.Location, workers int) *Iterator {
+	return DefaultRules().ParseFileParallel(r, ctx, loc, workers)
+}
+
+// ParseFileParallel parses r like ParseFile and yields the same events in the same order,
+// but splits the input into line-aligned chunks whose lines are matched against the rules
+// on workers goroutines (GOMAXPROCS when workers <= 0).
+//
+// Only the stateless part of a line runs on the workers: the timestamp and the first
+// regex that matches. Fields, hooks and the name table are applied on the caller's
+// goroutine in log order, so everything carried on ctx from one line to the next (pending
+// crits and heals, flurries, DoT casters, pet owners, zone and target) sees the same lines
+// in the same order as a sequential parse, including across chunk boundaries.
+//
+// Call Close when abandoning the iterator before Next returns false.
+func (rs *RuleSet) ParseFileParallel(r io.Reader, ctx *model.ParseContext, loc *time.Location, workers int) *Iterator {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	p := &parallelReader{
+		rules: rs,
+		loc:   loc,
+		jobs:  make(chan *chunk, workers),
+		order: make(chan *chunk, 2*workers),
+		stop:  make(chan struct{}),
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work()
+		}()
+	}
+	go func() {
+		p.read(r)
+		close(p.jobs)
+		wg.Wait()
+	}()
+	return &Iterator{rules: rs, ctx: ctx, loc: loc, par: p}
+}
+
+// A chunk is a run of whole lines. The reader queues it for a worker and, in file order,
+// for the iterator, which waits on done before finishing its lines.
+type chunk struct {
+	data  []byte
+	lines []preparedLine
+	err   error
+	done  chan struct{}
+}
+
+type parallelReader struct {
+	rules *RuleSet
+	loc   *time.Location
+	jobs  chan *chunk
+	order chan *chunk
+	stop  chan struct{}
+	once  sync.Once
+
+	cur *chunk
+	i   int
+}
+
+// read cuts r into chunks at line ends. A read error, including a line longer than
+// maxLineSize, ends the stream with a chunk carrying the error.
+func (p *parallelReader) read(r io.Reader) {
+	defer close(p.order)
+	var carry []byte
+	for {
+		// A partial line at least doubles the next read, so long lines stay linear.
+		buf := make([]byte, len(carry), len(carry)+max(parallelChunkSize, len(carry)))
+		copy(buf, carry)
+		n, err := io.ReadFull(r, buf[len(carry):cap(buf)])
+		buf = buf[:len(carry)+n]
+		eof := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !eof {
+			p.send(&chunk{err: err, done: closedDone()})
+			return
+		}
+
+		data := buf
+		carry = nil
+		if !eof {
+			cut := bytes.LastIndexByte(buf, '\n')
+			if cut < 0 {
+				if len(buf) > maxLineSize {
+					p.send(&chunk{err: bufio.ErrTooLong, done: closedDone()})
+					return
+				}
+				carry = buf
+				continue
+			}
+			data, carry = buf[:cut+1], buf[cut+1:]
+		}
+		if len(data) > 0 && !p.send(&chunk{data: data, done: make(chan struct{})}) {
+			return
+		}
+		if eof {
+			return
+		}
+	}
+}
+
+// send queues c for the iterator and, unless it only carries an error, for a worker.
+func (p *parallelReader) send(c *chunk) bool {
+	select {
+	case p.order <- c:
+	case <-p.stop:
+		return false
+	}
+	if c.data == nil {
+		return true
+	}
+	select {
+	case p.jobs <- c:
+		return true
+	case <-p.stop:
+		return false
+	}
+}
+
+func (p *parallelReader) work() {
+	for c := range p.jobs {
+		c.lines, c.err = p.prepare(c.data)
+		c.data = nil
+		close(c.done)
+	}
+}
+
+// prepare splits data into lines the way bufio.ScanLines does and prepares each one.
+func (p *parallelReader) prepare(data []byte) ([]preparedLine, error) {
+	lines := make([]preparedLine, 0, bytes.Count(data, []byte{'\n'})+1)
+	for len(data) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			line, data = data, nil
+		}
+		if len(line) > maxLineSize {
+			return lines, bufio.ErrTooLong
+		}
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+		if pl, ok := p.rules.prepareLine(string(line), p.loc); ok {
+			lines = append(lines, pl)
+		}
+	}
+	return lines, nil
+}
+
+// next returns the next prepared line in file order.
+func (p *parallelReader) next() (*preparedLine, error) {
+	for {
+		if p.cur != nil {
+			if p.i < len(p.cur.lines) {
+				p.i++
+				return &p.cur.lines[p.i-1], nil
+			}
+			if p.cur.err != nil {
+				return nil, p.cur.err
+			}
+		}
+		c, ok := <-p.order
+		if !ok {
+			return nil, nil
+		}
+		<-c.done
+		p.cur, p.i = c, 0
+	}
+}
+
+func (p *parallelReader) close() {
+	p.once.Do(func() { close(p.stop) })
+}
+
+func closedDone() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
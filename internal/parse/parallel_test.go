@@ -0,0 +1,135 @@
+package parse
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+func withChunkSize(t testing.TB, n int) {
+	t.Helper()
+	old := parallelChunkSize
+	parallelChunkSize = n
+	t.Cleanup(func() { parallelChunkSize = old })
+}
+
+func collectEvents(t testing.TB, it *Iterator) []model.Event {
+	t.Helper()
+	var out []model.Event
+	for it.Next() {
+		out = append(out, it.Event())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("iterate: %v", err)
+	}
+	return out
+}
+
+func TestParseFileParallel_MatchesSequentialOnTestdata(t *testing.T) {
+	// Small chunks put boundaries between crit meta lines and the hits they annotate.
+	for _, size := range []int{512, 64 * 1024, 4 * 1024 * 1024} {
+		withChunkSize(t, size)
+		for _, name := range testdataLogs {
+			data, err := os.ReadFile(filepath.Join("..", "..", "testdata", name))
+			if err != nil {
+				t.Fatalf("testdata file not present: %v", err)
+			}
+			player, _ := PlayerNameFromLogPath(name)
+			seqCtx := &model.ParseContext{LocalActorName: player}
+			parCtx := &model.ParseContext{LocalActorName: player}
+			want := collectEvents(t, ParseFile(strings.NewReader(string(data)), seqCtx, time.UTC))
+			got := collectEvents(t, ParseFileParallel(strings.NewReader(string(data)), parCtx, time.UTC, 4))
+			if len(got) != len(want) {
+				t.Fatalf("%s chunk %d: got %d events want %d", name, size, len(got), len(want))
+			}
+			for i := range want {
+				if !reflect.DeepEqual(got[i], want[i]) {
+					t.Fatalf("%s chunk %d: event %d\n got %+v\nwant %+v", name, size, i, got[i], want[i])
+				}
+			}
+			if !reflect.DeepEqual(parCtx, seqCtx) {
+				t.Fatalf("%s chunk %d: parse contexts differ after the file", name, size)
+			}
+		}
+	}
+}
+
+func TestParseFileParallel_LineEndings(t *testing.T) {
+	withChunkSize(t, 16)
+	in := "[Sat Jan 24 23:14:30 2026] You hit a gnoll for 5 points of damage.\r\n" +
+		"\n" +
+		"not a log line\n" +
+		"[Sat Jan 24 23:14:31 2026] A gnoll hits YOU for 3 points of damage.\r\r\n" +
+		"[Sat Jan 24 23:14:32 2026] You have entered Blackburrow."
+	want := collectEvents(t, ParseFile(strings.NewReader(in), &model.ParseContext{}, time.UTC))
+	got := collectEvents(t, ParseFileParallel(strings.NewReader(in), &model.ParseContext{}, time.UTC, 3))
+	if len(want) != 3 || !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseFileParallel_LineTooLong(t *testing.T) {
+	withChunkSize(t, 1024)
+	in := "[Sat Jan 24 23:14:30 2026] You hit a gnoll for 5 points of damage.\n" + strings.Repeat("x", maxLineSize+1) + "\n"
+	it := ParseFileParallel(strings.NewReader(in), &model.ParseContext{}, time.UTC, 2)
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if n != 1 || !errors.Is(it.Err(), bufio.ErrTooLong) {
+		t.Fatalf("got %d events, err %v; want 1 event and ErrTooLong", n, it.Err())
+	}
+}
+
+func TestParseFileParallel_CloseEarly(t *testing.T) {
+	withChunkSize(t, 256)
+	line := "[Sat Jan 24 23:14:30 2026] You hit a gnoll for 5 points of damage.\n"
+	it := ParseFileParallel(strings.NewReader(strings.Repeat(line, 10000)), &model.ParseContext{}, time.UTC, 2)
+	if !it.Next() {
+		t.Fatalf("no first event: %v", it.Err())
+	}
+	it.Close()
+	it.Close()
+}
+
+func benchmarkParseFile(b *testing.B, parallel bool) {
+	var sb strings.Builder
+	for _, name := range testdataLogs {
+		for _, line := range readTestdataLines(b, name) {
+			sb.WriteString(line)
+			sb.WriteByte('\n')
+		}
+	}
+	data := sb.String()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx := &model.ParseContext{LocalActorName: "Emberval"}
+		var it *Iterator
+		if parallel {
+			it = ParseFileParallel(strings.NewReader(data), ctx, time.UTC, 0)
+		} else {
+			it = ParseFile(strings.NewReader(data), ctx, time.UTC)
+		}
+		for it.Next() {
+		}
+		if err := it.Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseFile_Sequential(b *testing.B) {
+	benchmarkParseFile(b, false)
+}
+
+func BenchmarkParseFile_Parallel(b *testing.B) {
+	benchmarkParseFile(b, true)
+}
@@ -3,7 +3,6 @@ package parse
 import (
 	"bufio"
 	"io"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -13,311 +12,122 @@ import (
 
 const tsLayout = "Mon Jan 02 15:04:05 2006"
 
-var (
-	reTimestamp = regexp.MustCompile(`^\[(?P<ts>[^\]]+)\]\s+(?P<msg>.*)$`)
-
-	reCritMetaActor = regexp.MustCompile(`^(?P<actor>.+?)\s+scores\s+a\s+critical\s+hit!\s*\((?P<val>\d+)\)$`)
-	reCritMetaYou   = regexp.MustCompile(`^You\s+deliver\s+a\s+critical\s+blast!\s*\((?P<val>\d+)\)$`)
-
-	reCastStart  = regexp.MustCompile(`^You\s+begin\s+casting\s+(?P<spell>.+?)\.$`)
-	reAffliction = regexp.MustCompile(`^(?P<target>.+?)\s+is\s+afflicted\s+by\s+(?P<spell>.+?)\.$`)
-
-	reHealTarget       = regexp.MustCompile(`^(?P<target>.+?)\s+has\s+been\s+healed\s+for\s+(?P<amt>\d+)\s+points\.$`)
-	reHealTargetDamage = regexp.MustCompile(`^(?P<target>.+?)\s+has\s+been\s+healed\s+for\s+(?P<amt>\d+)\s+points\s+of\s+damage\.$`)
-	reHealYou          = regexp.MustCompile(`^You\s+have\s+been\s+healed\s+for\s+(?P<amt>\d+)\s+points\.$`)
-	reHealYouDamage    = regexp.MustCompile(`^You\s+have\s+been\s+healed\s+for\s+(?P<amt>\d+)\s+points\s+of\s+damage\.$`)
-
-	reIncomingByNonMelee = regexp.MustCompile(`^You\s+have\s+taken\s+(?P<amt>\d+)\s+points\s+of\s+damage\s+by\s+non-melee\.$`)
-	reIncomingNonMelee   = regexp.MustCompile(`^You\s+have\s+taken\s+(?P<amt>\d+)\s+points\s+of\s+non-melee\s+damage\.$`)
-
-	reIncomingOnMelee = regexp.MustCompile(`^(?P<actor>.+?)\s+(?P<verb>hits|hit|bashes|bash|kicks|kick|crushes|crush|slashes|slash|pierces|pierce|punches|punch|strikes|strike)\s+on\s+(?P<target>YOU|[A-Z][a-zA-Z'\-]{2,15})\s+for\s+(?P<amt>\d+)\s+points\s+of\s+damage\.$`)
-
-	reThornsMarker = regexp.MustCompile(`^(?P<target>.+?)\s+was\s+pierced\s+by\s+thorns\.$`)
+// ParseLine parses one log line with the built-in rules.
+func ParseLine(ctx *model.ParseContext, line string, loc *time.Location) (model.Event, bool) {
+	return DefaultRules().ParseLine(ctx, line, loc)
+}
 
-	reNonMelee = regexp.MustCompile(`^(?P<actor>.+?)\s+hit\s+(?P<target>.+?)\s+for\s+(?P<amt>\d+)\s+points\s+of\s+non-melee\s+damage\.$`)
+// ParseLine parses one log line with the rule set. It reports false for lines without a
+// timestamp; lines no rule matches come back as KindUnknown.
+func (rs *RuleSet) ParseLine(ctx *model.ParseContext, line string, loc *time.Location) (model.Event, bool) {
+	pl, ok := rs.prepareLine(line, loc)
+	if !ok {
+		return model.Event{}, false
+	}
+	return rs.finishLine(ctx, &pl), true
+}
 
-	reYouMelee   = regexp.MustCompile(`^You\s+(?P<verb>\w+)\s+(?P<target>.+?)\s+for\s+(?P<amt>\d+)\s+points\s+of\s+damage\.$`)
-	reOtherMelee = regexp.MustCompile(`^(?P<actor>.+?)\s+(?P<verb>hits|hit|kicks|kick|bashes|bash|crushes|crush|slashes|slash|pierces|pierce|punches|punch|claws|claw|bites|bite|mauls|maul|strikes|strike|backstabs|backstab|frenzies|frenzy|rends|rend)\s+(?P<target>.+?)\s+for\s+(?P<amt>\d+)\s+points\s+of\s+damage\.$`)
+// LineTime returns the timestamp of a log line with the built-in rules' layout.
+func LineTime(line string, loc *time.Location) (time.Time, bool) {
+	return DefaultRules().LineTime(line, loc)
+}
 
-	reYouMiss     = regexp.MustCompile(`^You\s+try\s+to\s+(?P<verb>\w+)\s+(?P<target>.+?),\s+but\s+miss!$`)
-	reTryHitAvoid = regexp.MustCompile(`^(?P<actor>.+?)\s+tries\s+to\s+hit\s+(?P<target>.+?),\s+but\s+(?P<defender>.+?)\s+(?P<avoid>dodges|blocks|parries|ripostes|misses)!$`)
-	reTryVerbMiss = regexp.MustCompile(`^(?P<actor>.+?)\s+tries\s+to\s+(?P<verb>kick|bash|strike|slash|pierce|crush|hit)\s+(?P<target>.+?),\s+but\s+misses!$`)
+// LineTime returns the timestamp of a log line without matching its message. It accepts
+// exactly the lines ParseLine does, and does not correct for clock jumps.
+func (rs *RuleSet) LineTime(line string, loc *time.Location) (time.Time, bool) {
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	ts, _, ok := rs.splitLine(line, loc)
+	return ts, ok
+}
 
-	reAutoAttack = regexp.MustCompile(`^Auto\s+attack\s+is\s+(on|off)\.$`)
-)
+// A preparedLine is the part of parsing a line that does not touch the ParseContext: the
+// timestamp split and the first rule whose regex matches. Preparing is where the time
+// goes, so the parallel reader does it on workers and only finishes lines in order.
+type preparedLine struct {
+	ts  time.Time
+	raw string
+	msg string
+	mod model.AttackModifier
+	pos int // index of the matched rule in rs.candidates(msg), -1 for none
+	m   []int
+}
 
-func ParseLine(ctx *model.ParseContext, line string, loc *time.Location) (model.Event, bool) {
+func (rs *RuleSet) prepareLine(line string, loc *time.Location) (preparedLine, bool) {
 	if len(line) > 0 && line[len(line)-1] == '\r' {
 		line = line[:len(line)-1]
 	}
 	if line == "" {
-		return model.Event{}, false
+		return preparedLine{}, false
 	}
 	if loc == nil {
 		loc = time.Local
 	}
 
-	idx := reTimestamp.FindStringSubmatchIndex(line)
-	if idx == nil {
-		return model.Event{}, false
-	}
-	tsStr := reSub(line, idx, 1)
-	msg := reSub(line, idx, 2)
-
-	ts, err := time.ParseInLocation(tsLayout, tsStr, loc)
-	if err != nil {
-		return model.Event{}, false
+	ts, msg, ok := rs.splitLine(line, loc)
+	if !ok {
+		return preparedLine{}, false
 	}
+	pl := preparedLine{ts: ts, raw: line}
+	pl.msg, pl.mod = splitAttackModifier(msg)
+	pl.pos, pl.m = rs.firstMatch(pl.msg, 0)
+	return pl, true
+}
 
+// finishLine fills the event for a prepared line, running the rule's hooks and the name
+// and pet bookkeeping against ctx. Lines must be finished in log order.
+func (rs *RuleSet) finishLine(ctx *model.ParseContext, pl *preparedLine) model.Event {
 	if ctx != nil && ctx.PendingCrit != nil && ctx.PendingCrit.TTL <= 0 {
 		ctx.PendingCrit = nil
 	}
-
-	ev := model.Event{Timestamp: ts, Raw: line, Kind: model.KindUnknown}
-
-	if m := reCritMetaActor.FindStringSubmatchIndex(msg); m != nil {
-		actor := reSub(msg, m, reCritMetaActor.SubexpIndex("actor"))
-		valStr := reSub(msg, m, reCritMetaActor.SubexpIndex("val"))
-		val, ok := parseInt64(valStr)
-		if ok {
-			ev.Kind = model.KindCritMeta
-			if ctx != nil && ctx.LocalActorName != "" && actor == ctx.LocalActorName {
-				actor = "YOU"
-			}
-			ev.Actor = actor
-			ev.MetaInt = val
-			if ctx != nil {
-				ctx.PendingCrit = &model.PendingCrit{Actor: actor, Ts: ts, Value: val, TTL: 2}
-			}
-			return ev, true
-		}
-	}
-	if m := reCritMetaYou.FindStringSubmatchIndex(msg); m != nil {
-		valStr := reSub(msg, m, reCritMetaYou.SubexpIndex("val"))
-		val, ok := parseInt64(valStr)
-		if ok {
-			ev.Kind = model.KindCritMeta
-			ev.Actor = "YOU"
-			ev.MetaInt = val
-			if ctx != nil {
-				ctx.PendingCrit = &model.PendingCrit{Actor: "YOU", Ts: ts, Value: val, TTL: 2}
-			}
-			return ev, true
-		}
+	if ctx != nil && ctx.PendingHeal != nil && ctx.PendingHeal.TTL <= 0 {
+		ctx.PendingHeal = nil
 	}
 
-	if m := reCastStart.FindStringSubmatchIndex(msg); m != nil {
-		ev.Kind = model.KindCastStart
-		ev.Actor = "YOU"
-		ev.SpellOrSkill = reSub(msg, m, reCastStart.SubexpIndex("spell"))
-		return ev, true
-	}
-	if m := reAffliction.FindStringSubmatchIndex(msg); m != nil {
-		ev.Kind = model.KindAffliction
-		ev.Target = reSub(msg, m, reAffliction.SubexpIndex("target"))
-		ev.SpellOrSkill = reSub(msg, m, reAffliction.SubexpIndex("spell"))
-		return ev, true
-	}
-	if m := reThornsMarker.FindStringSubmatchIndex(msg); m != nil {
-		ev.Kind = model.KindThornsMarker
-		ev.Target = reSub(msg, m, reThornsMarker.SubexpIndex("target"))
-		ev.AmountKnown = false
-		return ev, true
-	}
-
-	if m := reHealTarget.FindStringSubmatchIndex(msg); m != nil {
-		target := reSub(msg, m, reHealTarget.SubexpIndex("target"))
-		amtStr := reSub(msg, m, reHealTarget.SubexpIndex("amt"))
-		amt, ok := parseInt64(amtStr)
-		if ok {
-			ev.Kind = model.KindHeal
-			ev.Target = target
-			ev.Amount = amt
-			ev.AmountKnown = true
-			return ev, true
-		}
-	}
-	if m := reHealTargetDamage.FindStringSubmatchIndex(msg); m != nil {
-		target := reSub(msg, m, reHealTargetDamage.SubexpIndex("target"))
-		amtStr := reSub(msg, m, reHealTargetDamage.SubexpIndex("amt"))
-		amt, ok := parseInt64(amtStr)
-		if ok {
-			ev.Kind = model.KindHeal
-			ev.Target = target
-			ev.Amount = amt
-			ev.AmountKnown = true
-			return ev, true
-		}
-	}
-	if m := reHealYou.FindStringSubmatchIndex(msg); m != nil {
-		amtStr := reSub(msg, m, reHealYou.SubexpIndex("amt"))
-		amt, ok := parseInt64(amtStr)
-		if ok {
-			ev.Kind = model.KindHeal
-			ev.Target = "YOU"
-			ev.Amount = amt
-			ev.AmountKnown = true
-			return ev, true
-		}
-	}
-	if m := reHealYouDamage.FindStringSubmatchIndex(msg); m != nil {
-		amtStr := reSub(msg, m, reHealYouDamage.SubexpIndex("amt"))
-		amt, ok := parseInt64(amtStr)
-		if ok {
-			ev.Kind = model.KindHeal
-			ev.Target = "YOU"
-			ev.Amount = amt
-			ev.AmountKnown = true
-			return ev, true
-		}
-	}
+	ts, jump := adjustClock(ctx, pl.ts)
+	ev := model.Event{Timestamp: ts, Raw: pl.raw, Kind: model.KindUnknown, Modifier: pl.mod, ClockJump: jump}
+	rs.match(ctx, pl.msg, &ev, pl.pos, pl.m)
 
-	if m := reIncomingByNonMelee.FindStringSubmatchIndex(msg); m != nil {
-		amtStr := reSub(msg, m, reIncomingByNonMelee.SubexpIndex("amt"))
-		amt, ok := parseInt64(amtStr)
-		if ok {
-			ev.Kind = model.KindIncomingDamage
-			ev.Target = "YOU"
-			ev.Amount = amt
-			ev.AmountKnown = true
-			ev.Verb = "non-melee"
-			return ev, true
-		}
-	}
-	if m := reIncomingNonMelee.FindStringSubmatchIndex(msg); m != nil {
-		amtStr := reSub(msg, m, reIncomingNonMelee.SubexpIndex("amt"))
-		amt, ok := parseInt64(amtStr)
-		if ok {
-			ev.Kind = model.KindIncomingDamage
-			ev.Target = "YOU"
-			ev.Amount = amt
-			ev.AmountKnown = true
-			ev.Verb = "non-melee"
-			return ev, true
-		}
-	}
-	if m := reIncomingOnMelee.FindStringSubmatchIndex(msg); m != nil {
-		actor := reSub(msg, m, reIncomingOnMelee.SubexpIndex("actor"))
-		verb := reSub(msg, m, reIncomingOnMelee.SubexpIndex("verb"))
-		target := reSub(msg, m, reIncomingOnMelee.SubexpIndex("target"))
-		amtStr := reSub(msg, m, reIncomingOnMelee.SubexpIndex("amt"))
-		amt, ok := parseInt64(amtStr)
-		if ok {
-			ev.Kind = model.KindIncomingDamage
-			if ctx != nil && ctx.LocalActorName != "" && actor == ctx.LocalActorName {
-				actor = "YOU"
-			}
-			ev.Actor = actor
-			ev.Target = target
-			ev.Verb = verb
-			ev.Amount = amt
-			ev.AmountKnown = true
-			return ev, true
-		}
+	normalizeNames(ctx, &ev)
+	resolvePets(&ev)
+	if ctx != nil {
+		ev.Zone = ctx.Zone
 	}
+	return ev
+}
 
-	if m := reNonMelee.FindStringSubmatchIndex(msg); m != nil {
-		actor := reSub(msg, m, reNonMelee.SubexpIndex("actor"))
-		target := reSub(msg, m, reNonMelee.SubexpIndex("target"))
-		amtStr := reSub(msg, m, reNonMelee.SubexpIndex("amt"))
-		amt, ok := parseInt64(amtStr)
-		if ok {
-			ev.Kind = model.KindNonMeleeDamage
-			if ctx != nil && ctx.LocalActorName != "" && actor == ctx.LocalActorName {
-				actor = "YOU"
-			}
-			ev.Actor = actor
-			ev.Target = target
-			ev.Amount = amt
-			ev.AmountKnown = true
-			ev.DamageClass = model.DamageClassDirect
-			handlePendingCrit(ctx, &ev)
-			return ev, true
-		}
-	}
-	if m := reYouMelee.FindStringSubmatchIndex(msg); m != nil {
-		ev.Kind = model.KindMeleeDamage
-		ev.Actor = "YOU"
-		ev.Verb = reSub(msg, m, reYouMelee.SubexpIndex("verb"))
-		ev.Target = reSub(msg, m, reYouMelee.SubexpIndex("target"))
-		amtStr := reSub(msg, m, reYouMelee.SubexpIndex("amt"))
-		amt, ok := parseInt64(amtStr)
-		if ok {
-			ev.Amount = amt
-			ev.AmountKnown = true
-			applyDamageClass(&ev)
-			handlePendingCrit(ctx, &ev)
-			return ev, true
-		}
-	}
-	if m := reOtherMelee.FindStringSubmatchIndex(msg); m != nil {
-		ev.Kind = model.KindMeleeDamage
-		actor := reSub(msg, m, reOtherMelee.SubexpIndex("actor"))
-		if ctx != nil && ctx.LocalActorName != "" && actor == ctx.LocalActorName {
-			actor = "YOU"
-		}
-		ev.Actor = actor
-		ev.Verb = reSub(msg, m, reOtherMelee.SubexpIndex("verb"))
-		ev.Target = reSub(msg, m, reOtherMelee.SubexpIndex("target"))
-		amtStr := reSub(msg, m, reOtherMelee.SubexpIndex("amt"))
-		amt, ok := parseInt64(amtStr)
-		if ok {
-			ev.Amount = amt
-			ev.AmountKnown = true
-			applyDamageClass(&ev)
-			handlePendingCrit(ctx, &ev)
-			return ev, true
-		}
+// splitAttackModifier strips the "(Rampage)" / "(Wild Rampage)" suffix that follows
+// special-attack hits, e.g. "Oshiruk hits YOU for 812 points of damage. (Wild Rampage)".
+func splitAttackModifier(msg string) (string, model.AttackModifier) {
+	if !strings.HasSuffix(msg, ")") {
+		return msg, model.ModifierNone
 	}
-
-	if m := reYouMiss.FindStringSubmatchIndex(msg); m != nil {
-		ev.Kind = model.KindMiss
-		ev.Actor = "YOU"
-		ev.Verb = reSub(msg, m, reYouMiss.SubexpIndex("verb"))
-		ev.Target = reSub(msg, m, reYouMiss.SubexpIndex("target"))
-		handlePendingCrit(ctx, &ev)
-		return ev, true
-	}
-	if m := reTryHitAvoid.FindStringSubmatchIndex(msg); m != nil {
-		actor := reSub(msg, m, reTryHitAvoid.SubexpIndex("actor"))
-		target := reSub(msg, m, reTryHitAvoid.SubexpIndex("target"))
-		avoid := reSub(msg, m, reTryHitAvoid.SubexpIndex("avoid"))
-		if ctx != nil && ctx.LocalActorName != "" && actor == ctx.LocalActorName {
-			actor = "YOU"
-		}
-		ev.Actor = actor
-		ev.Target = target
-		ev.Verb = avoid
-		if avoid == "misses" {
-			ev.Kind = model.KindMiss
-		} else {
-			ev.Kind = model.KindAvoid
-		}
-		handlePendingCrit(ctx, &ev)
-		return ev, true
-	}
-	if m := reTryVerbMiss.FindStringSubmatchIndex(msg); m != nil {
-		ev.Kind = model.KindMiss
-		actor := reSub(msg, m, reTryVerbMiss.SubexpIndex("actor"))
-		if ctx != nil && ctx.LocalActorName != "" && actor == ctx.LocalActorName {
-			actor = "YOU"
-		}
-		ev.Actor = actor
-		ev.Verb = reSub(msg, m, reTryVerbMiss.SubexpIndex("verb"))
-		ev.Target = reSub(msg, m, reTryVerbMiss.SubexpIndex("target"))
-		handlePendingCrit(ctx, &ev)
-		return ev, true
+	if rest, ok := strings.CutSuffix(msg, " (Wild Rampage)"); ok {
+		return rest, model.ModifierWildRampage
 	}
-
-	if m := reAutoAttack.FindStringSubmatchIndex(msg); m != nil {
-		ev.Kind = model.KindZoneOrSystem
-		ev.SpellOrSkill = "auto_attack"
-		ev.Verb = reSub(msg, m, 1)
-		handlePendingCrit(ctx, &ev)
-		return ev, true
+	if rest, ok := strings.CutSuffix(msg, " (Rampage)"); ok {
+		return rest, model.ModifierRampage
 	}
+	return msg, model.ModifierNone
+}
 
-	return ev, true
+// avoidType maps the defence word of "..., but YOU dodge!" / "..., but Sigdis parries!".
+func avoidType(word string) model.AvoidType {
+	switch word {
+	case "dodge", "dodges":
+		return model.AvoidDodge
+	case "parry", "parries":
+		return model.AvoidParry
+	case "riposte", "ripostes":
+		return model.AvoidRiposte
+	case "block", "blocks":
+		return model.AvoidBlock
+	}
+	return model.AvoidNone
 }
 
 func applyDamageClass(ev *model.Event) {
@@ -346,21 +156,37 @@ func applyDamageClass(ev *model.Event) {
 }
 
 func ParseFile(r io.Reader, ctx *model.ParseContext, loc *time.Location) *Iterator {
-	return &Iterator{r: r, ctx: ctx, loc: loc}
+	return DefaultRules().ParseFile(r, ctx, loc)
+}
+
+func (rs *RuleSet) ParseFile(r io.Reader, ctx *model.ParseContext, loc *time.Location) *Iterator {
+	return &Iterator{r: r, rules: rs, ctx: ctx, loc: loc}
 }
 
 type Iterator struct {
-	r   io.Reader
-	s   *bufio.Scanner
-	err error
-	ctx *model.ParseContext
-	loc *time.Location
+	r     io.Reader
+	s     *bufio.Scanner
+	err   error
+	rules *RuleSet
+	ctx   *model.ParseContext
+	loc   *time.Location
+	par   *parallelReader
 
 	cur model.Event
 	ok  bool
+	// next is a line's event held back while the clock jump event ahead of it is yielded.
+	next    model.Event
+	hasNext bool
 }
 
 func (it *Iterator) Next() bool {
+	if it.hasNext {
+		it.cur, it.hasNext = it.next, false
+		return true
+	}
+	if it.par != nil {
+		return it.nextParallel()
+	}
 	if it.s == nil {
 		it.s = bufio.NewScanner(it.r)
 		// allow long lines
@@ -370,12 +196,11 @@ func (it *Iterator) Next() bool {
 
 	for it.s.Scan() {
 		line := it.s.Text()
-		e, ok := ParseLine(it.ctx, line, it.loc)
+		e, ok := it.rules.ParseLine(it.ctx, line, it.loc)
 		if !ok {
 			continue
 		}
-		it.cur = e
-		it.ok = true
+		it.yield(e)
 		return true
 	}
 	it.err = it.s.Err()
@@ -383,9 +208,39 @@ func (it *Iterator) Next() bool {
 	return false
 }
 
+func (it *Iterator) nextParallel() bool {
+	pl, err := it.par.next()
+	if pl == nil {
+		it.err = err
+		it.ok = false
+		it.par.close()
+		return false
+	}
+	it.yield(it.rules.finishLine(it.ctx, pl))
+	return true
+}
+
+// yield makes ev the current event, or the clock jump event ahead of it when there is one.
+func (it *Iterator) yield(ev model.Event) {
+	it.ok = true
+	if jump, ok := ClockJumpEvent(ev); ok {
+		it.cur, it.next, it.hasNext = jump, ev, true
+		return
+	}
+	it.cur = ev
+}
+
 func (it *Iterator) Event() model.Event { return it.cur }
 func (it *Iterator) Err() error         { return it.err }
 
+// Close stops a parallel iterator's reader and workers. It is not needed once Next has
+// returned false, and is a no-op for sequential iterators.
+func (it *Iterator) Close() {
+	if it.par != nil {
+		it.par.close()
+	}
+}
+
 func handlePendingCrit(ctx *model.ParseContext, ev *model.Event) {
 	if ctx == nil || ctx.PendingCrit == nil {
 		return
@@ -403,10 +258,11 @@ func handlePendingCrit(ctx *model.ParseContext, ev *model.Event) {
 	if ev.Actor != pc.Actor {
 		return
 	}
-	if ev.Kind != model.KindMeleeDamage && ev.Kind != model.KindNonMeleeDamage {
+	if ev.Kind != model.KindMeleeDamage && ev.Kind != model.KindNonMeleeDamage && ev.Kind != model.KindIncomingDamage {
 		return
 	}
 	ev.Crit = true
+	ev.CritType = pc.Type
 	ev.MetaInt = pc.Value
 	pc.TTL--
 	if pc.TTL <= 0 {
@@ -414,6 +270,95 @@ func handlePendingCrit(ctx *model.ParseContext, ev *model.Event) {
 	}
 }
 
+// handlePendingHeal marks a heal as exceptional when it follows the healer's
+// "performs an exceptional heal!" meta line, mirroring handlePendingCrit.
+func handlePendingHeal(ctx *model.ParseContext, ev *model.Event) {
+	if ctx == nil || ctx.PendingHeal == nil {
+		return
+	}
+	pc := ctx.PendingHeal
+	if pc.TTL <= 0 {
+		ctx.PendingHeal = nil
+		return
+	}
+	dt := ev.Timestamp.Sub(pc.Ts)
+	if dt < 0 || dt > 1*time.Second {
+		ctx.PendingHeal = nil
+		return
+	}
+	if ev.Actor != pc.Actor || ev.Kind != model.KindHeal {
+		return
+	}
+	ev.Crit = true
+	ev.CritType = pc.Type
+	ev.MetaInt = pc.Value
+	pc.TTL--
+	if pc.TTL <= 0 {
+		ctx.PendingHeal = nil
+	}
+}
+
+func notePendingFlurry(ctx *model.ParseContext, ev *model.Event) {
+	if ctx == nil {
+		return
+	}
+	ctx.PendingFlurry = &model.PendingFlurry{Actor: ev.Actor, Target: ev.Target, Ts: ev.Timestamp, TTL: 2}
+}
+
+// handlePendingFlurry tags the swings that follow a flurry announcement. Flurry hits
+// carry no suffix of their own, so the marker is the only signal. Misses and avoids
+// consume the window like hits do.
+func handlePendingFlurry(ctx *model.ParseContext, ev *model.Event) {
+	if ctx == nil || ctx.PendingFlurry == nil {
+		return
+	}
+	pf := ctx.PendingFlurry
+	dt := ev.Timestamp.Sub(pf.Ts)
+	if pf.TTL <= 0 || dt < 0 || dt > 1*time.Second {
+		ctx.PendingFlurry = nil
+		return
+	}
+	if ev.Actor != pf.Actor || (pf.Target != "" && ev.Target != pf.Target) {
+		return
+	}
+	if ev.Modifier == model.ModifierNone {
+		ev.Modifier = model.ModifierFlurry
+	}
+	pf.TTL--
+	if pf.TTL <= 0 {
+		ctx.PendingFlurry = nil
+	}
+}
+
+func localActor(ctx *model.ParseContext, actor string) string {
+	if actor == "You" {
+		return "YOU"
+	}
+	if ctx != nil && ctx.LocalActorName != "" && actor == ctx.LocalActorName {
+		return "YOU"
+	}
+	return actor
+}
+
+// currentTarget is the local player's target from the last "Targeted (...)" line, for
+// lines that only say "your target".
+func currentTarget(ctx *model.ParseContext) string {
+	if ctx == nil {
+		return ""
+	}
+	return ctx.Target
+}
+
+func healTargetName(target string, healer string) string {
+	switch strings.ToLower(target) {
+	case "you", "yourself":
+		return "YOU"
+	case "himself", "herself", "itself", "themselves":
+		return healer
+	}
+	return target
+}
+
 func reSub(s string, idx []int, group int) string {
 	if group <= 0 {
 		return ""
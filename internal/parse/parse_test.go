@@ -383,3 +383,699 @@ func TestParseLine_TimestampParses(t *testing.T) {
 	}
 	_ = time.Local
 }
+
+func TestParseLine_Death_SlainBy(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Sigdis"}
+	line := "[Sat Jan 24 23:14:06 2026] A Crocodile has been slain by Sigdis!"
+	ev, ok := ParseLine(ctx, line, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Kind != model.KindDeath {
+		t.Fatalf("kind=%v", ev.Kind)
+	}
+	if ev.Actor != "YOU" || ev.Target != "A Crocodile" {
+		t.Fatalf("actor/target=%q/%q", ev.Actor, ev.Target)
+	}
+	if ev.AmountKnown {
+		t.Fatalf("expected unknown amount")
+	}
+}
+
+func TestParseLine_Death_SlainBy_DoubleSpace(t *testing.T) {
+	line := "[Thu Jan 29 21:55:00 2026] Lord Hydrerious  has been slain by Sigdis!"
+	ev, ok := ParseLine(nil, line, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Kind != model.KindDeath {
+		t.Fatalf("kind=%v", ev.Kind)
+	}
+	if ev.Actor != "Sigdis" || ev.Target != "Lord Hydrerious" {
+		t.Fatalf("actor/target=%q/%q", ev.Actor, ev.Target)
+	}
+}
+
+func TestParseLine_Death_YouHaveSlain(t *testing.T) {
+	line := "[Sat Jan 24 23:18:10 2026] You have slain Lord Soth`s pet!"
+	ev, ok := ParseLine(nil, line, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Kind != model.KindDeath {
+		t.Fatalf("kind=%v", ev.Kind)
+	}
+	if ev.Actor != "YOU" || ev.Target != "Lord Soth`s pet" {
+		t.Fatalf("actor/target=%q/%q", ev.Actor, ev.Target)
+	}
+}
+
+func TestParseLine_Death_YouHaveBeenSlainBy(t *testing.T) {
+	line := "[Sat Jan 24 23:18:10 2026] You have been slain by Lord Soth!"
+	ev, ok := ParseLine(nil, line, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Kind != model.KindDeath {
+		t.Fatalf("kind=%v", ev.Kind)
+	}
+	if ev.Actor != "Lord Soth" || ev.Target != "YOU" {
+		t.Fatalf("actor/target=%q/%q", ev.Actor, ev.Target)
+	}
+}
+
+func TestParseLine_DoTTick_AttributedToLocalCaster(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Emberval"}
+	lines := []string{
+		"[Fri Jan 23 07:47:01 2026] You begin casting Bite of the Shissar Poison VII.",
+		"[Fri Jan 23 07:47:03 2026] A training dummy is afflicted by poison.",
+	}
+	var aff model.Event
+	for _, l := range lines {
+		ev, ok := ParseLine(ctx, l, time.Local)
+		if !ok {
+			t.Fatalf("expected ok for %q", l)
+		}
+		aff = ev
+	}
+	// The affliction names only the resist type; it carries the cast's spell name.
+	if aff.Actor != "YOU" || aff.SpellOrSkill != "Bite of the Shissar Poison VII" {
+		t.Fatalf("affliction actor/spell=%q/%q", aff.Actor, aff.SpellOrSkill)
+	}
+	line := "[Fri Jan 23 07:47:09 2026] A training dummy was hit by non-melee for 812 points of damage."
+	ev, ok := ParseLine(ctx, line, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Kind != model.KindNonMeleeDamage {
+		t.Fatalf("kind=%v", ev.Kind)
+	}
+	if ev.DamageClass != model.DamageClassDoT {
+		t.Fatalf("damageClass=%v", ev.DamageClass)
+	}
+	if ev.Actor != "YOU" || ev.Target != "A training dummy" {
+		t.Fatalf("actor/target=%q/%q", ev.Actor, ev.Target)
+	}
+	if ev.SpellOrSkill != "Bite of the Shissar Poison VII" {
+		t.Fatalf("spell=%q", ev.SpellOrSkill)
+	}
+	if ev.Amount != 812 || !ev.AmountKnown {
+		t.Fatalf("amount=%d known=%v", ev.Amount, ev.AmountKnown)
+	}
+
+	// A different target has no DoT marker.
+	other := "[Fri Jan 23 07:47:10 2026] Lord Soth was hit by non-melee for 35 points of damage."
+	ev, ok = ParseLine(ctx, other, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Actor != UnattributedDoTActor || ev.Target != "Lord Soth" {
+		t.Fatalf("actor/target=%q/%q", ev.Actor, ev.Target)
+	}
+}
+
+func TestParseLine_DoTTick_Unattributed(t *testing.T) {
+	line := "[Sat Jan 24 23:13:59 2026] A Crocodile was hit by non-melee for 35 points of damage."
+	ev, ok := ParseLine(&model.ParseContext{}, line, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Kind != model.KindNonMeleeDamage {
+		t.Fatalf("kind=%v", ev.Kind)
+	}
+	if ev.Actor != UnattributedDoTActor || ev.Target != "A Crocodile" {
+		t.Fatalf("actor/target=%q/%q", ev.Actor, ev.Target)
+	}
+}
+
+func TestParseLine_DoTTick_ExpiresAfterTTL(t *testing.T) {
+	ctx := &model.ParseContext{}
+	_, _ = ParseLine(ctx, "[Fri Jan 23 07:47:01 2026] You begin casting Bite of the Shissar Poison VII.", time.Local)
+	_, _ = ParseLine(ctx, "[Fri Jan 23 07:47:03 2026] A training dummy is afflicted by poison.", time.Local)
+	ev, _ := ParseLine(ctx, "[Fri Jan 23 07:55:00 2026] A training dummy was hit by non-melee for 812 points of damage.", time.Local)
+	if ev.Actor != UnattributedDoTActor {
+		t.Fatalf("actor=%q want=%q", ev.Actor, UnattributedDoTActor)
+	}
+}
+
+func TestParseLine_DoTTick_OtherCastersAfflictionNotCredited(t *testing.T) {
+	ctx := &model.ParseContext{}
+	_, _ = ParseLine(ctx, "[Fri Jan 23 07:47:01 2026] You begin casting Sanity Warp.", time.Local)
+	// Someone else's poison lands while our cast is pending.
+	ev, _ := ParseLine(ctx, "[Fri Jan 23 07:47:02 2026] A training dummy is afflicted by poison.", time.Local)
+	if ev.Actor != "" {
+		t.Fatalf("affliction actor=%q", ev.Actor)
+	}
+	ev, _ = ParseLine(ctx, "[Fri Jan 23 07:47:05 2026] A training dummy was hit by non-melee for 812 points of damage.", time.Local)
+	if ev.Actor != UnattributedDoTActor {
+		t.Fatalf("tick actor=%q want=%q", ev.Actor, UnattributedDoTActor)
+	}
+
+	// A matched cast is consumed, so a second poison landing elsewhere is not ours.
+	_, _ = ParseLine(ctx, "[Fri Jan 23 07:48:01 2026] You begin casting Bite of the Shissar Poison VII.", time.Local)
+	_, _ = ParseLine(ctx, "[Fri Jan 23 07:48:02 2026] A training dummy is afflicted by poison.", time.Local)
+	_, _ = ParseLine(ctx, "[Fri Jan 23 07:48:03 2026] DPS Machine is afflicted by poison.", time.Local)
+	ev, _ = ParseLine(ctx, "[Fri Jan 23 07:48:06 2026] DPS Machine was hit by non-melee for 500 points of damage.", time.Local)
+	if ev.Actor != UnattributedDoTActor {
+		t.Fatalf("second target tick actor=%q", ev.Actor)
+	}
+	ev, _ = ParseLine(ctx, "[Fri Jan 23 07:48:06 2026] A training dummy was hit by non-melee for 500 points of damage.", time.Local)
+	if ev.Actor != "YOU" || ev.SpellOrSkill != "Bite of the Shissar Poison VII" {
+		t.Fatalf("tick actor=%q spell=%q", ev.Actor, ev.SpellOrSkill)
+	}
+}
+
+func TestParseLine_TakenDamageFromBySpell(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Danser"}
+	line := "[Sat Jan 31 21:15:02 2026] Oshiruk has taken 4210 damage from Danser by Venom of the Snake."
+	ev, ok := ParseLine(ctx, line, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Kind != model.KindNonMeleeDamage || ev.DamageClass != model.DamageClassDoT {
+		t.Fatalf("kind=%v damageClass=%v", ev.Kind, ev.DamageClass)
+	}
+	if ev.Actor != "YOU" || ev.Target != "Oshiruk" || ev.SpellOrSkill != "Venom of the Snake" {
+		t.Fatalf("actor/target/spell=%q/%q/%q", ev.Actor, ev.Target, ev.SpellOrSkill)
+	}
+	if ev.Amount != 4210 || !ev.AmountKnown {
+		t.Fatalf("amount=%d known=%v", ev.Amount, ev.AmountKnown)
+	}
+
+	you := "[Sat Jan 31 21:15:03 2026] You have taken 900 damage from Oshiruk by Tendrils of Oshiruk"
+	ev, ok = ParseLine(ctx, you, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Kind != model.KindIncomingDamage {
+		t.Fatalf("kind=%v", ev.Kind)
+	}
+	if ev.Actor != "Oshiruk" || ev.Target != "YOU" || ev.SpellOrSkill != "Tendrils of Oshiruk" {
+		t.Fatalf("actor/target/spell=%q/%q/%q", ev.Actor, ev.Target, ev.SpellOrSkill)
+	}
+}
+
+func TestParseLine_Heal_HasHealedYou(t *testing.T) {
+	line := "[Sat Jan 31 21:14:56 2026] Sigdis has healed you for 3854 points."
+	ev, ok := ParseLine(nil, line, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Kind != model.KindHeal {
+		t.Fatalf("kind=%v", ev.Kind)
+	}
+	if ev.Actor != "Sigdis" || ev.Target != "YOU" {
+		t.Fatalf("actor/target=%q/%q", ev.Actor, ev.Target)
+	}
+	if ev.Amount != 3854 || !ev.AmountKnown {
+		t.Fatalf("amount=%d known=%v", ev.Amount, ev.AmountKnown)
+	}
+	if ev.Crit {
+		t.Fatalf("unexpected crit")
+	}
+}
+
+func TestParseLine_Heal_HealedBySpell(t *testing.T) {
+	line := "[Sat Jan 31 21:15:10 2026] Sigdis healed you for 17455 hit points by Elixir of Atonement Rk. III."
+	ev, ok := ParseLine(nil, line, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Kind != model.KindHeal {
+		t.Fatalf("kind=%v", ev.Kind)
+	}
+	if ev.Actor != "Sigdis" || ev.Target != "YOU" {
+		t.Fatalf("actor/target=%q/%q", ev.Actor, ev.Target)
+	}
+	if ev.SpellOrSkill != "Elixir of Atonement Rk. III" {
+		t.Fatalf("spell=%q", ev.SpellOrSkill)
+	}
+	if ev.Amount != 17455 {
+		t.Fatalf("amount=%d", ev.Amount)
+	}
+}
+
+func TestParseLine_Heal_YouHaveHealed(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Danser"}
+	line := "[Sat Jan 31 21:15:10 2026] You have healed Sigdis for 2210 points."
+	ev, ok := ParseLine(ctx, line, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if ev.Kind != model.KindHeal {
+		t.Fatalf("kind=%v", ev.Kind)
+	}
+	if ev.Actor != "YOU" || ev.Target != "Sigdis" {
+		t.Fatalf("actor/target=%q/%q", ev.Actor, ev.Target)
+	}
+}
+
+func TestParseLine_Heal_ExceptionalHealAssociation(t *testing.T) {
+	ctx := &model.ParseContext{}
+	meta := "[Sat Jan 31 21:14:56 2026] Sigdis performs an exceptional heal! (72250)"
+	mev, ok := ParseLine(ctx, meta, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if mev.Kind != model.KindCritMeta || mev.Actor != "Sigdis" || mev.MetaInt != 72250 {
+		t.Fatalf("meta kind/actor/metaint=%v/%q/%d", mev.Kind, mev.Actor, mev.MetaInt)
+	}
+
+	// A damage line from the same actor must not consume the heal meta.
+	dmg := "[Sat Jan 31 21:14:56 2026] Sigdis hit Oshiruk for 100 points of non-melee damage."
+	dev, _ := ParseLine(ctx, dmg, time.Local)
+	if dev.Crit {
+		t.Fatalf("damage unexpectedly marked crit")
+	}
+
+	heal := "[Sat Jan 31 21:14:56 2026] Sigdis has healed you for 3854 points."
+	ev, ok := ParseLine(ctx, heal, time.Local)
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if !ev.Crit {
+		t.Fatalf("expected crit heal")
+	}
+	if ev.MetaInt != 72250 {
+		t.Fatalf("metaint=%d", ev.MetaInt)
+	}
+}
+
+func TestParseLine_RampageSuffix(t *testing.T) {
+	cases := []struct {
+		line   string
+		kind   model.EventKind
+		target string
+		mod    model.AttackModifier
+	}{
+		{"[Sat Jan 31 21:15:20 2026] Oshiruk hits YOU for 812 points of damage. (Wild Rampage)", model.KindIncomingDamage, "YOU", model.ModifierWildRampage},
+		{"[Sat Jan 31 21:15:21 2026] Fallen Knight of Soth hits Sigdis for 1203 points of damage. (Rampage)", model.KindMeleeDamage, "Sigdis", model.ModifierRampage},
+		{"[Sat Jan 31 21:15:22 2026] Oshiruk hits YOU for 640 points of damage.", model.KindIncomingDamage, "YOU", model.ModifierNone},
+	}
+	for _, c := range cases {
+		ev, ok := ParseLine(&model.ParseContext{}, c.line, time.Local)
+		if !ok {
+			t.Fatalf("expected ok: %s", c.line)
+		}
+		if ev.Kind != c.kind || !ev.AmountKnown {
+			t.Fatalf("kind=%v known=%v: %s", ev.Kind, ev.AmountKnown, c.line)
+		}
+		if ev.Target != c.target || ev.Modifier != c.mod {
+			t.Fatalf("target=%q modifier=%v: %s", ev.Target, ev.Modifier, c.line)
+		}
+	}
+}
+
+func TestParseLine_SpecialAttackMarkers(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	ev, _ := ParseLine(ctx, "[Thu Jan 29 21:54:14 2026] Lord Hydrerious goes on a WILD RAMPAGE!", time.Local)
+	if ev.Kind != model.KindSpecialAttack || ev.Actor != "Lord Hydrerious" || ev.Modifier != model.ModifierWildRampage {
+		t.Fatalf("wild rampage kind=%v actor=%q modifier=%v", ev.Kind, ev.Actor, ev.Modifier)
+	}
+	ev, _ = ParseLine(ctx, "[Thu Jan 29 21:54:19 2026] Lord Hydrerious goes on a RAMPAGE!", time.Local)
+	if ev.Kind != model.KindSpecialAttack || ev.Modifier != model.ModifierRampage {
+		t.Fatalf("rampage kind=%v modifier=%v", ev.Kind, ev.Modifier)
+	}
+
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:16:23 2026] Sharp Tooth executes a FLURRY of attacks on Genaenyu!", time.Local)
+	if ev.Kind != model.KindSpecialAttack || ev.Actor != "Sharp Tooth" || ev.Target != "YOU" || ev.Modifier != model.ModifierFlurry {
+		t.Fatalf("flurry kind=%v actor=%q target=%q modifier=%v", ev.Kind, ev.Actor, ev.Target, ev.Modifier)
+	}
+	// Unrelated lines in between do not consume the flurry.
+	ParseLine(ctx, "[Sat Jan 24 23:16:23 2026] Sharp Tooth was pierced by thorns.", time.Local)
+	ParseLine(ctx, "[Sat Jan 24 23:16:23 2026] Genaenyu hits Sharp Tooth for 100 points of damage.", time.Local)
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:16:23 2026] Sharp Tooth hits YOU for 957 points of damage.", time.Local)
+	if ev.Kind != model.KindIncomingDamage || ev.Modifier != model.ModifierFlurry {
+		t.Fatalf("flurry hit kind=%v modifier=%v", ev.Kind, ev.Modifier)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:16:23 2026] Sharp Tooth hits YOU for 812 points of damage.", time.Local)
+	if ev.Modifier != model.ModifierFlurry {
+		t.Fatalf("second flurry hit modifier=%v", ev.Modifier)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:16:23 2026] Sharp Tooth hits YOU for 640 points of damage.", time.Local)
+	if ev.Modifier != model.ModifierNone {
+		t.Fatalf("flurry should expire after two swings, modifier=%v", ev.Modifier)
+	}
+
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:00 2026] You unleash a flurry of attacks.", time.Local)
+	if ev.Kind != model.KindSpecialAttack || ev.Actor != "YOU" || ev.Modifier != model.ModifierFlurry {
+		t.Fatalf("own flurry kind=%v actor=%q modifier=%v", ev.Kind, ev.Actor, ev.Modifier)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:00 2026] You pierce A Crocodile for 1395 points of damage.", time.Local)
+	if ev.Kind != model.KindMeleeDamage || ev.Modifier != model.ModifierFlurry {
+		t.Fatalf("own flurry hit kind=%v modifier=%v", ev.Kind, ev.Modifier)
+	}
+}
+
+func TestParseLine_PetNames(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	ev, _ := ParseLine(ctx, "[Sat Jan 24 23:17:40 2026] Lord Soth hits YOU for 1203 points of damage.", time.Local)
+	if ev.ActorOwner != "" {
+		t.Fatalf("unexpected owner %q", ev.ActorOwner)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:17:54 2026] Genaenyu hit LordSoth`s pet for 12 points of non-melee damage.", time.Local)
+	if ev.Actor != "YOU" || ev.Target != "Lord Soth`s pet" {
+		t.Fatalf("actor=%q target=%q", ev.Actor, ev.Target)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:17:56 2026] LordSoth`s pet was hit by non-melee for 35 points of damage.", time.Local)
+	if ev.Target != "Lord Soth`s pet" {
+		t.Fatalf("dot target=%q", ev.Target)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:17:56 2026] Lord Soth`s pet hits Sigdis for 244 points of damage.", time.Local)
+	if ev.Actor != "Lord Soth`s pet" || ev.ActorOwner != "Lord Soth" {
+		t.Fatalf("actor=%q owner=%q", ev.Actor, ev.ActorOwner)
+	}
+
+	// Unknown spaceless owners are left alone.
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:18:00 2026] Karca`s warder hits a rat for 300 points of damage.", time.Local)
+	if ev.Actor != "Karca`s warder" || ev.ActorOwner != "Karca" {
+		t.Fatalf("warder actor=%q owner=%q", ev.Actor, ev.ActorOwner)
+	}
+}
+
+func TestParseLine_NameNormalisation(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	ev, _ := ParseLine(ctx, "[Thu Jan 29 21:55:00 2026] You try to pierce Lord Hydrerious , but miss!", time.Local)
+	if ev.Target != "Lord Hydrerious" {
+		t.Fatalf("target=%q", ev.Target)
+	}
+	ev, _ = ParseLine(ctx, "[Thu Jan 29 21:55:00 2026] A training dummy tries to hit YOU, but misses!", time.Local)
+	if ev.Actor != "A training dummy" {
+		t.Fatalf("actor=%q", ev.Actor)
+	}
+	for i := 0; i < 2; i++ {
+		ev, _ = ParseLine(ctx, "[Thu Jan 29 21:55:01 2026] You pierce a training dummy for 100 points of damage.", time.Local)
+		if ev.Target != "A training dummy" {
+			t.Fatalf("case variant target=%q", ev.Target)
+		}
+	}
+	if d := ctx.Names.Display["A training dummy"]; d != "a training dummy" {
+		t.Fatalf("display=%q want the more frequent spelling", d)
+	}
+
+	// A spaceless variant seen first stays canonical, but displays with spaces.
+	ParseLine(ctx, "[Thu Jan 29 21:55:02 2026] Sigdis hit BigRat`s pet for 10 points of non-melee damage.", time.Local)
+	ev, _ = ParseLine(ctx, "[Thu Jan 29 21:55:03 2026] Sigdis hit Big Rat`s pet for 10 points of non-melee damage.", time.Local)
+	if ev.Target != "BigRat`s pet" || ctx.Names.Display["BigRat`s pet"] != "Big Rat`s pet" {
+		t.Fatalf("target=%q display=%q", ev.Target, ctx.Names.Display["BigRat`s pet"])
+	}
+}
+
+func TestParseLine_HostileSwingOnYouIsIncoming(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+	lines := []string{
+		"[Sat Jan 24 23:14:02 2026] A Crocodile hits YOU for 1203 points of damage.",
+		"[Sat Jan 24 23:19:02 2026] Lord Soth frenzies on YOU for 60000 points of damage.",
+	}
+	for _, line := range lines {
+		ev, ok := ParseLine(ctx, line, time.Local)
+		if !ok {
+			t.Fatalf("expected ok: %s", line)
+		}
+		if ev.Kind != model.KindIncomingDamage || ev.Target != "YOU" || !ev.AmountKnown {
+			t.Fatalf("kind=%v target=%q known=%v: %s", ev.Kind, ev.Target, ev.AmountKnown, line)
+		}
+	}
+}
+
+func TestParseLine_CritTypes(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	ev, _ := ParseLine(ctx, "[Sat Jan 24 23:14:05 2026] You strike through your opponent's defenses!", time.Local)
+	if ev.Kind != model.KindCritMeta || ev.CritType != model.CritStrikethrough || ev.Actor != "YOU" {
+		t.Fatalf("strikethrough kind=%v critType=%v actor=%q", ev.Kind, ev.CritType, ev.Actor)
+	}
+
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:05 2026] Genaenyu lands a Crippling Blow!(9471)", time.Local)
+	if ev.Kind != model.KindCritMeta || ev.CritType != model.CritCrippling || ev.Actor != "YOU" || ev.MetaInt != 9471 {
+		t.Fatalf("crippling kind=%v critType=%v actor=%q meta=%d", ev.Kind, ev.CritType, ev.Actor, ev.MetaInt)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:05 2026] You pierce A Crocodile for 9581 points of damage.", time.Local)
+	if !ev.Crit || ev.CritType != model.CritCrippling {
+		t.Fatalf("hit crit=%v critType=%v", ev.Crit, ev.CritType)
+	}
+
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:05 2026] Genaenyu scores a critical hit! (3654)", time.Local)
+	if ev.CritType != model.CritNormal {
+		t.Fatalf("critical hit critType=%v", ev.CritType)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:05 2026] You slash A Crocodile for 3764 points of damage.", time.Local)
+	if !ev.Crit || ev.CritType != model.CritNormal {
+		t.Fatalf("hit crit=%v critType=%v", ev.Crit, ev.CritType)
+	}
+
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:13:58 2026] Sigdis delivers a critical blast! (28794)", time.Local)
+	if ev.Kind != model.KindCritMeta || ev.CritType != model.CritNormal || ev.Actor != "Sigdis" {
+		t.Fatalf("blast kind=%v critType=%v actor=%q", ev.Kind, ev.CritType, ev.Actor)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:13:58 2026] Sigdis hit A Crocodile for 28794 points of non-melee damage.", time.Local)
+	if !ev.Crit || ev.MetaInt != 28794 {
+		t.Fatalf("blast hit crit=%v meta=%d", ev.Crit, ev.MetaInt)
+	}
+
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:10 2026] Sigdis performs an exceptional heal! (9172)", time.Local)
+	if ev.CritType != model.CritExceptionalHeal {
+		t.Fatalf("exceptional heal critType=%v", ev.CritType)
+	}
+}
+
+func TestParseLine_AvoidVariants(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	cases := []struct {
+		line   string
+		kind   model.EventKind
+		actor  string
+		target string
+		verb   string
+		avoid  model.AvoidType
+	}{
+		{"[Sat Jan 24 23:16:20 2026] Sharp Tooth tries to bite YOU, but YOU dodge!", model.KindAvoid, "Sharp Tooth", "YOU", "bite", model.AvoidDodge},
+		{"[Sat Jan 24 23:16:20 2026] A Crocodile tries to claw YOU, but YOU riposte!", model.KindAvoid, "A Crocodile", "YOU", "claw", model.AvoidRiposte},
+		{"[Sat Jan 24 23:16:20 2026] A Crocodile tries to sting YOU, but YOU parry!", model.KindAvoid, "A Crocodile", "YOU", "sting", model.AvoidParry},
+		{"[Sat Jan 24 23:16:20 2026] Oshiruk tries to slice Sigdis, but Sigdis blocks!", model.KindAvoid, "Oshiruk", "Sigdis", "slice", model.AvoidBlock},
+		{"[Sat Jan 24 23:16:20 2026] Oshiruk tries to bash Sigdis, but misses!", model.KindMiss, "Oshiruk", "Sigdis", "bash", model.AvoidMiss},
+		{"[Sat Jan 24 23:16:20 2026] Genaenyu tries to backstab Oshiruk, but Oshiruk parries!", model.KindAvoid, "YOU", "Oshiruk", "backstab", model.AvoidParry},
+		{"[Sat Jan 24 23:16:20 2026] You try to pierce Oshiruk, but Oshiruk ripostes!", model.KindAvoid, "YOU", "Oshiruk", "pierce", model.AvoidRiposte},
+		{"[Sat Jan 24 23:16:20 2026] You try to kick Oshiruk, but miss!", model.KindMiss, "YOU", "Oshiruk", "kick", model.AvoidMiss},
+	}
+	for _, tc := range cases {
+		ev, ok := ParseLine(ctx, tc.line, time.Local)
+		if !ok {
+			t.Fatalf("expected ok for %q", tc.line)
+		}
+		if ev.Kind != tc.kind || ev.Actor != tc.actor || ev.Target != tc.target || ev.Verb != tc.verb || ev.Avoid != tc.avoid {
+			t.Fatalf("%q: kind=%v actor=%q target=%q verb=%q avoid=%v", tc.line, ev.Kind, ev.Actor, ev.Target, ev.Verb, ev.Avoid)
+		}
+	}
+}
+
+func TestParseLine_ResistsAndSpellShield(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	ev, ok := ParseLine(ctx, "[Sat Jan 24 23:14:38 2026] Your target resisted the Sanity Warp spell.", time.Local)
+	if !ok || ev.Kind != model.KindResist || ev.Actor != "YOU" || ev.Target != "" || ev.SpellOrSkill != "Sanity Warp" {
+		t.Fatalf("outgoing resist ok=%v kind=%v actor=%q target=%q spell=%q", ok, ev.Kind, ev.Actor, ev.Target, ev.SpellOrSkill)
+	}
+	ev, ok = ParseLine(ctx, "[Sat Jan 24 23:16:24 2026] You resist the Petrifying Earth spell!", time.Local)
+	if !ok || ev.Kind != model.KindResist || ev.Actor != "" || ev.Target != "YOU" || ev.SpellOrSkill != "Petrifying Earth" {
+		t.Fatalf("incoming resist ok=%v kind=%v actor=%q target=%q spell=%q", ok, ev.Kind, ev.Actor, ev.Target, ev.SpellOrSkill)
+	}
+	ev, ok = ParseLine(ctx, "[Sat Jan 24 23:14:37 2026] The Spellshield absorbed 35 of 120 points of damage", time.Local)
+	if !ok || ev.Kind != model.KindSpellAbsorb || ev.Target != "YOU" || ev.Amount != 35 || ev.MetaInt != 120 {
+		t.Fatalf("spellshield ok=%v kind=%v target=%q amount=%d total=%d", ok, ev.Kind, ev.Target, ev.Amount, ev.MetaInt)
+	}
+}
+
+func TestParseLine_ZoneChange(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	ev, ok := ParseLine(ctx, "[Thu Jan 29 21:54:04 2026] You have entered The Iceclad Ocean.", time.Local)
+	if !ok || ev.Kind != model.KindZoneOrSystem || ev.SpellOrSkill != "zone" || ev.Zone != "The Iceclad Ocean" {
+		t.Fatalf("zone ok=%v kind=%v spell=%q zone=%q", ok, ev.Kind, ev.SpellOrSkill, ev.Zone)
+	}
+	if ctx.Zone != "The Iceclad Ocean" {
+		t.Fatalf("ctx zone=%q", ctx.Zone)
+	}
+	ev, _ = ParseLine(ctx, "[Thu Jan 29 21:54:10 2026] You pierce Lord Hydrerious for 100 points of damage.", time.Local)
+	if ev.Zone != "The Iceclad Ocean" {
+		t.Fatalf("event zone=%q", ev.Zone)
+	}
+	ev, _ = ParseLine(ctx, "[Thu Jan 29 21:54:11 2026] You have entered an area where levitation effects do not function.", time.Local)
+	if ev.Kind == model.KindZoneOrSystem || ctx.Zone != "The Iceclad Ocean" {
+		t.Fatalf("levitation notice kind=%v zone=%q", ev.Kind, ctx.Zone)
+	}
+}
+
+func TestParseLine_Chat(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	cases := []struct {
+		line    string
+		channel string
+		speaker string
+		target  string
+		message string
+	}{
+		{"[Sat Jan 24 23:13:50 2026] You say, '#zone arena'", "say", "YOU", "", "#zone arena"},
+		{"[Sat Jan 24 23:16:10 2026] Sharp Tooth says 'You will not evade me, Genaenyu!' ", "npc", "Sharp Tooth", "", "You will not evade me, Genaenyu!"},
+		{"[Sat Jan 24 23:18:00 2026] Lord Soth shouts 'Feel my wrath!'", "npc", "Lord Soth", "", "Feel my wrath!"},
+		{"[Sat Jan 24 23:18:01 2026] Danser tells the group, 'inc'", "group", "Danser", "", "inc"},
+		{"[Sat Jan 24 23:18:02 2026] You tell your party, 'ok'", "group", "YOU", "", "ok"},
+		{"[Sat Jan 24 23:18:03 2026] Karca tells the guild, 'grats'", "guild", "Karca", "", "grats"},
+		{"[Sat Jan 24 23:18:04 2026] Karca tells the raid, 'go'", "raid", "Karca", "", "go"},
+		{"[Sat Jan 24 23:18:05 2026] Empro says out of character, 'lfg'", "ooc", "Empro", "", "lfg"},
+		{"[Sat Jan 24 23:18:06 2026] Groon shouts, 'train to zone'", "shout", "Groon", "", "train to zone"},
+		{"[Sat Jan 24 23:18:07 2026] Sigdis tells you, 'need a port?'", "tell", "Sigdis", "YOU", "need a port?"},
+		{"[Sat Jan 24 23:18:08 2026] You told Sigdis, 'yes please'", "tell", "YOU", "Sigdis", "yes please"},
+		{"[Sat Jan 24 23:18:09 2026] Maran says from discord, 'hi all'", "discord", "Maran", "", "hi all"},
+		{"[Sat Jan 24 23:18:10 2026] Adem tells General:1, 'wts'", "General", "Adem", "", "wts"},
+		{"[Sat Jan 24 23:18:11 2026] Genaenyu says, 'hello'", "say", "YOU", "", "hello"},
+	}
+	for _, tc := range cases {
+		ev, ok := ParseLine(ctx, tc.line, time.Local)
+		if !ok || ev.Kind != model.KindChat {
+			t.Fatalf("%q: ok=%v kind=%v", tc.line, ok, ev.Kind)
+		}
+		if ev.Channel != tc.channel || ev.Actor != tc.speaker || ev.Target != tc.target || ev.Message != tc.message {
+			t.Fatalf("%q: channel=%q speaker=%q target=%q message=%q", tc.line, ev.Channel, ev.Actor, ev.Target, ev.Message)
+		}
+	}
+}
+
+func TestParseLine_StatusEffects(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	cases := []struct {
+		line    string
+		subject string
+		effect  model.StatusEffect
+	}{
+		{"[Sat Jan 24 23:17:54 2026] You are stunned!", "YOU", model.StatusStunned},
+		{"[Sat Jan 24 23:17:56 2026] You are no longer stunned.", "YOU", model.StatusStunEnded},
+		{"[Sat Jan 24 23:17:59 2026] You shake off the stun effect!", "YOU", model.StatusStunResisted},
+		{"[Sat Jan 24 23:14:42 2026] You avoid the stunning blow.", "YOU", model.StatusStunAvoided},
+		{"[Sat Jan 24 23:14:43 2026] You have failed to taunt your target.", "YOU", model.StatusTauntFailed},
+		{"[Sat Jan 24 23:18:00 2026] Lord Soth staggers.", "Lord Soth", model.StatusStagger},
+		{"[Sat Jan 24 23:18:01 2026] Fallen Knight of Soth looks delirious.", "Fallen Knight of Soth", model.StatusDelirium},
+	}
+	for _, tc := range cases {
+		ev, ok := ParseLine(ctx, tc.line, time.Local)
+		if !ok || ev.Kind != model.KindStatus {
+			t.Fatalf("%q: ok=%v kind=%v", tc.line, ok, ev.Kind)
+		}
+		if ev.Target != tc.subject || ev.Status != tc.effect {
+			t.Fatalf("%q: subject=%q effect=%v", tc.line, ev.Target, ev.Status)
+		}
+	}
+}
+
+func TestParseLine_LootAndExperience(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	ev, ok := ParseLine(ctx, "[Sat Jan 24 23:15:50 2026] --You have looted a Fragment of a Ruby.--", time.Local)
+	if !ok || ev.Kind != model.KindLoot || ev.Actor != "YOU" || ev.Item != "Fragment of a Ruby" || ev.Amount != 1 || ev.Target != "" {
+		t.Fatalf("loot ok=%v kind=%v actor=%q item=%q amount=%d corpse=%q", ok, ev.Kind, ev.Actor, ev.Item, ev.Amount, ev.Target)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:31:03 2026] --Sigdis has looted a Reaper`s Revenge.--", time.Local)
+	if ev.Kind != model.KindLoot || ev.Actor != "Sigdis" || ev.Item != "Reaper`s Revenge" {
+		t.Fatalf("other loot kind=%v actor=%q item=%q", ev.Kind, ev.Actor, ev.Item)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:31:04 2026] --You have looted 3 Bone Chips from a decaying skeleton's corpse.--", time.Local)
+	if ev.Kind != model.KindLoot || ev.Item != "Bone Chips" || ev.Amount != 3 || ev.Target != "a decaying skeleton" {
+		t.Fatalf("corpse loot kind=%v item=%q amount=%d corpse=%q", ev.Kind, ev.Item, ev.Amount, ev.Target)
+	}
+
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:06 2026] You gain party experience!!", time.Local)
+	if ev.Kind != model.KindExperience || ev.Verb != "party" {
+		t.Fatalf("party xp kind=%v verb=%q", ev.Kind, ev.Verb)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:06 2026] You gain experience!!", time.Local)
+	if ev.Kind != model.KindExperience || ev.Verb != "solo" {
+		t.Fatalf("solo xp kind=%v verb=%q", ev.Kind, ev.Verb)
+	}
+
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:06 2026] You have gained 3 ability point(s)!  You now have 4317 ability point(s).", time.Local)
+	if ev.Kind != model.KindAbilityPoint || ev.Amount != 3 || ev.MetaInt != 4317 {
+		t.Fatalf("aa kind=%v amount=%d total=%d", ev.Kind, ev.Amount, ev.MetaInt)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:07 2026] You have gained an ability point!  You now have 12 ability points.", time.Local)
+	if ev.Kind != model.KindAbilityPoint || ev.Amount != 1 || ev.MetaInt != 12 {
+		t.Fatalf("single aa kind=%v amount=%d total=%d", ev.Kind, ev.Amount, ev.MetaInt)
+	}
+}
+
+func TestParseLine_TargetSelection(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	ev, ok := ParseLine(ctx, "[Sat Jan 24 23:14:30 2026] Targeted (NPC): Lord Soth", time.Local)
+	if !ok || ev.Kind != model.KindTarget || ev.Actor != "YOU" || ev.Target != "Lord Soth" || ev.Verb != "npc" {
+		t.Fatalf("npc target ok=%v kind=%v actor=%q target=%q verb=%q", ok, ev.Kind, ev.Actor, ev.Target, ev.Verb)
+	}
+	if ctx.Target != "Lord Soth" || !ctx.TargetIsNPC {
+		t.Fatalf("ctx target=%q npc=%v", ctx.Target, ctx.TargetIsNPC)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:38 2026] Your target resisted the Sanity Warp spell.", time.Local)
+	if ev.Kind != model.KindResist || ev.Target != "Lord Soth" {
+		t.Fatalf("resist kind=%v target=%q", ev.Kind, ev.Target)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:39 2026] You cannot see your target.", time.Local)
+	if ev.Kind != model.KindZoneOrSystem || ev.SpellOrSkill != "cannot_see_target" || ev.Target != "Lord Soth" {
+		t.Fatalf("cannot see kind=%v spell=%q target=%q", ev.Kind, ev.SpellOrSkill, ev.Target)
+	}
+
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:40 2026] Targeted (Player): Sigdis", time.Local)
+	if ev.Kind != model.KindTarget || ev.Target != "Sigdis" || ev.Verb != "player" || ctx.TargetIsNPC {
+		t.Fatalf("player target kind=%v target=%q verb=%q npc=%v", ev.Kind, ev.Target, ev.Verb, ctx.TargetIsNPC)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:41 2026] Targeted (Player): Genaenyu", time.Local)
+	if ev.Target != "YOU" || ctx.Target != "YOU" {
+		t.Fatalf("self target=%q ctx=%q", ev.Target, ctx.Target)
+	}
+}
+
+func TestParseLine_CastLifecycle(t *testing.T) {
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	cases := []struct {
+		line    string
+		kind    model.EventKind
+		outcome model.CastOutcome
+		actor   string
+		spell   string
+		target  string
+	}{
+		{"[Sat Jan 24 23:14:30 2026] Sigdis begins casting Complete Heal.", model.KindCastStart, model.CastNone, "Sigdis", "Complete Heal", ""},
+		{"[Sat Jan 24 23:14:30 2026] A Living Flora begins to cast a spell. <Root>", model.KindCastStart, model.CastNone, "A Living Flora", "Root", ""},
+		{"[Sat Jan 24 23:14:30 2026] Karca begins to cast a spell.", model.KindCastStart, model.CastNone, "Karca", "", ""},
+		{"[Sat Jan 24 23:14:31 2026] Your spell is interrupted.", model.KindCastOutcome, model.CastInterrupted, "YOU", "", ""},
+		{"[Sat Jan 24 23:14:31 2026] Sigdis's casting is interrupted!", model.KindCastOutcome, model.CastInterrupted, "Sigdis", "", ""},
+		{"[Sat Jan 24 23:14:32 2026] Your spell fizzles!", model.KindCastOutcome, model.CastFizzled, "YOU", "", ""},
+		{"[Sat Jan 24 23:14:32 2026] Karca's spell fizzles!", model.KindCastOutcome, model.CastFizzled, "Karca", "", ""},
+		{"[Sat Jan 24 23:14:33 2026] Sigdis regains concentration and continues casting.", model.KindCastOutcome, model.CastRecovered, "Sigdis", "", ""},
+		{"[Sat Jan 24 23:14:33 2026] You regain your concentration and continue your casting.", model.KindCastOutcome, model.CastRecovered, "YOU", "", ""},
+		{"[Sat Jan 24 23:14:34 2026] Your song ends abruptly.", model.KindCastOutcome, model.CastSongEnded, "YOU", "", ""},
+		{"[Sat Jan 24 23:14:35 2026] Your Clarity spell has worn off of Sigdis.", model.KindCastOutcome, model.CastWornOff, "YOU", "Clarity", "Sigdis"},
+		{"[Sat Jan 24 23:14:36 2026] The Bite of the Shissar Poison wears off your weapon.", model.KindCastOutcome, model.CastWornOff, "", "Bite of the Shissar Poison", "YOU"},
+	}
+	for _, tc := range cases {
+		ev, ok := ParseLine(ctx, tc.line, time.Local)
+		if !ok || ev.Kind != tc.kind || ev.Cast != tc.outcome || ev.Actor != tc.actor || ev.SpellOrSkill != tc.spell || ev.Target != tc.target {
+			t.Fatalf("%s: ok=%v kind=%v cast=%v actor=%q spell=%q target=%q", tc.line, ok, ev.Kind, ev.Cast, ev.Actor, ev.SpellOrSkill, ev.Target)
+		}
+	}
+
+	// An affliction matching the pending cast is credited to the caster.
+	ParseLine(ctx, "[Sat Jan 24 23:14:40 2026] You begin casting Sanity Warp.", time.Local)
+	ev, _ := ParseLine(ctx, "[Sat Jan 24 23:14:42 2026] Sharp Tooth is afflicted by Sanity Warp.", time.Local)
+	if ev.Kind != model.KindAffliction || ev.Actor != "YOU" {
+		t.Fatalf("affliction kind=%v actor=%q", ev.Kind, ev.Actor)
+	}
+	ev, _ = ParseLine(ctx, "[Sat Jan 24 23:14:43 2026] Sharp Tooth is afflicted by poison.", time.Local)
+	if ev.Actor != "" {
+		t.Fatalf("unrelated affliction actor=%q", ev.Actor)
+	}
+}
@@ -0,0 +1,34 @@
+package parse
+
+import (
+	"strings"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+// resolvePets links pet actors to their owner. Pet names are already canonical here, see
+// canonicalName.
+func resolvePets(ev *model.Event) {
+	if owner, ok := model.PetOwner(ev.Actor); ok {
+		ev.ActorOwner = owner
+	}
+}
+
+// restorePetOwner puts back the spaces the client drops from multi-word owners in some
+// lines ("Genaenyu hit LordSoth`s pet ...") once the owner has been seen spelled out.
+func restorePetOwner(t *model.NameTable, name string) string {
+	owner, suffix, ok := model.SplitPet(name)
+	if !ok {
+		return name
+	}
+	if strings.Contains(owner, " ") {
+		if _, ok := t.ByKey[nameKey(owner)]; !ok {
+			t.ByKey[nameKey(owner)] = owner
+		}
+		return name
+	}
+	if spelled, ok := t.ByKey[nameKey(owner)]; ok {
+		return spelled + suffix
+	}
+	return name
+}
@@ -0,0 +1,582 @@
+package parse
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+// A RulePack is a named list of parse rules, loaded from JSON. The built-in "imperium"
+// pack (rules/imperium.json) holds every line pattern the parser knows; a server-specific
+// pack is compiled on top of it to add, replace or disable rules.
+//
+// TimeLayout is the time.Parse layout of the text between the line's brackets, for clients
+// that do not write "Mon Jan 02 15:04:05 2006". The last pack that sets it wins.
+type RulePack struct {
+	Name       string `json:"name"`
+	TimeLayout string `json:"time_layout,omitempty"`
+	Rules      []Rule `json:"rules"`
+}
+
+// Rule turns one message pattern into an event. Pattern is matched against the message
+// after the "[timestamp] " prefix. Fields maps named captures to event fields and Set gives
+// fixed field values; captures override Set when they match something. Hooks name the
+// built-in behaviours to run after the fields are filled (see ruleHooks).
+//
+// Rules are tried from the highest Priority down; a later pack's rule with the same Name
+// replaces the earlier one, and Disabled removes it.
+type Rule struct {
+	Name     string            `json:"name"`
+	Pattern  string            `json:"pattern"`
+	Kind     string            `json:"kind"`
+	Priority int               `json:"priority"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	Set      map[string]string `json:"set,omitempty"`
+	Hooks    []string          `json:"hooks,omitempty"`
+	Disabled bool              `json:"disabled,omitempty"`
+}
+
+// RuleSet is a compiled, ordered set of rules. It is safe for concurrent use; parse state
+// lives in model.ParseContext.
+type RuleSet struct {
+	rules  []*compiledRule
+	layout string
+
+	byLastByte [256][]*compiledRule
+	noSuffix   []*compiledRule
+}
+
+type compiledRule struct {
+	name  string
+	re    *regexp.Regexp
+	kind  model.EventKind
+	set   []fieldValue
+	caps  []fieldCapture
+	hooks []ruleHook
+
+	prefix, suffix, needle string
+}
+
+type fieldValue struct {
+	field string
+	value string
+}
+
+type fieldCapture struct {
+	group int
+	field string
+}
+
+// ruleMatch is what hooks see of the matched message.
+type ruleMatch struct {
+	msg string
+	m   []int
+	re  *regexp.Regexp
+}
+
+func (rm ruleMatch) group(name string) string {
+	return reSub(rm.msg, rm.m, rm.re.SubexpIndex(name))
+}
+
+// A ruleHook runs after a rule's fields are filled; returning false rejects the match and
+// parsing moves on to the next rule.
+type ruleHook func(ctx *model.ParseContext, ev *model.Event, rm ruleMatch) bool
+
+var eventKinds = map[string]model.EventKind{
+	"melee":           model.KindMeleeDamage,
+	"non_melee":       model.KindNonMeleeDamage,
+	"miss":            model.KindMiss,
+	"avoid":           model.KindAvoid,
+	"crit_meta":       model.KindCritMeta,
+	"cast_start":      model.KindCastStart,
+	"affliction":      model.KindAffliction,
+	"heal":            model.KindHeal,
+	"thorns_marker":   model.KindThornsMarker,
+	"death":           model.KindDeath,
+	"zone_or_system":  model.KindZoneOrSystem,
+	"incoming_damage": model.KindIncomingDamage,
+	"special_attack":  model.KindSpecialAttack,
+	"resist":          model.KindResist,
+	"spell_absorb":    model.KindSpellAbsorb,
+	"chat":            model.KindChat,
+	"status":          model.KindStatus,
+	"loot":            model.KindLoot,
+	"experience":      model.KindExperience,
+	"ability_point":   model.KindAbilityPoint,
+	"target":          model.KindTarget,
+	"cast_outcome":    model.KindCastOutcome,
+}
+
+var (
+	critTypes = map[string]model.CritType{
+		"normal":           model.CritNormal,
+		"crippling":        model.CritCrippling,
+		"deadly":           model.CritDeadly,
+		"exceptional_heal": model.CritExceptionalHeal,
+		"strikethrough":    model.CritStrikethrough,
+	}
+	attackModifiers = map[string]model.AttackModifier{
+		"rampage":      model.ModifierRampage,
+		"wild_rampage": model.ModifierWildRampage,
+		"flurry":       model.ModifierFlurry,
+	}
+	statusEffects = map[string]model.StatusEffect{
+		"stunned":       model.StatusStunned,
+		"stun_ended":    model.StatusStunEnded,
+		"stun_resisted": model.StatusStunResisted,
+		"stun_avoided":  model.StatusStunAvoided,
+		"taunt_failed":  model.StatusTauntFailed,
+		"stagger":       model.StatusStagger,
+		"delirium":      model.StatusDelirium,
+	}
+	castOutcomes = map[string]model.CastOutcome{
+		"interrupted": model.CastInterrupted,
+		"fizzled":     model.CastFizzled,
+		"recovered":   model.CastRecovered,
+		"song_ended":  model.CastSongEnded,
+		"worn_off":    model.CastWornOff,
+	}
+	damageClasses = map[string]model.DamageClass{
+		"pierce": model.DamageClassPierce,
+		"slash":  model.DamageClassSlash,
+		"crush":  model.DamageClassCrush,
+		"bash":   model.DamageClassBash,
+		"kick":   model.DamageClassKick,
+		"direct": model.DamageClassDirect,
+		"dot":    model.DamageClassDoT,
+	}
+)
+
+// setField stores a captured or fixed value in the named event field. It reports false
+// when the value does not fit the field, e.g. a non-numeric amount.
+func setField(ev *model.Event, field, value string) bool {
+	switch field {
+	case "actor":
+		ev.Actor = value
+	case "target":
+		ev.Target = value
+	case "spell":
+		ev.SpellOrSkill = value
+	case "verb":
+		ev.Verb = value
+	case "item":
+		ev.Item = value
+	case "channel":
+		ev.Channel = value
+	case "message":
+		ev.Message = value
+	case "zone":
+		ev.Zone = value
+	case "amount":
+		n, ok := parseInt64(value)
+		if !ok {
+			return false
+		}
+		ev.Amount = n
+		ev.AmountKnown = true
+	case "meta":
+		n, ok := parseInt64(value)
+		if !ok {
+			return false
+		}
+		ev.MetaInt = n
+	case "avoid":
+		ev.Avoid = avoidType(value)
+		if value == "miss" {
+			ev.Avoid = model.AvoidMiss
+		}
+		return ev.Avoid != model.AvoidNone
+	case "crit":
+		t, ok := critTypes[value]
+		ev.CritType = t
+		return ok
+	case "modifier":
+		mod, ok := attackModifiers[value]
+		ev.Modifier = mod
+		return ok
+	case "status":
+		st, ok := statusEffects[value]
+		ev.Status = st
+		return ok
+	case "cast":
+		c, ok := castOutcomes[value]
+		ev.Cast = c
+		return ok
+	case "damage_class":
+		dc, ok := damageClasses[value]
+		ev.DamageClass = dc
+		return ok
+	default:
+		return false
+	}
+	return true
+}
+
+func knownField(field string) bool {
+	switch field {
+	case "actor", "target", "spell", "verb", "item", "channel", "message", "zone",
+		"amount", "meta", "avoid", "crit", "modifier", "status", "cast", "damage_class":
+		return true
+	}
+	return false
+}
+
+//go:embed rules/imperium.json
+var imperiumJSON []byte
+
+var (
+	defaultRulesOnce sync.Once
+	defaultRules     *RuleSet
+)
+
+// ImperiumPack returns a fresh copy of the built-in rule pack.
+func ImperiumPack() *RulePack {
+	p, err := LoadRulePack(strings.NewReader(string(imperiumJSON)))
+	if err != nil {
+		panic(fmt.Sprintf("parse: built-in rule pack: %v", err))
+	}
+	return p
+}
+
+// DefaultRules is the compiled built-in pack used by ParseLine and ParseFile.
+func DefaultRules() *RuleSet {
+	defaultRulesOnce.Do(func() {
+		rs, err := CompileRules(ImperiumPack())
+		if err != nil {
+			panic(fmt.Sprintf("parse: built-in rule pack: %v", err))
+		}
+		defaultRules = rs
+	})
+	return defaultRules
+}
+
+func LoadRulePack(r io.Reader) (*RulePack, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var p RulePack
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("decode rule pack: %w", err)
+	}
+	return &p, nil
+}
+
+func LoadRulePackFile(path string) (*RulePack, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := LoadRulePack(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// LoadRuleSet compiles the built-in pack with the packs at paths layered on top, in order.
+func LoadRuleSet(paths ...string) (*RuleSet, error) {
+	if len(paths) == 0 {
+		return DefaultRules(), nil
+	}
+	packs := []*RulePack{ImperiumPack()}
+	for _, path := range paths {
+		p, err := LoadRulePackFile(path)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, p)
+	}
+	return CompileRules(packs...)
+}
+
+// CompileRules merges packs in order and compiles the result. Rules with equal priority
+// keep the order they were first declared in.
+func CompileRules(packs ...*RulePack) (*RuleSet, error) {
+	var order []string
+	var layout string
+	byName := make(map[string]Rule)
+	for _, p := range packs {
+		if p == nil {
+			continue
+		}
+		if p.TimeLayout != "" {
+			ref := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+			got, err := time.Parse(p.TimeLayout, ref.Format(p.TimeLayout))
+			if err != nil {
+				return nil, fmt.Errorf("pack %q: time layout %q: %w", p.Name, p.TimeLayout, err)
+			}
+			if !got.Equal(ref) {
+				return nil, fmt.Errorf("pack %q: time layout %q does not give the date and time to the second", p.Name, p.TimeLayout)
+			}
+			layout = p.TimeLayout
+		}
+		for i, r := range p.Rules {
+			if r.Name == "" {
+				return nil, fmt.Errorf("pack %q: rule %d has no name", p.Name, i)
+			}
+			if _, ok := byName[r.Name]; !ok {
+				order = append(order, r.Name)
+			}
+			byName[r.Name] = r
+		}
+	}
+
+	rs := &RuleSet{layout: layout}
+	for _, name := range order {
+		r := byName[name]
+		if r.Disabled {
+			continue
+		}
+		cr, err := compileRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", name, err)
+		}
+		rs.rules = append(rs.rules, cr)
+	}
+	prio := make(map[string]int, len(byName))
+	for name, r := range byName {
+		prio[name] = r.Priority
+	}
+	sort.SliceStable(rs.rules, func(i, j int) bool {
+		return prio[rs.rules[i].name] > prio[rs.rules[j].name]
+	})
+	rs.buildDispatch()
+	return rs, nil
+}
+
+func compileRule(r Rule) (*compiledRule, error) {
+	if r.Pattern == "" {
+		return nil, fmt.Errorf("no pattern")
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	kind, ok := eventKinds[r.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %q", r.Kind)
+	}
+	cr := &compiledRule{name: r.Name, re: re, kind: kind}
+	cr.prefix, cr.suffix, cr.needle = ruleLiterals(r.Pattern)
+
+	for _, field := range sortedKeys(r.Set) {
+		if !knownField(field) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		var probe model.Event
+		if !setField(&probe, field, r.Set[field]) {
+			return nil, fmt.Errorf("invalid value %q for field %q", r.Set[field], field)
+		}
+		cr.set = append(cr.set, fieldValue{field: field, value: r.Set[field]})
+	}
+	for _, group := range sortedKeys(r.Fields) {
+		field := r.Fields[group]
+		if !knownField(field) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		i := re.SubexpIndex(group)
+		if i < 0 {
+			return nil, fmt.Errorf("pattern has no capture %q", group)
+		}
+		cr.caps = append(cr.caps, fieldCapture{group: i, field: field})
+	}
+	for _, name := range r.Hooks {
+		h, ok := ruleHooks[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown hook %q", name)
+		}
+		cr.hooks = append(cr.hooks, h)
+	}
+	return cr, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// apply fills ev from msg and the rule's submatch indexes m. It reports false when a
+// captured value does not fit its field or a hook rejects the match.
+func (cr *compiledRule) apply(ctx *model.ParseContext, msg string, m []int, ev *model.Event) bool {
+	out := *ev
+	out.Kind = cr.kind
+	for _, fv := range cr.set {
+		setField(&out, fv.field, fv.value)
+	}
+	for _, fc := range cr.caps {
+		v := reSub(msg, m, fc.group)
+		if v == "" {
+			continue
+		}
+		if !setField(&out, fc.field, v) {
+			return false
+		}
+	}
+	rm := ruleMatch{msg: msg, m: m, re: cr.re}
+	for _, h := range cr.hooks {
+		if !h(ctx, &out, rm) {
+			return false
+		}
+	}
+	*ev = out
+	return true
+}
+
+// Names lists the compiled rules in the order they are tried.
+func (rs *RuleSet) Names() []string {
+	out := make([]string, 0, len(rs.rules))
+	for _, cr := range rs.rules {
+		out = append(out, cr.name)
+	}
+	return out
+}
+
+// ruleHooks are the behaviours a rule can ask for by name. Most keep cross-line state on
+// the ParseContext: pending crits and heals, the local player's cast, DoT ownership, the
+// current zone and target.
+var ruleHooks = map[string]ruleHook{
+	"local_actor": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		ev.Actor = localActor(ctx, ev.Actor)
+		return true
+	},
+	"local_target": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		ev.Target = localActor(ctx, ev.Target)
+		return true
+	},
+	"crit_meta": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		if ctx != nil {
+			ctx.PendingCrit = &model.PendingCrit{Actor: ev.Actor, Ts: ev.Timestamp, Value: ev.MetaInt, TTL: 2, Type: ev.CritType}
+		}
+		return true
+	},
+	"heal_crit_meta": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		if ctx != nil {
+			ctx.PendingHeal = &model.PendingCrit{Actor: ev.Actor, Ts: ev.Timestamp, Value: ev.MetaInt, TTL: 2, Type: ev.CritType}
+		}
+		return true
+	},
+	"pending_crit": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		handlePendingCrit(ctx, ev)
+		return true
+	},
+	"pending_heal": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		handlePendingHeal(ctx, ev)
+		return true
+	},
+	"pending_cast": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		notePendingCast(ctx, ev)
+		return true
+	},
+	"affliction": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		noteAffliction(ctx, ev)
+		return true
+	},
+	"attribute_dot": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		attributeDoT(ctx, ev)
+		return true
+	},
+	"clear_dots": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		clearDoTs(ctx, ev.Target)
+		return true
+	},
+	"flurry_marker": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		notePendingFlurry(ctx, ev)
+		return true
+	},
+	"pending_flurry": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		handlePendingFlurry(ctx, ev)
+		return true
+	},
+	// wild_rampage upgrades a rampage when the pattern's "wild" capture matched.
+	"wild_rampage": func(_ *model.ParseContext, ev *model.Event, rm ruleMatch) bool {
+		if rm.group("wild") != "" {
+			ev.Modifier = model.ModifierWildRampage
+		}
+		return true
+	},
+	"heal_target": func(_ *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		ev.Target = healTargetName(ev.Target, ev.Actor)
+		return true
+	},
+	"damage_class": func(_ *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		applyDamageClass(ev)
+		return true
+	},
+	// incoming_to_local turns a swing at the local player into incoming damage; third-person
+	// swings are oriented later by the encounter segmenter.
+	"incoming_to_local": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		if ev.Target == "YOU" || (ctx != nil && ctx.LocalActorName != "" && ev.Target == ctx.LocalActorName) {
+			ev.Kind = model.KindIncomingDamage
+			ev.Target = "YOU"
+		}
+		return true
+	},
+	"current_target": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		if ev.Target == "" {
+			ev.Target = currentTarget(ctx)
+		}
+		return true
+	},
+	// zone records the zone just entered. "You have entered an area where levitation
+	// effects do not function." is not a zone.
+	"zone": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		if strings.HasPrefix(ev.Zone, "an area ") {
+			return false
+		}
+		if ctx != nil {
+			ctx.Zone = ev.Zone
+		}
+		return true
+	},
+	"set_target": func(ctx *model.ParseContext, ev *model.Event, _ ruleMatch) bool {
+		ev.Verb = strings.ToLower(ev.Verb)
+		if ctx != nil {
+			ctx.Target = ev.Target
+			ctx.TargetIsNPC = ev.Verb == "npc"
+		}
+		return true
+	},
+}
+
+// firstMatch finds the first rule, from position start in rs.candidates(msg), whose
+// regex matches msg. Rules whose literals rule the line out are skipped without running
+// their regex. It returns -1 when no rule matches.
+func (rs *RuleSet) firstMatch(msg string, start int) (int, []int) {
+	cands := rs.candidates(msg)
+	for i := start; i < len(cands); i++ {
+		cr := cands[i]
+		if !cr.mayMatch(msg) {
+			continue
+		}
+		if m := cr.re.FindStringSubmatchIndex(msg); m != nil {
+			return i, m
+		}
+	}
+	return -1, nil
+}
+
+// match fills ev from the first rule that matches msg and accepts it, starting from the
+// match firstMatch found at pos.
+func (rs *RuleSet) match(ctx *model.ParseContext, msg string, ev *model.Event, pos int, m []int) bool {
+	cands := rs.candidates(msg)
+	for pos >= 0 {
+		if cands[pos].apply(ctx, msg, m, ev) {
+			return true
+		}
+		pos, m = rs.firstMatch(msg, pos+1)
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+func TestRulePack_CustomRules(t *testing.T) {
+	custom, err := LoadRulePack(strings.NewReader(`{
+		"name": "custom-server",
+		"rules": [
+			{
+				"name": "holy_smite",
+				"kind": "non_melee",
+				"priority": 5,
+				"pattern": "^(?P<actor>.+?) smites (?P<target>.+?) for (?P<amt>\\d+) points of holy damage\\.$",
+				"fields": {"actor": "actor", "target": "target", "amt": "amount"},
+				"set": {"spell": "Holy Smite", "damage_class": "direct"},
+				"hooks": ["local_actor"]
+			},
+			{
+				"name": "zone_enter",
+				"kind": "zone_or_system",
+				"priority": 230,
+				"pattern": "^You have been transported to (?P<zone>.+?)\\.$",
+				"fields": {"zone": "zone"},
+				"set": {"spell": "zone"},
+				"hooks": ["zone"]
+			},
+			{"name": "chat_ooc", "disabled": true}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	rs, err := CompileRules(ImperiumPack(), custom)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	ctx := &model.ParseContext{LocalActorName: "Genaenyu"}
+
+	ev, ok := rs.ParseLine(ctx, "[Sat Jan 24 23:14:30 2026] Genaenyu smites Lord Soth for 1200 points of holy damage.", time.Local)
+	if !ok || ev.Kind != model.KindNonMeleeDamage || ev.Actor != "YOU" || ev.Target != "Lord Soth" || ev.Amount != 1200 || ev.SpellOrSkill != "Holy Smite" || ev.DamageClass != model.DamageClassDirect {
+		t.Fatalf("smite ok=%v ev=%+v", ok, ev)
+	}
+	ev, _ = rs.ParseLine(ctx, "[Sat Jan 24 23:14:31 2026] You have been transported to Nexus.", time.Local)
+	if ev.Kind != model.KindZoneOrSystem || ctx.Zone != "Nexus" {
+		t.Fatalf("replaced zone rule kind=%v zone=%q", ev.Kind, ctx.Zone)
+	}
+	ev, _ = rs.ParseLine(ctx, "[Sat Jan 24 23:14:32 2026] You have entered The Arena.", time.Local)
+	if ev.Kind != model.KindUnknown || ctx.Zone != "Nexus" {
+		t.Fatalf("old zone pattern still active kind=%v zone=%q", ev.Kind, ctx.Zone)
+	}
+	ev, _ = rs.ParseLine(ctx, "[Sat Jan 24 23:14:33 2026] Sigdis says out of character, 'hi'", time.Local)
+	if ev.Kind == model.KindChat && ev.Channel == "ooc" {
+		t.Fatalf("disabled rule matched: %+v", ev)
+	}
+
+	// The built-in rules are untouched by the custom set.
+	ev, _ = ParseLine(nil, "[Sat Jan 24 23:14:34 2026] You have entered The Arena.", time.Local)
+	if ev.Kind != model.KindZoneOrSystem || ev.Zone != "The Arena" {
+		t.Fatalf("built-in zone kind=%v zone=%q", ev.Kind, ev.Zone)
+	}
+}
+
+func TestRulePack_Priority(t *testing.T) {
+	rs, err := CompileRules(&RulePack{Rules: []Rule{
+		{Name: "low", Kind: "chat", Priority: 1, Pattern: `^(?P<msg>.*)$`, Fields: map[string]string{"msg": "message"}, Set: map[string]string{"channel": "low"}},
+		{Name: "high", Kind: "chat", Priority: 10, Pattern: `^hello (?P<msg>.*)$`, Fields: map[string]string{"msg": "message"}, Set: map[string]string{"channel": "high"}},
+	}})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	if got := strings.Join(rs.Names(), ","); got != "high,low" {
+		t.Fatalf("order=%s", got)
+	}
+	ev, _ := rs.ParseLine(nil, "[Sat Jan 24 23:14:30 2026] hello there", time.Local)
+	if ev.Channel != "high" || ev.Message != "there" {
+		t.Fatalf("channel=%q message=%q", ev.Channel, ev.Message)
+	}
+	ev, _ = rs.ParseLine(nil, "[Sat Jan 24 23:14:30 2026] goodbye", time.Local)
+	if ev.Channel != "low" {
+		t.Fatalf("channel=%q", ev.Channel)
+	}
+}
+
+func TestRulePack_CompileErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		rule Rule
+		want string
+	}{
+		{"no pattern", Rule{Name: "r", Kind: "chat"}, "no pattern"},
+		{"bad regex", Rule{Name: "r", Kind: "chat", Pattern: `(`}, "missing closing )"},
+		{"unknown kind", Rule{Name: "r", Kind: "spam", Pattern: `x`}, `unknown kind "spam"`},
+		{"unknown field", Rule{Name: "r", Kind: "chat", Pattern: `(?P<x>x)`, Fields: map[string]string{"x": "colour"}}, `unknown field "colour"`},
+		{"missing capture", Rule{Name: "r", Kind: "chat", Pattern: `x`, Fields: map[string]string{"x": "message"}}, `no capture "x"`},
+		{"bad value", Rule{Name: "r", Kind: "status", Pattern: `x`, Set: map[string]string{"status": "asleep"}}, `invalid value "asleep"`},
+		{"unknown hook", Rule{Name: "r", Kind: "chat", Pattern: `x`, Hooks: []string{"explode"}}, `unknown hook "explode"`},
+	}
+	for _, tc := range cases {
+		_, err := CompileRules(&RulePack{Rules: []Rule{tc.rule}})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: err=%v want %q", tc.name, err, tc.want)
+		}
+	}
+	if _, err := LoadRulePack(strings.NewReader(`{"rules": [{"name": "r", "patern": "x"}]}`)); err == nil {
+		t.Fatalf("expected unknown JSON field to fail")
+	}
+}
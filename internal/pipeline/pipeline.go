@@ -0,0 +1,198 @@
+// Package pipeline runs parsed log events through a chain of stages, so that every
+// consumer of a log (the CLI commands, the desktop UI, the hub publisher) sees the same
+// events normalised and filtered the same way.
+//
+// A Pipeline owns the parse state for one log. Lines come in from a Source, such as the log
+// file (File) or a tailer (Tail), are parsed, and each event then passes through the
+// stages in order: normalisers such as PlayerName rewrite it, filters such as TimeFilter
+// drop it, and sinks such as a segmenter or the hub publisher consume it. ForLog builds the
+// chain every consumer shares.
+package pipeline
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/engine"
+	"github.com/ZehenForever/eqemu-log-parser/internal/logindex"
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+	"github.com/ZehenForever/eqemu-log-parser/internal/parse"
+	"github.com/ZehenForever/eqemu-log-parser/internal/tail"
+)
+
+// A Processor is one stage. It may change the event in place, and returns false to drop
+// it, in which case later stages do not see it.
+type Processor interface {
+	Process(ev *model.Event) bool
+}
+
+// Func adapts a function to a Processor.
+type Func func(ev *model.Event) bool
+
+func (f Func) Process(ev *model.Event) bool { return f(ev) }
+
+// Pipeline parses the lines of one log and passes the events through its stages. It is
+// not safe for concurrent use; the Tail source takes the lock that anything else reading
+// the sinks holds.
+type Pipeline struct {
+	rules  *parse.RuleSet
+	ctx    *model.ParseContext
+	loc    *time.Location
+	stages []Processor
+}
+
+// New returns a pipeline parsing with rules (nil for the built-in ones) in loc, keeping
+// parse state in ctx.
+func New(rules *parse.RuleSet, ctx *model.ParseContext, loc *time.Location, stages ...Processor) *Pipeline {
+	if rules == nil {
+		rules = parse.DefaultRules()
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	return &Pipeline{rules: rules, ctx: ctx, loc: loc, stages: stages}
+}
+
+// ForLog returns the pipeline the CLI and the UI read the log at path through: clock jumps
+// are noted with notef (nil to skip), then the time filter and the rewrite of YOU to the
+// player named in the file name, then sinks.
+func ForLog(path string, rules *parse.RuleSet, loc *time.Location, tf engine.TimeFilter, notef func(format string, args ...any), sinks ...Processor) *Pipeline {
+	playerName, _ := parse.PlayerNameFromLogPath(path)
+	ctx := &model.ParseContext{LocalActorName: playerName}
+	return New(rules, ctx, loc,
+		ClockJumps(notef),
+		TimeFilter(tf),
+		PlayerName(playerName),
+	).Then(sinks...)
+}
+
+// Context is the parse state, for sinks that share the name table.
+func (p *Pipeline) Context() *model.ParseContext { return p.ctx }
+
+// Then appends stages to the end of the pipeline.
+func (p *Pipeline) Then(stages ...Processor) *Pipeline {
+	p.stages = append(p.stages, stages...)
+	return p
+}
+
+// Push runs ev through the stages and reports whether it came out of the last one.
+func (p *Pipeline) Push(ev model.Event) bool {
+	for _, s := range p.stages {
+		if !s.Process(&ev) {
+			return false
+		}
+	}
+	return true
+}
+
+// Line parses one line, as from a tailer, and pushes its event. It reports false for
+// lines that do not parse or are dropped.
+func (p *Pipeline) Line(line string) bool {
+	ev, ok := p.rules.ParseLine(p.ctx, line, p.loc)
+	if !ok {
+		return false
+	}
+	if jump, ok := parse.ClockJumpEvent(ev); ok {
+		p.Push(jump)
+	}
+	return p.Push(ev)
+}
+
+// Parse parses r to the end on parallel workers and pushes every event in order.
+func (p *Pipeline) Parse(r io.Reader) error {
+	it := p.rules.ParseFileParallel(r, p.ctx, p.loc, 0)
+	defer it.Close()
+	for it.Next() {
+		p.Push(it.Event())
+	}
+	return it.Err()
+}
+
+// A Source feeds a log into a pipeline until it runs out or ctx is done, pushing lines
+// with Line or handing a reader to Parse. File and Tail are the built-in sources; stdin, a
+// hub replay or a test reader is just another Source.
+type Source func(ctx context.Context, p *Pipeline) error
+
+// Run feeds src into the pipeline.
+func (p *Pipeline) Run(ctx context.Context, src Source) error {
+	return src(ctx, p)
+}
+
+// File reads the log at path to the end, from the first line stamped at or after cutoff
+// when there is one, found from the sidecar index or by binary search.
+func File(path string, cutoff *time.Time) Source {
+	return func(_ context.Context, p *Pipeline) error {
+		f, err := logindex.Open(path, cutoff, p.rules, p.loc, p.ctx)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return p.Parse(f)
+	}
+}
+
+// Tail follows the log at path, from its end when startAtEnd is set, and parses each new
+// line until ctx is done. Lines are parsed holding mu when it is not nil, so that the
+// caller can read the sinks from another goroutine.
+func Tail(path string, startAtEnd bool, mu sync.Locker) Source {
+	return func(ctx context.Context, p *Pipeline) error {
+		tlr, err := tail.NewTailer(path, tail.TailOptions{StartAtEnd: startAtEnd})
+		if err != nil {
+			return err
+		}
+		defer tlr.Stop()
+		return tlr.Run(ctx, func(line string) {
+			if mu != nil {
+				mu.Lock()
+				defer mu.Unlock()
+			}
+			p.Line(line)
+		})
+	}
+}
+
+// ClockJumps notes each clock jump event with notef, since encounters are split there and
+// times on either side cannot be compared. It drops nothing.
+func ClockJumps(notef func(format string, args ...any)) Processor {
+	return Func(func(ev *model.Event) bool {
+		if notef != nil && ev.Kind == model.KindClockJump {
+			notef("log clock jumped %s at %q; encounters split there", ev.ClockJump, ev.Raw)
+		}
+		return true
+	})
+}
+
+// PlayerName rewrites the log's "YOU" as actor or target to the player's name. It does
+// nothing when the name is not known.
+func PlayerName(name string) Processor {
+	return Func(func(ev *model.Event) bool {
+		if name == "" {
+			return true
+		}
+		if ev.Actor == "YOU" {
+			ev.Actor = name
+		}
+		if ev.Target == "YOU" {
+			ev.Target = name
+		}
+		return true
+	})
+}
+
+// TimeFilter drops events the filter does not allow. Clock jump events always pass: the
+// encounters on either side of a jump must be split even when it lands before the cutoff.
+func TimeFilter(tf engine.TimeFilter) Processor {
+	return Func(func(ev *model.Event) bool {
+		return ev.Kind == model.KindClockJump || tf.Allow(ev.Timestamp)
+	})
+}
+
+// Sink passes every event to fn, such as a segmenter's Process, and keeps it.
+func Sink(fn func(ev model.Event)) Processor {
+	return Func(func(ev *model.Event) bool {
+		fn(*ev)
+		return true
+	})
+}
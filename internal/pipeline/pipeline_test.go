@@ -0,0 +1,156 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZehenForever/eqemu-log-parser/internal/engine"
+	"github.com/ZehenForever/eqemu-log-parser/internal/model"
+)
+
+func TestPipeline_StagesRunInOrder(t *testing.T) {
+	base := time.Date(2026, time.January, 24, 20, 0, 0, 0, time.UTC)
+	cutoff := base.Add(time.Minute)
+	var got []model.Event
+	p := New(nil, &model.ParseContext{}, time.UTC,
+		TimeFilter(engine.TimeFilter{Cutoff: &cutoff}),
+		PlayerName("Emberval"),
+		Func(func(ev *model.Event) bool { return ev.Kind != model.KindChat }),
+	).Then(Sink(func(ev model.Event) { got = append(got, ev) }))
+
+	for _, ev := range []model.Event{
+		{Timestamp: base, Actor: "YOU", Kind: model.KindMeleeDamage},
+		{Timestamp: cutoff, Actor: "YOU", Target: "a gnoll", Kind: model.KindMeleeDamage},
+		{Timestamp: cutoff, Actor: "a gnoll", Target: "YOU", Kind: model.KindMeleeDamage},
+		{Timestamp: cutoff, Actor: "YOU", Kind: model.KindChat},
+	} {
+		p.Push(ev)
+	}
+	if len(got) != 2 || got[0].Actor != "Emberval" || got[1].Target != "Emberval" {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestPipeline_ParseMatchesLines(t *testing.T) {
+	path := filepath.Join("..", "..", "testdata", "eqlog_Emberval_Imperium_EQ.txt")
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("testdata file not present: %v", err)
+	}
+	defer f.Close()
+
+	collect := func(dst *[]model.Event) *Pipeline {
+		return New(nil, &model.ParseContext{LocalActorName: "Emberval"}, time.UTC,
+			PlayerName("Emberval"),
+			Sink(func(ev model.Event) { *dst = append(*dst, ev) }),
+		)
+	}
+	var whole, lines []model.Event
+	if err := collect(&whole).Parse(f); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatalf("seek: %v", err)
+	}
+	p := collect(&lines)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 4*1024*1024)
+	for sc.Scan() {
+		p.Line(sc.Text())
+	}
+	if len(whole) == 0 || !reflect.DeepEqual(whole, lines) {
+		t.Fatalf("Parse gave %d events, Line gave %d", len(whole), len(lines))
+	}
+	for _, ev := range whole {
+		if ev.Actor == "YOU" || ev.Target == "YOU" {
+			t.Fatalf("YOU not rewritten: %q", ev.Raw)
+		}
+	}
+}
+
+func TestForLog_FileThenTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eqlog_Emberval_Imperium.txt")
+	log := "[Sat Jan 24 20:00:00 2026] You hit a gnoll for 5 points of damage.\n" +
+		// The clock was set back an hour with no zone change to explain it.
+		"[Sat Jan 24 19:00:05 2026] You hit a gnoll for 6 points of damage.\n"
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var mu sync.Mutex
+	var notes []string
+	var got []model.Event
+	p := ForLog(path, nil, time.UTC, engine.TimeFilter{},
+		func(format string, args ...any) { notes = append(notes, fmt.Sprintf(format, args...)) },
+		Sink(func(ev model.Event) { got = append(got, ev) }),
+	)
+	if err := p.Run(context.Background(), File(path, nil)); err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	if len(notes) != 1 || len(got) != 3 || got[1].Kind != model.KindClockJump || got[2].Actor != "Emberval" {
+		t.Fatalf("notes %q, events %+v", notes, got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx, Tail(path, true, &mu)) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		// Append until the tailer, which may not have opened the file yet, sees a line.
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		_, _ = f.WriteString("[Sat Jan 24 19:00:09 2026] You hit a gnoll for 7 points of damage.\n")
+		f.Close()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n > 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("tailed line never reached the sink")
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Tail: %v", err)
+	}
+	if ev := got[3]; ev.Actor != "Emberval" || ev.Amount != 7 {
+		t.Fatalf("tailed %+v", ev)
+	}
+}
+
+func TestForLog_ClockJumpBeforeCutoffSplitsEncounters(t *testing.T) {
+	cutoff := time.Date(2026, time.January, 24, 20, 0, 0, 0, time.UTC)
+	stamp := func(d time.Duration, dmg int) string {
+		return fmt.Sprintf("[%s] You slash a rat for %d points of damage.\n", cutoff.Add(d).Format("Mon Jan 02 15:04:05 2006"), dmg)
+	}
+	path := filepath.Join(t.TempDir(), "eqlog_Emberval_Imperium.txt")
+	log := stamp(0, 10) + stamp(time.Minute, 20) +
+		// The clock is set back to before the cutoff; that line is filtered out, but the
+		// jump must still reach the segmenter.
+		stamp(-30*time.Second, 30) + stamp(5*time.Second, 40)
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	seg := engine.NewEncounterSegmenter(2*time.Minute, "Emberval")
+	p := ForLog(path, nil, time.UTC, engine.TimeFilter{Cutoff: &cutoff}, nil, Sink(seg.Process))
+	if err := p.Run(context.Background(), File(path, nil)); err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	encs := seg.Finalize()
+	if len(encs) != 2 || encs[0].Total != 30 || !encs[0].ClockJumped || encs[1].Total != 40 {
+		t.Fatalf("encounters=%d want the 30 before the jump and the 40 after it", len(encs))
+	}
+}